@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/context"
@@ -23,7 +26,24 @@ type AuthUser struct {
 
 //Structure that will allow returning a token as a response
 type tokenResult struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// ExpiresIn is the lifetime of the token in seconds
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// tokenClaims are the claims of the tokens, the tokens don't have credentials
+type tokenClaims struct {
+	Username  string   `json:"username"`
+	Role      RoleType `json:"role"`
+	TokenType string   `json:"tokenType"`
+	// Password is only read from the legacy tokens
+	Password string `json:"password,omitempty"`
+	jwt.StandardClaims
 }
 
 // Status that a conversation can have
@@ -36,6 +56,12 @@ const (
 	roleKey             = "role"
 )
 
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+	kidHeader        = "kid"
+)
+
 // Signing method to create JWT
 var singMethod jwt.SigningMethod = jwt.SigningMethodHS384
 
@@ -62,13 +88,74 @@ func (app *App) authenticate(w http.ResponseWriter, r *http.Request, _ httproute
 		helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid credentials.")
 		return
 	}
-	token, err := app.SignedTokenString(*user)
+	result, err := app.tokenResult(*user)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, result)
+}
+
+// refreshToken returns a new access token and a new refresh token if the refresh token sent is valid,
+// each refresh token can be used only once
+func (app *App) refreshToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var request refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.InvalidPayload+" : the refreshToken is required")
+		return
+	}
+
+	claims, err := app.validClaims(request.RefreshToken, refreshTokenType)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid refresh token.")
+		return
+	}
+
+	used, err := app.useRefreshToken(claims)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !used {
+		helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid refresh token, it was already used.")
+		return
+	}
+
+	result, err := app.tokenResult(AuthUser{Username: claims.Username, Role: claims.Role})
 	if err != nil {
 		helpers.WriteFailedResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	helpers.WriteSuccessResponse(w, &tokenResult{Token: token})
+	helpers.WriteSuccessResponse(w, result)
+}
+
+// useRefreshToken remembers the jti of the refresh token until it expires, it returns false when the token was
+// already used or it cannot be remembered
+func (app *App) useRefreshToken(claims *tokenClaims) (bool, error) {
+	if app.RefreshTokenCache == nil || claims.Id == "" {
+		return false, nil
+	}
+
+	ttl := time.Unix(claims.ExpiresAt, 0).Sub(jwt.TimeFunc()) + time.Second
+	return app.RefreshTokenCache.UseRefreshToken(app.Client, claims.Id, ttl)
+}
+
+func (app *App) tokenResult(user AuthUser) (*tokenResult, error) {
+	token, err := app.SignedTokenString(user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tokenResult{Token: token, ExpiresIn: int64(app.AccessTokenTTL.Seconds())}
+	if app.RefreshTokenTTL > 0 {
+		result.RefreshToken, err = app.signToken(user, refreshTokenType, app.RefreshTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 // Function getUserByToken will return the username and role if the token sent in
@@ -110,37 +197,104 @@ func (app *App) authorizeMiddleware(next httprouter.Handle, roles []RoleType) ht
 func (app *App) validToken(w http.ResponseWriter, req *http.Request, params httprouter.Params, next httprouter.Handle, token string, roles []RoleType) {
 	tokenParse, err := app.parseBearerToken(token)
 	if err != nil {
+		// the malformed, unverifiable and expired tokens are rejected, the other errors are internal
+		var validationError *jwt.ValidationError
+		if errors.As(err, &validationError) {
+			if validationError.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorIssuedAt|jwt.ValidationErrorNotValidYet) != 0 {
+				helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid Authorization token, the token is expired.")
+				return
+			}
+			helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid Authorization token.")
+			return
+		}
 		helpers.WriteFailedResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	if tokenParse.Valid {
-		var user AuthUser
-		tokenClaims, _ := helpers.MarshalJSON(tokenParse.Claims)
-		json.Unmarshal(tokenClaims, &user)
-		if app.validateCredentials(user) {
-			for _, role := range roles {
-				if user.Role == role {
-					context.Set(req, userKey, user)
-					next(w, req, params)
-					return
-				}
-			}
-			helpers.WriteFailedResponse(w, http.StatusForbidden, "Invalid Authorization token.")
+
+	user, ok := app.tokenUser(tokenParse)
+	if !tokenParse.Valid || !ok {
+		helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid Authorization token.")
+		return
+	}
+
+	for _, role := range roles {
+		if user.Role == role {
+			context.Set(req, userKey, user)
+			next(w, req, params)
 			return
 		}
 	}
-	helpers.WriteFailedResponse(w, http.StatusUnauthorized, "Invalid Authorization token.")
+	helpers.WriteFailedResponse(w, http.StatusForbidden, "Invalid Authorization token.")
+}
+
+// tokenUser returns the user of an access token, the tokens without expiration were created before the
+// standard claims and they are only valid with AllowLegacyTokens and the credentials of the user
+func (app *App) tokenUser(token *jwt.Token) (AuthUser, bool) {
+	claims, ok := token.Claims.(*tokenClaims)
+	if !ok {
+		return AuthUser{}, false
+	}
+
+	if claims.ExpiresAt == 0 {
+		user := AuthUser{Username: claims.Username, Role: claims.Role}
+		return user, app.AllowLegacyTokens && app.validateCredentials(AuthUser{
+			Username: claims.Username,
+			Password: claims.Password,
+			Role:     claims.Role,
+		})
+	}
+
+	user := AuthUser{Username: claims.Username, Role: claims.Role}
+	return user, claims.TokenType == accessTokenType && app.validUser(user)
+}
+
+// validClaims returns the claims of a valid token of the type
+func (app *App) validClaims(token, tokenType string) (*tokenClaims, error) {
+	tokenParse, err := app.parseBearerToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := tokenParse.Claims.(*tokenClaims)
+	if !ok || !tokenParse.Valid || claims.ExpiresAt == 0 || claims.TokenType != tokenType ||
+		!app.validUser(AuthUser{Username: claims.Username, Role: claims.Role}) {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
 }
 
+// parseBearerToken verifies the token with the secret of its kid, the tokens without kid are verified with the
+// current secret
 func (app *App) parseBearerToken(bearerToken string) (*jwt.Token, error) {
-	return jwt.Parse(bearerToken, func(token *jwt.Token) (interface{}, error) {
+	return jwt.ParseWithClaims(bearerToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("there was an error, SigningMethod is invalid")
 		}
-		return []byte(app.SecretKey), nil
+
+		kid, ok := token.Header[kidHeader].(string)
+		if !ok || kid == app.SecretKeyID {
+			return []byte(app.SecretKey), nil
+		}
+
+		secret, ok := app.SecretKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("there was an error, the kid %s is unknown", kid)
+		}
+		return []byte(secret), nil
 	})
 }
 
+// validUser validates that the user of the token is still configured with the role
+func (app *App) validUser(user AuthUser) bool {
+	switch user.Role {
+	case Yalo:
+		return user.Username == app.YaloUsername
+	case Salesforce:
+		return user.Username == app.SalesforceUsername
+	}
+	return false
+}
+
 func (app *App) validateCredentials(user AuthUser) bool {
 	switch user.Role {
 	case Yalo:
@@ -156,13 +310,32 @@ func (app *App) validateCredentials(user AuthUser) bool {
 	return false
 }
 
-// signedTokenString signs the jwt with the signature method and the secret of the api
+// SignedTokenString returns an access token of the user signed with the current secret of the api
 func (app *App) SignedTokenString(user AuthUser) (string, error) {
-	token := jwt.NewWithClaims(singMethod, jwt.MapClaims{
-		"username": user.Username,
-		"password": user.Password,
-		"role":     user.Role,
+	return app.signToken(user, accessTokenType, app.AccessTokenTTL)
+}
+
+func (app *App) signToken(user AuthUser, tokenType string, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := jwt.TimeFunc()
+	token := jwt.NewWithClaims(singMethod, tokenClaims{
+		Username:  user.Username,
+		Role:      user.Role,
+		TokenType: tokenType,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.Username,
+			Id:        hex.EncodeToString(jti),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
 	})
+	if app.SecretKeyID != "" {
+		token.Header[kidHeader] = app.SecretKeyID
+	}
 
 	signedToken, err := token.SignedString([]byte(app.SecretKey))
 	if err != nil {
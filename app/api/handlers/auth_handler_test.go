@@ -14,6 +14,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
 	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
 	"yalochat.com/salesforce-integration/app/manage"
 	"yalochat.com/salesforce-integration/base/cache"
@@ -36,9 +37,23 @@ var apiConfig = ApiConfig{
 	SalesforceUsername: salesforceUserTest,
 	SalesforcePassword: salesforcePasswordTest,
 	SecretKey:          secretTest,
+	SecretKeyID:        "v2",
+	SecretKeys:         map[string]string{"v1": "previousSecret"},
+	AccessTokenTTL:     time.Hour,
+	RefreshTokenTTL:    24 * time.Hour,
 	UseProfile:         true,
 }
 
+// newTestToken returns a token signed like the tokens of the api
+func newTestToken(t *testing.T, user AuthUser, tokenType string, ttl time.Duration) string {
+	app := &App{SecretKey: apiConfig.SecretKey, SecretKeyID: apiConfig.SecretKeyID}
+	token, err := app.signToken(user, tokenType, ttl)
+	if err != nil {
+		t.Fatalf("Could not sign the token: %s", err)
+	}
+	return token
+}
+
 // TODO: Upgrade unit tests as in chat_handler_test.go
 func TestAuthenticate(t *testing.T) {
 	m, s := cache.CreateRedisServer()
@@ -59,7 +74,6 @@ func TestAuthenticate(t *testing.T) {
 		requestURL := "/v1/authenticate"
 		req, _ := http.NewRequest("POST", requestURL, bytes.NewBuffer([]byte("{\"username\":\"yaloUser\",\"password\":\"yaloPassword\"}")))
 		response := httptest.NewRecorder()
-		expected := yaloTokenTest
 
 		handler.ServeHTTP(response, req)
 		if response.Code != http.StatusOK {
@@ -71,9 +85,15 @@ func TestAuthenticate(t *testing.T) {
 			t.Fatalf("Response should produce an token result, but found this: %s", response.Body)
 		}
 
-		if token.Token != expected {
-			t.Fatalf("Response should produce an token %s, but found this: %s", expected, token.Token)
-		}
+		claims, err := getApp().validClaims(token.Token, accessTokenType)
+		assert.NoError(t, err)
+		assert.Equal(t, yaloUserTest, claims.Username)
+		assert.Equal(t, Yalo, claims.Role)
+		assert.Empty(t, claims.Password)
+		assert.Equal(t, int64(3600), token.ExpiresIn)
+
+		_, err = getApp().validClaims(token.RefreshToken, refreshTokenType)
+		assert.NoError(t, err)
 
 	})
 
@@ -81,7 +101,6 @@ func TestAuthenticate(t *testing.T) {
 		requestURL := "/v1/authenticate"
 		req, _ := http.NewRequest("POST", requestURL, bytes.NewBuffer([]byte("{\"username\":\"salesforceUser\",\"password\":\"salesforcePassword\"}")))
 		response := httptest.NewRecorder()
-		expected := saleforceTokenTest
 
 		handler.ServeHTTP(response, req)
 		if response.Code != http.StatusOK {
@@ -93,9 +112,10 @@ func TestAuthenticate(t *testing.T) {
 			t.Fatalf("Response should produce an token result, but found this: %s", response.Body)
 		}
 
-		if token.Token != expected {
-			t.Fatalf("Response should produce an token %s, but found this: %s", expected, token.Token)
-		}
+		claims, err := getApp().validClaims(token.Token, accessTokenType)
+		assert.NoError(t, err)
+		assert.Equal(t, salesforceUserTest, claims.Username)
+		assert.Equal(t, Salesforce, claims.Role)
 
 	})
 
@@ -204,7 +224,7 @@ func TestProtected(t *testing.T) {
 	t.Run("Should return a user ", func(t *testing.T) {
 		requestURL := "/v1/tokens/check"
 		req, _ := http.NewRequest("GET", requestURL, nil)
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, accessTokenType, time.Hour)))
 		response := httptest.NewRecorder()
 		expected := AuthUser{Username: yaloUserTest, Role: Yalo}
 
@@ -243,7 +263,7 @@ func TestAuthorizeMiddlewareByHeader(t *testing.T) {
 	t.Run("Should respond successfully with Yalo token test", func(t *testing.T) {
 		requestURL := "/v1/tokens/check"
 		req, _ := http.NewRequest("GET", requestURL, nil)
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, accessTokenType, time.Hour)))
 		response := httptest.NewRecorder()
 
 		handler.ServeHTTP(response, req)
@@ -328,11 +348,11 @@ func TestAuthorizeMiddlewareByHeader(t *testing.T) {
 		req, _ := http.NewRequest("GET", requestURL, nil)
 		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 		response := httptest.NewRecorder()
-		errorExpected := "there was an error, SigningMethod is invalid"
+		errorExpected := "Invalid Authorization token."
 
 		handler.ServeHTTP(response, req)
-		if response.Code != http.StatusInternalServerError {
-			t.Errorf("Response should be %v, but it answer with %v ", http.StatusInternalServerError, response.Code)
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf("Response should be %v, but it answer with %v ", http.StatusUnauthorized, response.Code)
 		}
 		var result helpers.FailedResponse
 		err := json.NewDecoder(response.Body).Decode(&result)
@@ -389,7 +409,7 @@ func TestAuthorizeMiddlewareByQueryParam(t *testing.T) {
 	})
 
 	t.Run("Should respond successfully with token", func(t *testing.T) {
-		requestURL := "/v1/tokens/check?token=" + yaloTokenTest
+		requestURL := "/v1/tokens/check?token=" + newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, accessTokenType, time.Hour)
 		req, _ := http.NewRequest("GET", requestURL, nil)
 		response := httptest.NewRecorder()
 
@@ -435,23 +455,32 @@ func TestParseBearerToken(t *testing.T) {
 }
 
 func TestSignedTokenString(t *testing.T) {
-	app := &App{SecretKey: secretTest}
+	app := &App{SecretKey: secretTest, SecretKeyID: "v2", AccessTokenTTL: time.Hour}
 
 	t.Run("Should retrieve token signed", func(t *testing.T) {
-		hash := sha1.New()
-		hash.Write([]byte(yaloPasswordTest))
-		user := AuthUser{Password: hex.EncodeToString(hash.Sum(nil)), Username: yaloUserTest, Role: Yalo}
-		jwt, err := app.SignedTokenString(user)
-		tokenExpected := yaloTokenTest
-
-		if err != nil {
-			t.Errorf("Response err should be nil, but it answer with %v ", err)
-		}
-
-		if jwt != tokenExpected {
-			t.Errorf("Response should be %v, but it answer with %v ", tokenExpected, jwt)
-		}
-
+		user := AuthUser{Password: "password", Username: yaloUserTest, Role: Yalo}
+		token, err := app.SignedTokenString(user)
+		assert.NoError(t, err)
+
+		parsedToken, err := app.parseBearerToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "v2", parsedToken.Header[kidHeader])
+
+		claims := parsedToken.Claims.(*tokenClaims)
+		assert.Equal(t, yaloUserTest, claims.Username)
+		assert.Equal(t, yaloUserTest, claims.Subject)
+		assert.Equal(t, Yalo, claims.Role)
+		assert.Equal(t, accessTokenType, claims.TokenType)
+		assert.Empty(t, claims.Password)
+		assert.NotEmpty(t, claims.Id)
+		assert.Equal(t, int64(3600), claims.ExpiresAt-claims.IssuedAt)
+		assert.NotContains(t, token, "password")
+
+		otherToken, err := app.SignedTokenString(user)
+		assert.NoError(t, err)
+		otherParsedToken, err := app.parseBearerToken(otherToken)
+		assert.NoError(t, err)
+		assert.NotEqual(t, claims.Id, otherParsedToken.Claims.(*tokenClaims).Id)
 	})
 
 	t.Run("Should get error when signing ", func(t *testing.T) {
@@ -465,3 +494,191 @@ func TestSignedTokenString(t *testing.T) {
 		singMethod = jwt.SigningMethodHS384
 	})
 }
+
+func TestValidToken(t *testing.T) {
+	hash := sha1.New()
+	hash.Write([]byte(yaloPasswordTest))
+	yaloPasswordHash := hex.EncodeToString(hash.Sum(nil))
+	hash = sha1.New()
+	hash.Write([]byte(salesforcePasswordTest))
+
+	app := &App{
+		YaloUsername:       yaloUserTest,
+		YaloPassword:       yaloPasswordHash,
+		SalesforceUsername: salesforceUserTest,
+		SalesforcePassword: hex.EncodeToString(hash.Sum(nil)),
+		SecretKey:          secretTest,
+		SecretKeyID:        "v2",
+		SecretKeys:         map[string]string{"v1": "previousSecret"},
+		AccessTokenTTL:     time.Hour,
+	}
+	yaloUser := AuthUser{Username: yaloUserTest, Role: Yalo}
+
+	serve := func(app *App, token string, roles []RoleType) *httptest.ResponseRecorder {
+		handler := ddrouter.New(ddrouter.WithServiceName("appName.http"))
+		handler.GET("/v1/tokens/check", app.authorizeMiddleware(app.getUserByToken, roles))
+		req, _ := http.NewRequest("GET", "/v1/tokens/check", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+		return response
+	}
+
+	t.Run("Should accept a token signed with a previous secret", func(t *testing.T) {
+		previousApp := &App{SecretKey: "previousSecret", SecretKeyID: "v1", AccessTokenTTL: time.Hour}
+		token, err := previousApp.SignedTokenString(yaloUser)
+		assert.NoError(t, err)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `{"username":"yaloUser","role":"YALO_ROLE"}`, response.Body.String())
+	})
+
+	t.Run("Should reject a token with an unknown kid", func(t *testing.T) {
+		unknownApp := &App{SecretKey: "otherSecret", SecretKeyID: "v0", AccessTokenTTL: time.Hour}
+		token, err := unknownApp.SignedTokenString(yaloUser)
+		assert.NoError(t, err)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Equal(t, `{"ErrorDescription":"Invalid Authorization token."}`, response.Body.String())
+	})
+
+	t.Run("Should reject a token with an invalid signature", func(t *testing.T) {
+		otherApp := &App{SecretKey: "otherSecret", SecretKeyID: "v2", AccessTokenTTL: time.Hour}
+		token, err := otherApp.SignedTokenString(yaloUser)
+		assert.NoError(t, err)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Equal(t, `{"ErrorDescription":"Invalid Authorization token."}`, response.Body.String())
+	})
+
+	t.Run("Should reject an expired token", func(t *testing.T) {
+		token := newTestToken(t, yaloUser, accessTokenType, -time.Minute)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Equal(t, `{"ErrorDescription":"Invalid Authorization token, the token is expired."}`, response.Body.String())
+	})
+
+	t.Run("Should reject a refresh token", func(t *testing.T) {
+		token := newTestToken(t, yaloUser, refreshTokenType, time.Hour)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should reject a token of a user that is not configured", func(t *testing.T) {
+		token := newTestToken(t, AuthUser{Username: "otherUser", Role: Yalo}, accessTokenType, time.Hour)
+
+		response := serve(app, token, []RoleType{Yalo})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should reject a token without the role", func(t *testing.T) {
+		token := newTestToken(t, yaloUser, accessTokenType, time.Hour)
+
+		response := serve(app, token, []RoleType{Salesforce})
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("Should reject a legacy token", func(t *testing.T) {
+		response := serve(app, saleforceTokenTest, []RoleType{Salesforce})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should accept a legacy token when they are allowed", func(t *testing.T) {
+		legacyApp := *app
+		legacyApp.AllowLegacyTokens = true
+
+		response := serve(&legacyApp, saleforceTokenTest, []RoleType{Salesforce})
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `{"username":"salesforceUser","role":"SALESFORCE_ROLE"}`, response.Body.String())
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	m, s := cache.CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	redisCache, _ := cache.NewRedisCache(&cache.RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	})
+	app := &App{
+		Client:            "client",
+		YaloUsername:      yaloUserTest,
+		SecretKey:         secretTest,
+		SecretKeyID:       "v2",
+		AccessTokenTTL:    time.Hour,
+		RefreshTokenTTL:   24 * time.Hour,
+		RefreshTokenCache: cache.NewRefreshTokenCache(redisCache),
+	}
+	handler := ddrouter.New(ddrouter.WithServiceName("appName.http"))
+	handler.POST("/v1/tokens/refresh", app.refreshToken)
+
+	refresh := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/v1/tokens/refresh", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+		return response
+	}
+
+	t.Run("Should return new tokens", func(t *testing.T) {
+		refreshToken := newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, refreshTokenType, time.Hour)
+
+		response := refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, refreshToken))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var token tokenResult
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&token))
+		claims, err := app.validClaims(token.Token, accessTokenType)
+		assert.NoError(t, err)
+		assert.Equal(t, yaloUserTest, claims.Username)
+		_, err = app.validClaims(token.RefreshToken, refreshTokenType)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3600), token.ExpiresIn)
+	})
+
+	t.Run("Should reject a refresh token that was already used", func(t *testing.T) {
+		refreshToken := newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, refreshTokenType, time.Hour)
+
+		response := refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, refreshToken))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		response = refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, refreshToken))
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Contains(t, response.Body.String(), "it was already used")
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		refreshToken := newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, refreshTokenType, time.Hour)
+		m.SetError("server is down")
+		defer m.SetError("")
+
+		response := refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, refreshToken))
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+
+	t.Run("Should reject an access token", func(t *testing.T) {
+		accessToken := newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, accessTokenType, time.Hour)
+
+		response := refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, accessToken))
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should reject an expired refresh token", func(t *testing.T) {
+		refreshToken := newTestToken(t, AuthUser{Username: yaloUserTest, Role: Yalo}, refreshTokenType, -time.Minute)
+
+		response := refresh(fmt.Sprintf(`{"refreshToken":"%s"}`, refreshToken))
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should fail without the refresh token", func(t *testing.T) {
+		response := refresh(`{}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
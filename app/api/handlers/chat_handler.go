@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -16,6 +19,12 @@ import (
 	"yalochat.com/salesforce-integration/base/helpers"
 )
 
+// idempotencyKeyHeader is the header with the key to create a chat only once when the request is retried
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// asyncParam is the query param that creates the chat in background
+const asyncParam = "async"
+
 type ChatPayload struct {
 	UserID      string                 `json:"userID" validate:"required"`
 	Name        string                 `json:"name" validate:"required"`
@@ -25,6 +34,38 @@ type ChatPayload struct {
 	Email       string                 `json:"email" validate:"required"`
 	PhoneNumber string                 `json:"phoneNumber"`
 	ExtraData   map[string]interface{} `json:"extraData"`
+	// CallbackURL receives the outcome of the asynchronous requests
+	CallbackURL string `json:"callbackUrl" validate:"omitempty,url"`
+}
+
+// ProactiveChatPayload is a chat that an agent starts from a case, the chat is routed to the button of the agent.
+// Without text the user is notified with the proactiveChatTemplate message
+type ProactiveChatPayload struct {
+	UserID      string                 `json:"userID" validate:"required"`
+	Name        string                 `json:"name" validate:"required"`
+	Provider    string                 `json:"provider" validate:"required,oneof=whatsapp facebook"`
+	BotSlug     string                 `json:"botSlug" validate:"required"`
+	BotId       string                 `json:"botId"`
+	CaseID      string                 `json:"caseId" validate:"required"`
+	ContactID   string                 `json:"contactId" validate:"required"`
+	ButtonID    string                 `json:"buttonId" validate:"required"`
+	Email       string                 `json:"email"`
+	PhoneNumber string                 `json:"phoneNumber"`
+	Text        string                 `json:"text"`
+	ExtraData   map[string]interface{} `json:"extraData"`
+}
+
+// CloseChatsPayload selects the chats to close, at least one of userIds, statuses or olderThan is required
+type CloseChatsPayload struct {
+	UserIDs  []string `json:"userIds"`
+	Statuses []string `json:"statuses"`
+	// OlderThan is a duration, e.g. 6h
+	OlderThan string `json:"olderThan"`
+	Provider  string `json:"provider"`
+	BotSlug   string `json:"botSlug"`
+	BotState  string `json:"botState"`
+	// DryRun is true by default, the chats are closed only with false
+	DryRun *bool `json:"dryRun"`
 }
 
 // Connect and create chat between user and salesforce
@@ -87,19 +128,101 @@ func (app *App) createChat(w http.ResponseWriter, r *http.Request, params httpro
 	logFields[events.Interconnection] = interconnection
 	span.SetTag(events.UserID, interconnection.UserID)
 	span.SetTag(events.Interconnection, fmt.Sprintf("%#v", interconnection))
-	if err := app.ManageManager.CreateChat(r.Context(), interconnection); err != nil {
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	logFields["idempotencyKey"] = idempotencyKey
+	if r.URL.Query().Get(asyncParam) == "true" {
+		app.createChatAsync(w, r, span, logFields, idempotencyKey, chatPayload.CallbackURL, interconnection)
+		return
+	}
+	if err := app.ManageManager.CreateChatIdempotent(r.Context(), idempotencyKey, interconnection); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, constants.ErrChatRequestInProgress) {
+			statusCode = http.StatusConflict
+		}
 		errorMessage = err.Error()
 		span.SetTag(ext.Error, err)
 		span.SetTag(ext.ErrorDetails, errorMessage)
 		logrus.WithFields(logFields).Error(errorMessage)
-		span.SetTag(ext.HTTPCode, http.StatusInternalServerError)
-		helpers.WriteFailedResponse(w, http.StatusInternalServerError, errorMessage)
+		span.SetTag(ext.HTTPCode, statusCode)
+		helpers.WriteFailedResponse(w, statusCode, errorMessage)
 		return
 	}
 	span.SetTag(ext.HTTPCode, http.StatusOK)
 	helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "Chat created succefully"})
 }
 
+// createChatAsync responds 202 with the chat request while the chat is created in background
+func (app *App) createChatAsync(w http.ResponseWriter, r *http.Request, span tracer.Span, logFields logrus.Fields,
+	requestID, callbackURL string, interconnection *manage.Interconnection) {
+	chatRequest, err := app.ManageManager.CreateChatAsync(r.Context(), requestID, callbackURL, interconnection)
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		span.SetTag(ext.HTTPCode, http.StatusInternalServerError)
+		logrus.WithFields(logFields).WithError(err).Error("Could not create the chat request")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not create the chat request", err))
+		return
+	}
+
+	span.SetTag(ext.HTTPCode, http.StatusAccepted)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("%s/chat-requests/%s", apiVersion, chatRequest.Key))
+	w.WriteHeader(http.StatusAccepted)
+	result, _ := helpers.MarshalJSON(chatRequest)
+	w.Write(result)
+}
+
+// Get the status of an asynchronous chat request
+func (app *App) getChatRequest(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	requestID := params.ByName("request_id")
+
+	chatRequest, err := app.ManageManager.GetChatRequest(requestID)
+	if errors.Is(err, constants.ErrChatRequestNotFound) {
+		helpers.WriteFailedResponse(w, http.StatusNotFound, helpers.ErrorMessage("could not get the chat request", err))
+		return
+	}
+	if err != nil {
+		logrus.WithField("requestId", requestID).WithError(err).Error("Could not get the chat request")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not get the chat request", err))
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, chatRequest)
+}
+
+// Create a chat requested by an agent for an existing case and contact
+func (app *App) createProactiveChat(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	payload := &ProactiveChatPayload{}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	if err := helpers.Govalidator().Struct(payload); err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.ValidatePayloadError, err))
+		return
+	}
+
+	interconnection := manage.NewInterconnection(&manage.NewInterconnectionParams{
+		UserID:      payload.UserID,
+		Name:        payload.Name,
+		Provider:    manage.Provider(payload.Provider),
+		BotSlug:     payload.BotSlug,
+		BotID:       payload.BotId,
+		PhoneNumber: payload.PhoneNumber,
+		Email:       payload.Email,
+		ExtraData:   payload.ExtraData,
+	})
+	interconnection.CaseID = payload.CaseID
+
+	if err := app.ManageManager.CreateProactiveChat(r.Context(), interconnection, payload.ContactID, payload.ButtonID, payload.Text); err != nil {
+		logrus.WithField(events.Payload, payload).WithError(err).Error("Could not create the proactive chat")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "Chat created successfully"})
+}
+
 // Connect and end the chat between the user and the sales force
 func (app *App) finishChat(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	userID := params.ByName("user_id")
@@ -112,3 +235,143 @@ func (app *App) finishChat(w http.ResponseWriter, r *http.Request, params httpro
 
 	helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "Chat finished successfully"})
 }
+
+// Get the status of the chat of the user
+func (app *App) getChatStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID := params.ByName("user_id")
+
+	status, err := app.ManageManager.GetChatStatus(userID)
+	if errors.Is(err, constants.ErrInterconnectionNotFound) {
+		helpers.WriteFailedResponse(w, http.StatusNotFound, helpers.ErrorMessage("could not get the chat status", err))
+		return
+	}
+	if err != nil {
+		logrus.WithField(events.UserID, userID).WithError(err).Error("Could not get the chat status")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not get the chat status", err))
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, status)
+}
+
+// List the chats stored in redis, they can be filtered by status, provider, botSlug and age,
+// and paginated with page and size
+func (app *App) listChats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter, err := getChatFilter(r)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	chats, err := app.ManageManager.ListChats(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Could not list the chats")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not list the chats", err))
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, chats)
+}
+
+// getChatFilter reads the filter of the chats from the query params, minAge and maxAge are durations, e.g. 30m
+func getChatFilter(r *http.Request) (manage.ChatFilter, error) {
+	query := r.URL.Query()
+	filter := manage.ChatFilter{
+		Status:   manage.InterconnectionStatus(strings.ToUpper(query.Get("status"))),
+		Provider: manage.Provider(query.Get("provider")),
+		BotSlug:  query.Get("botSlug"),
+	}
+
+	var err error
+	if minAge := query.Get("minAge"); minAge != "" {
+		filter.MinAge, err = time.ParseDuration(minAge)
+		if err != nil {
+			return filter, fmt.Errorf("invalid minAge: %s", minAge)
+		}
+	}
+
+	if maxAge := query.Get("maxAge"); maxAge != "" {
+		filter.MaxAge, err = time.ParseDuration(maxAge)
+		if err != nil {
+			return filter, fmt.Errorf("invalid maxAge: %s", maxAge)
+		}
+	}
+
+	switch sort := query.Get("sort"); sort {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, fmt.Errorf("invalid sort: %s", sort)
+	}
+
+	filter.Page, filter.Size, err = helpers.GetPaginationValues(r)
+	if err != nil {
+		return filter, fmt.Errorf("invalid pagination: %w", err)
+	}
+	if filter.Page < 1 || filter.Size < 1 {
+		return filter, fmt.Errorf("invalid pagination: page %d and size %d", filter.Page, filter.Size)
+	}
+	return filter, nil
+}
+
+// Close the chats that match the filter, by default it only reports the chats that would be closed
+func (app *App) closeChats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	payload := &CloseChatsPayload{}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	filter, err := getCloseChatsFilter(payload)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	report, err := app.ManageManager.CloseChats(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Could not close the chats")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not close the chats", err))
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		events.Payload: payload,
+		"dryRun":       report.DryRun,
+		"matched":      report.Matched,
+		"closed":       report.Closed,
+	}).Info("Bulk close of chats")
+	helpers.WriteSuccessResponse(w, report)
+}
+
+func getCloseChatsFilter(payload *CloseChatsPayload) (manage.CloseChatsFilter, error) {
+	filter := manage.CloseChatsFilter{
+		UserIDs:  payload.UserIDs,
+		Provider: manage.Provider(payload.Provider),
+		BotSlug:  payload.BotSlug,
+		BotState: payload.BotState,
+		DryRun:   payload.DryRun == nil || *payload.DryRun,
+	}
+
+	if len(payload.UserIDs) == 0 && len(payload.Statuses) == 0 && payload.OlderThan == "" {
+		return filter, errors.New("userIds, statuses or olderThan is required")
+	}
+
+	for _, value := range payload.Statuses {
+		status := manage.InterconnectionStatus(strings.ToUpper(value))
+		if status != manage.Active && status != manage.OnHold {
+			return filter, fmt.Errorf("invalid status: %s, only the %s and %s chats can be closed", status, manage.Active, manage.OnHold)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	if payload.OlderThan != "" {
+		olderThan, err := time.ParseDuration(payload.OlderThan)
+		if err != nil {
+			return filter, fmt.Errorf("invalid olderThan: %s", payload.OlderThan)
+		}
+		filter.OlderThan = olderThan
+	}
+	return filter, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -16,6 +17,8 @@ import (
 	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
 	"yalochat.com/salesforce-integration/app/api/handlers/mocks"
 	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
 )
 
 const (
@@ -53,7 +56,7 @@ func TestCreateChat(t *testing.T) {
 
 		interconnection := manage.NewInterconnection(interconnectionParams)
 
-		managerMock.On("CreateChat", mock.Anything, interconnection).Return(nil).Once()
+		managerMock.On("CreateChatIdempotent", mock.Anything, "requestKey", interconnection).Return(nil).Once()
 		getApp().ManageManager = managerMock
 
 		interconnectionBin, err := json.Marshal(interconnection)
@@ -61,6 +64,7 @@ func TestCreateChat(t *testing.T) {
 
 		req, _ := http.NewRequest("POST", requestURL, bytes.NewBuffer(interconnectionBin))
 		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		req.Header.Add(idempotencyKeyHeader, "requestKey")
 		response := httptest.NewRecorder()
 		handler.ServeHTTP(response, req)
 		logrus.Infof("Response : %s", response.Body.String())
@@ -154,7 +158,7 @@ func TestCreateChat(t *testing.T) {
 
 		interconnection := manage.NewInterconnection(interconnectionParams)
 
-		managerMock.On("CreateChat", mock.Anything, interconnection).Return(assert.AnError).Once()
+		managerMock.On("CreateChatIdempotent", mock.Anything, "", interconnection).Return(assert.AnError).Once()
 		getApp().ManageManager = managerMock
 
 		interconnectionBin, err := json.Marshal(interconnection)
@@ -182,6 +186,176 @@ func TestCreateChat(t *testing.T) {
 			t.Fatalf("Logs should contain <%s>, but this was found <%s>", expectedLog, logs)
 		}
 	})
+
+	t.Run("Should return conflict when the chat request is in progress", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CreateChatIdempotent", mock.Anything, "requestKey", mock.Anything).
+			Return(fmt.Errorf("%w for the user 5217331175599", constants.ErrChatRequestInProgress)).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", requestURL, strings.NewReader(`{"userID":"5217331175599","name":"Eduardo Ochoa",`+
+			`"provider":"whatsapp","botSlug":"coppel-bot","botId":"521554578545","email":"ochoapumas@gmail.com"}`))
+		req.Header.Add(idempotencyKeyHeader, "requestKey")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+
+	t.Run("Should accept the asynchronous chat request", func(t *testing.T) {
+		chatRequest := &cache.ChatRequest{Key: "requestKey", UserID: userID, Status: cache.ChatRequestPending}
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CreateChatAsync", mock.Anything, "requestKey", "https://bot.yalochat.com/callback", mock.Anything).
+			Return(chatRequest, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", requestURL+"?async=true", strings.NewReader(`{"userID":"5217331175599",`+
+			`"name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","botId":"521554578545",`+
+			`"email":"ochoapumas@gmail.com","callbackUrl":"https://bot.yalochat.com/callback"}`))
+		req.Header.Add(idempotencyKeyHeader, "requestKey")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		assert.Equal(t, "/v1/chat-requests/requestKey", response.Header().Get("Location"))
+		expected, err := json.Marshal(chatRequest)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), response.Body.String())
+		managerMock.AssertNotCalled(t, "CreateChatIdempotent", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return bad request with an invalid callback url", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", requestURL+"?async=true", strings.NewReader(`{"userID":"5217331175599",`+
+			`"name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","botId":"521554578545",`+
+			`"email":"ochoapumas@gmail.com","callbackUrl":"callback"}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		managerMock.AssertNotCalled(t, "CreateChatAsync", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return internal error when the chat request is not created", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CreateChatAsync", mock.Anything, "", "", mock.Anything).Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", requestURL+"?async=true", strings.NewReader(`{"userID":"5217331175599",`+
+			`"name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","botId":"521554578545",`+
+			`"email":"ochoapumas@gmail.com"}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
+
+func TestGetChatRequest(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET(fmt.Sprintf("%s/chat-requests/:request_id", apiVersion), app.getChatRequest)
+	url := fmt.Sprintf("%s/chat-requests/requestKey", apiVersion)
+
+	t.Run("Should get the chat request", func(t *testing.T) {
+		chatRequest := &cache.ChatRequest{Key: "requestKey", UserID: userID, Status: cache.ChatRequestNoAgents,
+			Error: "event [ChatRequestFail] : [Unavailable]"}
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("GetChatRequest", "requestKey").Return(chatRequest, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(chatRequest)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should return not found when there is no chat request", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("GetChatRequest", "requestKey").Return(nil, constants.ErrChatRequestNotFound).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("GetChatRequest", "requestKey").Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
+
+func TestCreateProactiveChat(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	url := fmt.Sprintf("%s/chats/proactive", apiVersion)
+	handler.POST(url, app.createProactiveChat)
+	payload := `{"userID":"5217331175599","name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot",` +
+		`"caseId":"caseID","contactId":"contactID","buttonId":"buttonID","text":"Necesitamos tu identificación"}`
+
+	t.Run("Should create the proactive chat", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CreateProactiveChat", mock.Anything, mock.MatchedBy(func(interconnection *manage.Interconnection) bool {
+			return interconnection.UserID == userID && interconnection.CaseID == "caseID" &&
+				interconnection.Provider == manage.WhatsappProvider && interconnection.BotSlug == botSlug
+		}), "contactID", buttonId, "Necesitamos tu identificación").Return(nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		managerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return bad request with an invalid payload", func(t *testing.T) {
+		payloads := []string{
+			`{`,
+			`{"userID":"5217331175599","name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","contactId":"contactID","buttonId":"buttonID"}`,
+			`{"userID":"5217331175599","name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","caseId":"caseID","buttonId":"buttonID"}`,
+			`{"userID":"5217331175599","name":"Eduardo Ochoa","provider":"whatsapp","botSlug":"coppel-bot","caseId":"caseID","contactId":"contactID"}`,
+			`{"userID":"5217331175599","name":"Eduardo Ochoa","provider":"telegram","botSlug":"coppel-bot","caseId":"caseID","contactId":"contactID","buttonId":"buttonID"}`,
+		}
+		for _, payload := range payloads {
+			managerMock := new(mocks.ManagerI)
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, payload)
+			managerMock.AssertNotCalled(t, "CreateProactiveChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Should return internal error when the chat is not created", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CreateProactiveChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
 }
 
 func TestFinishChat(t *testing.T) {
@@ -223,3 +397,216 @@ func TestFinishChat(t *testing.T) {
 			response.Body.String())
 	})
 }
+
+func TestGetChatStatus(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET(fmt.Sprintf("%s/chats/:user_id", apiVersion), app.getChatStatus)
+	url := fmt.Sprintf("%s/chats/%s", apiVersion, userID)
+
+	t.Run("Should get the status of the chat", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		status := &manage.ChatStatus{
+			UserID:          userID,
+			Status:          manage.Active,
+			Provider:        manage.WhatsappProvider,
+			BotSlug:         botSlug,
+			CaseID:          "caseID",
+			CaseNumber:      "00001234",
+			OwnsLongPolling: true,
+		}
+		managerMock.On("GetChatStatus", userID).Return(status, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(status)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should return not found when there is no chat", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("GetChatStatus", userID).Return(nil, constants.ErrInterconnectionNotFound).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Equal(t, `{"ErrorDescription":"could not get the chat status : not found interconnection"}`, response.Body.String())
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("GetChatStatus", userID).Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
+
+func TestListChats(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET(fmt.Sprintf("%s/chats", apiVersion), app.listChats)
+	url := fmt.Sprintf("%s/chats", apiVersion)
+
+	t.Run("Should list the chats with the default pagination", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		chats := &manage.ChatList{
+			Chats:  []manage.ChatStatus{{UserID: userID, Status: manage.Active}},
+			Total:  1,
+			Page:   1,
+			Size:   10,
+			Counts: map[manage.InterconnectionStatus]int{manage.Active: 1},
+		}
+		managerMock.On("ListChats", manage.ChatFilter{Page: 1, Size: 10}).Return(chats, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(chats)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should list the chats with filters", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		filter := manage.ChatFilter{
+			Status:    manage.OnHold,
+			Provider:  manage.WhatsappProvider,
+			BotSlug:   botSlug,
+			MinAge:    30 * time.Minute,
+			MaxAge:    2 * time.Hour,
+			Ascending: true,
+			Page:      2,
+			Size:      5,
+		}
+		managerMock.On("ListChats", filter).Return(&manage.ChatList{}, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url+"?status=on_hold&provider=whatsapp&botSlug=coppel-bot&minAge=30m&maxAge=2h&sort=asc&page=2&size=5", nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		managerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return bad request with invalid params", func(t *testing.T) {
+		for _, query := range []string{"?minAge=old", "?maxAge=1", "?sort=name", "?page=a&size=1", "?page=0&size=10"} {
+			managerMock := new(mocks.ManagerI)
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("GET", url+query, nil)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, query)
+			managerMock.AssertNotCalled(t, "ListChats", mock.Anything)
+		}
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("ListChats", mock.Anything).Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
+
+func TestCloseChats(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.POST(fmt.Sprintf("%s/chats/close", apiVersion), app.closeChats)
+	url := fmt.Sprintf("%s/chats/close", apiVersion)
+
+	t.Run("Should run a dry-run by default", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		report := &manage.CloseChatsReport{
+			DryRun:  true,
+			Matched: 1,
+			Chats:   []manage.CloseChatResult{{UserID: userID, Status: manage.Active}},
+		}
+		managerMock.On("CloseChats", manage.CloseChatsFilter{
+			Statuses:  []manage.InterconnectionStatus{manage.Active},
+			OlderThan: 6 * time.Hour,
+			DryRun:    true,
+		}).Return(report, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(`{"statuses":["active"],"olderThan":"6h"}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(report)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should close the chats of the users", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CloseChats", manage.CloseChatsFilter{
+			UserIDs:  []string{userID},
+			Provider: manage.WhatsappProvider,
+			BotSlug:  botSlug,
+			BotState: "closed",
+		}).Return(&manage.CloseChatsReport{}, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(
+			`{"userIds":["5217331175599"],"provider":"whatsapp","botSlug":"coppel-bot","botState":"closed","dryRun":false}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		managerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return bad request with an invalid payload", func(t *testing.T) {
+		payloads := []string{
+			`{`,
+			`{"provider":"whatsapp"}`,
+			`{"statuses":["CLOSED"]}`,
+			`{"olderThan":"yesterday"}`,
+		}
+		for _, payload := range payloads {
+			managerMock := new(mocks.ManagerI)
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, payload)
+			managerMock.AssertNotCalled(t, "CloseChats", mock.Anything)
+		}
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CloseChats", mock.Anything).Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(`{"olderThan":"6h"}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/health"
 	"yalochat.com/salesforce-integration/base/helpers"
 )
 
@@ -26,3 +27,23 @@ func (app *App) welcomeAPI(w http.ResponseWriter, r *http.Request, params httpro
 		helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "Welcome to API!"})
 	}
 }
+
+// liveness is the liveness probe, it only validates that the API responds
+func (app *App) liveness(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	helpers.WriteSuccessResponse(w, health.Report{Status: health.Up})
+}
+
+// readiness is the readiness probe, it responds 503 when a critical dependency is down
+func (app *App) readiness(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	report := app.ManageManager.CheckReadiness(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == health.Down {
+		logrus.WithField("components", report.Components).Error("The app is not ready")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	result, _ := helpers.MarshalJSON(report)
+	w.Write(result)
+}
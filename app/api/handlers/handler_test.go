@@ -2,13 +2,22 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-redis/redis"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
+	"yalochat.com/salesforce-integration/app/api/handlers/mocks"
+	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/health"
 )
 
 const welcomeURL = "/v1/welcome"
@@ -51,3 +60,90 @@ func TestWelcomeAPI(t *testing.T) {
 		}
 	})
 }
+
+func TestLiveness(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET("/healthz", app.liveness)
+
+	t.Run("Should return a http response OK", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/healthz", nil)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"status":"up"}`, response.Body.String())
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET("/readyz", app.readiness)
+
+	t.Run("Should return a http response OK when the app is degraded", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CheckReadiness", mock.Anything).Return(health.Report{
+			Status: health.Degraded,
+			Components: map[string]health.ComponentReport{
+				"redis":        {Status: health.Up, Critical: true},
+				"integrations": {Status: health.Down, Error: "connection refused"},
+			},
+		}).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		var report health.Report
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, health.Degraded, report.Status)
+		assert.Equal(t, "connection refused", report.Components["integrations"].Error)
+	})
+
+	t.Run("Should return a http response unavailable when a critical component is down", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("CheckReadiness", mock.Anything).Return(health.Report{
+			Status: health.Down,
+			Components: map[string]health.ComponentReport{
+				"redis": {Status: health.Down, Critical: true, Error: "connection refused"},
+			},
+		}).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.Contains(t, response.Body.String(), `"status":"down"`)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	m, s := cache.CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	redisOptions := cache.RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+		SessionsTTL: time.Second,
+	}
+	handler := ddrouter.New(ddrouter.WithServiceName("appName.http"))
+	managerOptions := &manage.ManagerOptions{AppName: "appName", Client: "coppel", RedisOptions: redisOptions}
+	API(handler, managerOptions, apiConfig)
+
+	t.Run("Should return the metrics with the client label", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/metrics", nil)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "go_goroutines")
+		assert.Contains(t, response.Body.String(), `salesforce_integration_file_upload_size_bytes_count{client="coppel"}`)
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+// List the attempts to deliver the lifecycle webhooks to the subscribers, the newest attempts first
+func (app *App) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter, err := getWebhookDeliveryFilter(r)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	deliveries, err := app.ManageManager.ListWebhookDeliveries(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Could not list the webhook deliveries")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not list the webhook deliveries", err))
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, deliveries)
+}
+
+// getWebhookDeliveryFilter reads the filter of the webhook deliveries from the query params
+func getWebhookDeliveryFilter(r *http.Request) (manage.WebhookDeliveryFilter, error) {
+	query := r.URL.Query()
+	filter := manage.WebhookDeliveryFilter{
+		Status:     cache.WebhookDeliveryStatus(query.Get("status")),
+		EventType:  manage.LifecycleEventType(query.Get("eventType")),
+		Subscriber: query.Get("subscriber"),
+	}
+
+	switch filter.Status {
+	case "", cache.WebhookPending, cache.WebhookDelivered, cache.WebhookFailed:
+	default:
+		return filter, fmt.Errorf("invalid status: %s", filter.Status)
+	}
+
+	var err error
+	filter.Page, filter.Size, err = helpers.GetPaginationValues(r)
+	if err != nil {
+		return filter, fmt.Errorf("invalid pagination: %w", err)
+	}
+	if filter.Page < 1 || filter.Size < 1 {
+		return filter, fmt.Errorf("invalid pagination: page %d and size %d", filter.Page, filter.Size)
+	}
+	return filter, nil
+}
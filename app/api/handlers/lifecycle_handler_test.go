@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
+	"yalochat.com/salesforce-integration/app/api/handlers/mocks"
+	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/cache"
+)
+
+func TestListWebhookDeliveries(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.GET(fmt.Sprintf("%s/webhooks/deliveries", apiVersion), app.listWebhookDeliveries)
+	url := fmt.Sprintf("%s/webhooks/deliveries", apiVersion)
+
+	t.Run("Should list the deliveries with the default pagination", func(t *testing.T) {
+		list := &manage.WebhookDeliveryList{
+			Deliveries: []cache.WebhookDelivery{{
+				ID:         "deliveryID",
+				Subscriber: "crm",
+				EventID:    "eventID",
+				EventType:  string(manage.ChatCreatedEvent),
+				Status:     cache.WebhookDelivered,
+				Attempts:   1,
+			}},
+			Total: 1,
+			Page:  1,
+			Size:  10,
+		}
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("ListWebhookDeliveries", manage.WebhookDeliveryFilter{Page: 1, Size: 10}).Return(list, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(list)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should list the deliveries with filters", func(t *testing.T) {
+		filter := manage.WebhookDeliveryFilter{
+			Status:     cache.WebhookFailed,
+			EventType:  manage.ChatEndedEvent,
+			Subscriber: "crm",
+			Page:       2,
+			Size:       5,
+		}
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("ListWebhookDeliveries", filter).Return(&manage.WebhookDeliveryList{}, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url+"?status=failed&eventType=chat.ended&subscriber=crm&page=2&size=5", nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		managerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return bad request with invalid params", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		getApp().ManageManager = managerMock
+
+		for _, query := range []string{"?status=sent", "?page=0&size=10", "?page=a&size=10"} {
+			req, _ := http.NewRequest("GET", url+query, nil)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, query)
+		}
+		managerMock.AssertNotCalled(t, "ListWebhookDeliveries", manage.WebhookDeliveryFilter{})
+	})
+
+	t.Run("Should return internal error when redis fails", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("ListWebhookDeliveries", manage.WebhookDeliveryFilter{Page: 1, Size: 10}).Return(nil, assert.AnError).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
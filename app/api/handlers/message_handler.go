@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+// UserMessagePayload is a message from Salesforce to the user, it needs the userID or, in whatsapp, the phoneNumber,
+// and the text or a media. Without caseId the message is logged in the case of the last chat of the user
+type UserMessagePayload struct {
+	UserID      string `json:"userID" validate:"required_without=PhoneNumber"`
+	PhoneNumber string `json:"phoneNumber" validate:"required_without=UserID"`
+	Provider    string `json:"provider" validate:"required,oneof=whatsapp facebook"`
+	BotSlug     string `json:"botSlug" validate:"required"`
+	CaseID      string `json:"caseId"`
+	Text        string `json:"text" validate:"required_without=MediaURL"`
+	MediaURL    string `json:"mediaUrl" validate:"omitempty,url"`
+	MediaType   string `json:"mediaType" validate:"required_with=MediaURL,omitempty,oneof=image video audio document"`
+}
+
+// Send a message from Salesforce to the user outside a chat, the message is logged in the case
+func (app *App) sendMessageToUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	payload := &UserMessagePayload{}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	if err := helpers.Govalidator().Struct(payload); err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.ValidatePayloadError, err))
+		return
+	}
+
+	result, err := app.ManageManager.SendMessageToUser(r.Context(), manage.OutboundMessage{
+		UserID:      payload.UserID,
+		PhoneNumber: payload.PhoneNumber,
+		Provider:    manage.Provider(payload.Provider),
+		BotSlug:     payload.BotSlug,
+		CaseID:      payload.CaseID,
+		Text:        payload.Text,
+		MediaURL:    payload.MediaURL,
+		MediaType:   payload.MediaType,
+	})
+	switch {
+	case errors.Is(err, constants.ErrInvalidMessage):
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	case errors.Is(err, constants.ErrCaseNotFound):
+		helpers.WriteFailedResponse(w, http.StatusNotFound, helpers.ErrorMessage("could not send the message", err))
+		return
+	case err != nil:
+		logrus.WithField(events.Payload, payload).WithError(err).Error("Could not send the message to the user")
+		helpers.WriteFailedResponse(w, http.StatusInternalServerError, helpers.ErrorMessage("could not send the message", err))
+		return
+	}
+
+	helpers.WriteSuccessResponse(w, result)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
+	"yalochat.com/salesforce-integration/app/api/handlers/mocks"
+	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+func TestSendMessageToUser(t *testing.T) {
+	handler := ddrouter.New(ddrouter.WithServiceName("salesforce-integration.http"))
+	handler.POST(fmt.Sprintf("%s/messages", apiVersion), app.sendMessageToUser)
+	url := fmt.Sprintf("%s/messages", apiVersion)
+
+	t.Run("Should send the message to the user", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		result := &manage.OutboundMessageResult{
+			ID:       "messageID",
+			UserID:   userID,
+			Provider: manage.WhatsappProvider,
+			CaseID:   "caseID",
+		}
+		managerMock.On("SendMessageToUser", mock.Anything, manage.OutboundMessage{
+			UserID:    userID,
+			Provider:  manage.WhatsappProvider,
+			BotSlug:   botSlug,
+			CaseID:    "caseID",
+			Text:      "Your case was resolved",
+			MediaURL:  "https://example.com/image.png",
+			MediaType: "image",
+		}).Return(result, nil).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("POST", url, strings.NewReader(`{"userID":"5217331175599","provider":"whatsapp",`+
+			`"botSlug":"coppel-bot","caseId":"caseID","text":"Your case was resolved",`+
+			`"mediaUrl":"https://example.com/image.png","mediaType":"image"}`))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expected, err := json.Marshal(result)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expected), response.Body.String())
+	})
+
+	t.Run("Should return bad request with an invalid payload", func(t *testing.T) {
+		payloads := []string{
+			`{`,
+			`{"provider":"whatsapp","botSlug":"coppel-bot","text":"Hola"}`,
+			`{"userID":"5217331175599","provider":"telegram","botSlug":"coppel-bot","text":"Hola"}`,
+			`{"userID":"5217331175599","provider":"whatsapp","text":"Hola"}`,
+			`{"userID":"5217331175599","provider":"whatsapp","botSlug":"coppel-bot"}`,
+			`{"userID":"5217331175599","provider":"whatsapp","botSlug":"coppel-bot","mediaUrl":"https://example.com/image.png"}`,
+			`{"userID":"5217331175599","provider":"whatsapp","botSlug":"coppel-bot","mediaUrl":"image","mediaType":"image"}`,
+		}
+		for _, payload := range payloads {
+			managerMock := new(mocks.ManagerI)
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("POST", url, strings.NewReader(payload))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, payload)
+			managerMock.AssertNotCalled(t, "SendMessageToUser", mock.Anything, mock.Anything)
+		}
+	})
+
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "Should return bad request with an invalid message", err: fmt.Errorf("%w: the userID is required", constants.ErrInvalidMessage), status: http.StatusBadRequest},
+		{name: "Should return not found when there is no case", err: constants.ErrCaseNotFound, status: http.StatusNotFound},
+		{name: "Should return internal error when salesforce fails", err: assert.AnError, status: http.StatusInternalServerError},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			managerMock := new(mocks.ManagerI)
+			managerMock.On("SendMessageToUser", mock.Anything, mock.Anything).Return(nil, testCase.err).Once()
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("POST", url, strings.NewReader(`{"phoneNumber":"5217331175599","provider":"facebook","botSlug":"coppel-bot","text":"Hola"}`))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, testCase.status, response.Code)
+		})
+	}
+}
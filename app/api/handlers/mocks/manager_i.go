@@ -7,6 +7,8 @@ import (
 
 	cache "yalochat.com/salesforce-integration/base/cache"
 
+	health "yalochat.com/salesforce-integration/base/health"
+
 	manage "yalochat.com/salesforce-integration/app/manage"
 
 	mock "github.com/stretchr/testify/mock"
@@ -19,6 +21,43 @@ type ManagerI struct {
 	mock.Mock
 }
 
+// CheckReadiness provides a mock function with given fields: ctx
+func (_m *ManagerI) CheckReadiness(ctx context.Context) health.Report {
+	ret := _m.Called(ctx)
+
+	var r0 health.Report
+	if rf, ok := ret.Get(0).(func(context.Context) health.Report); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(health.Report)
+	}
+
+	return r0
+}
+
+// CloseChats provides a mock function with given fields: filter
+func (_m *ManagerI) CloseChats(filter manage.CloseChatsFilter) (*manage.CloseChatsReport, error) {
+	ret := _m.Called(filter)
+
+	var r0 *manage.CloseChatsReport
+	if rf, ok := ret.Get(0).(func(manage.CloseChatsFilter) *manage.CloseChatsReport); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manage.CloseChatsReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(manage.CloseChatsFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateChat provides a mock function with given fields: ctx, interconnection
 func (_m *ManagerI) CreateChat(ctx context.Context, interconnection *manage.Interconnection) error {
 	ret := _m.Called(ctx, interconnection)
@@ -33,6 +72,80 @@ func (_m *ManagerI) CreateChat(ctx context.Context, interconnection *manage.Inte
 	return r0
 }
 
+// CreateChatAsync provides a mock function with given fields: ctx, requestID, callbackURL, interconnection
+func (_m *ManagerI) CreateChatAsync(ctx context.Context, requestID string, callbackURL string, interconnection *manage.Interconnection) (*cache.ChatRequest, error) {
+	ret := _m.Called(ctx, requestID, callbackURL, interconnection)
+
+	var r0 *cache.ChatRequest
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *manage.Interconnection) *cache.ChatRequest); ok {
+		r0 = rf(ctx, requestID, callbackURL, interconnection)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*cache.ChatRequest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *manage.Interconnection) error); ok {
+		r1 = rf(ctx, requestID, callbackURL, interconnection)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateChatIdempotent provides a mock function with given fields: ctx, idempotencyKey, interconnection
+func (_m *ManagerI) CreateChatIdempotent(ctx context.Context, idempotencyKey string, interconnection *manage.Interconnection) error {
+	ret := _m.Called(ctx, idempotencyKey, interconnection)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *manage.Interconnection) error); ok {
+		r0 = rf(ctx, idempotencyKey, interconnection)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateProactiveChat provides a mock function with given fields: ctx, interconnection, contactID, buttonID, text
+func (_m *ManagerI) CreateProactiveChat(ctx context.Context, interconnection *manage.Interconnection, contactID string, buttonID string, text string) error {
+	ret := _m.Called(ctx, interconnection, contactID, buttonID, text)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *manage.Interconnection, string, string, string) error); ok {
+		r0 = rf(ctx, interconnection, contactID, buttonID, text)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FilterContextByUserID provides a mock function with given fields: userID, filter
+func (_m *ManagerI) FilterContextByUserID(userID string, filter manage.ContextFilter) ([]cache.Context, int) {
+	ret := _m.Called(userID, filter)
+
+	var r0 []cache.Context
+	if rf, ok := ret.Get(0).(func(string, manage.ContextFilter) []cache.Context); ok {
+		r0 = rf(userID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cache.Context)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, manage.ContextFilter) int); ok {
+		r1 = rf(userID, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	return r0, r1
+}
+
 // FinishChat provides a mock function with given fields: userID
 func (_m *ManagerI) FinishChat(userID string) error {
 	ret := _m.Called(userID)
@@ -47,6 +160,52 @@ func (_m *ManagerI) FinishChat(userID string) error {
 	return r0
 }
 
+// GetChatRequest provides a mock function with given fields: requestID
+func (_m *ManagerI) GetChatRequest(requestID string) (*cache.ChatRequest, error) {
+	ret := _m.Called(requestID)
+
+	var r0 *cache.ChatRequest
+	if rf, ok := ret.Get(0).(func(string) *cache.ChatRequest); ok {
+		r0 = rf(requestID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*cache.ChatRequest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(requestID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetChatStatus provides a mock function with given fields: userID
+func (_m *ManagerI) GetChatStatus(userID string) (*manage.ChatStatus, error) {
+	ret := _m.Called(userID)
+
+	var r0 *manage.ChatStatus
+	if rf, ok := ret.Get(0).(func(string) *manage.ChatStatus); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manage.ChatStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetContextByUserID provides a mock function with given fields: userID
 func (_m *ManagerI) GetContextByUserID(userID string) []cache.Context {
 	ret := _m.Called(userID)
@@ -63,6 +222,52 @@ func (_m *ManagerI) GetContextByUserID(userID string) []cache.Context {
 	return r0
 }
 
+// ListChats provides a mock function with given fields: filter
+func (_m *ManagerI) ListChats(filter manage.ChatFilter) (*manage.ChatList, error) {
+	ret := _m.Called(filter)
+
+	var r0 *manage.ChatList
+	if rf, ok := ret.Get(0).(func(manage.ChatFilter) *manage.ChatList); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manage.ChatList)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(manage.ChatFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: filter
+func (_m *ManagerI) ListWebhookDeliveries(filter manage.WebhookDeliveryFilter) (*manage.WebhookDeliveryList, error) {
+	ret := _m.Called(filter)
+
+	var r0 *manage.WebhookDeliveryList
+	if rf, ok := ret.Get(0).(func(manage.WebhookDeliveryFilter) *manage.WebhookDeliveryList); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manage.WebhookDeliveryList)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(manage.WebhookDeliveryFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterWebhookInIntegrations provides a mock function with given fields: provider
 func (_m *ManagerI) RegisterWebhookInIntegrations(provider string) error {
 	ret := _m.Called(provider)
@@ -91,6 +296,20 @@ func (_m *ManagerI) RemoveWebhookInIntegrations(provider string) error {
 	return r0
 }
 
+// RenderContextByUserID provides a mock function with given fields: userID, allContext
+func (_m *ManagerI) RenderContextByUserID(userID string, allContext []cache.Context) string {
+	ret := _m.Called(userID, allContext)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, []cache.Context) string); ok {
+		r0 = rf(userID, allContext)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // SaveContext provides a mock function with given fields: ctx, integration
 func (_m *ManagerI) SaveContext(ctx context.Context, integration *models.IntegrationsRequest) error {
 	ret := _m.Called(ctx, integration)
@@ -119,6 +338,43 @@ func (_m *ManagerI) SaveContextFB(ctx context.Context, integration *models.Integ
 	return r0
 }
 
+// SendMessageToUser provides a mock function with given fields: ctx, message
+func (_m *ManagerI) SendMessageToUser(ctx context.Context, message manage.OutboundMessage) (*manage.OutboundMessageResult, error) {
+	ret := _m.Called(ctx, message)
+
+	var r0 *manage.OutboundMessageResult
+	if rf, ok := ret.Get(0).(func(context.Context, manage.OutboundMessage) *manage.OutboundMessageResult); ok {
+		r0 = rf(ctx, message)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manage.OutboundMessageResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, manage.OutboundMessage) error); ok {
+		r1 = rf(ctx, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateMessageStatus provides a mock function with given fields: ctx, integration
+func (_m *ManagerI) UpdateMessageStatus(ctx context.Context, integration *models.IntegrationsRequest) error {
+	ret := _m.Called(ctx, integration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IntegrationsRequest) error); ok {
+		r0 = rf(ctx, integration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewManagerI interface {
 	mock.TestingT
 	Cleanup(func())
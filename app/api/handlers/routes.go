@@ -5,10 +5,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http/pprof"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
 	"yalochat.com/salesforce-integration/app/manage"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/metrics"
 )
 
 // App contains the resources related to the application
@@ -22,6 +26,16 @@ type App struct {
 	SecretKey             string
 	IntegrationsSignature string
 	IgnoreMessageTypes    string
+	SignatureVerifier     *signatureVerifier
+	// SecretKeyID is the kid of the tokens signed with SecretKey, SecretKeys are the previous secrets by kid
+	// that are still accepted
+	SecretKeyID       string
+	SecretKeys        map[string]string
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	AllowLegacyTokens bool
+	// RefreshTokenCache remembers the refresh tokens that were used, they are single-use
+	RefreshTokenCache cache.IRefreshTokenCache
 }
 
 type ApiConfig struct {
@@ -33,6 +47,15 @@ type ApiConfig struct {
 	IntegrationsSignature string
 	IgnoreMessageTypes    string
 	UseProfile            bool
+	// SignatureClockSkew and SignatureReplayWindow configure the verification of the signature of the webhooks
+	SignatureClockSkew    time.Duration
+	SignatureReplayWindow time.Duration
+	SignatureReportOnly   bool
+	SecretKeyID           string
+	SecretKeys            map[string]string
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	AllowLegacyTokens     bool
 }
 
 const apiVersion = "/v1"
@@ -71,6 +94,32 @@ func API(srv *ddrouter.Router, managerOptions *manage.ManagerOptions, apiConfig
 		SecretKey:             apiConfig.SecretKey,
 		IntegrationsSignature: apiConfig.IntegrationsSignature,
 		IgnoreMessageTypes:    apiConfig.IgnoreMessageTypes,
+		SecretKeyID:           apiConfig.SecretKeyID,
+		SecretKeys:            apiConfig.SecretKeys,
+		AccessTokenTTL:        apiConfig.AccessTokenTTL,
+		RefreshTokenTTL:       apiConfig.RefreshTokenTTL,
+		AllowLegacyTokens:     apiConfig.AllowLegacyTokens,
+		SignatureVerifier: newSignatureVerifier(apiConfig.IntegrationsSignature, managerOptions.Client,
+			apiConfig.SignatureClockSkew, apiConfig.SignatureReplayWindow, apiConfig.SignatureReportOnly,
+			newSignatureCache(managerOptions, apiConfig)),
+	}
+
+	if apiConfig.IntegrationsSignature == "" {
+		logrus.Warn("The signature of the webhooks is not verified, there is no integrations signature")
+	} else if apiConfig.SignatureReportOnly {
+		logrus.Warn("The invalid signatures of the webhooks are only reported")
+	}
+
+	if apiConfig.AllowLegacyTokens {
+		logrus.Warn("The tokens without expiration are accepted")
+	}
+
+	if app.RefreshTokenTTL > 0 {
+		app.RefreshTokenCache = newRefreshTokenCache(managerOptions)
+		if app.RefreshTokenCache == nil {
+			logrus.Error("The refresh tokens are disabled, they need redis to be used only once")
+			app.RefreshTokenTTL = 0
+		}
 	}
 
 	if len(apiConfig.IgnoreMessageTypes) > 0 {
@@ -81,13 +130,33 @@ func API(srv *ddrouter.Router, managerOptions *manage.ManagerOptions, apiConfig
 
 	setApp(*app)
 
+	registry, err := metrics.NewRegistry(managerOptions.Client, manager.MetricsCollector())
+	if err != nil {
+		logrus.WithError(err).Error("Could not register the metrics")
+	}
+
 	srv.GET(fmt.Sprintf("%s/welcome", apiVersion), app.welcomeAPI)
+	srv.GET("/healthz", app.liveness)
+	srv.GET("/readyz", app.readiness)
+	if registry != nil {
+		srv.Handler("GET", "/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	}
 	srv.POST(fmt.Sprintf("%s/authenticate", apiVersion), app.authenticate)
+	if app.RefreshTokenTTL > 0 {
+		srv.POST(fmt.Sprintf("%s/tokens/refresh", apiVersion), app.refreshToken)
+	}
 	srv.GET(fmt.Sprintf("%s/tokens/check", apiVersion), app.authorizeMiddleware(app.getUserByToken, []RoleType{Yalo, Salesforce}))
 	srv.POST(fmt.Sprintf("%s/chats/connect", apiVersion), app.authorizeMiddleware(app.createChat, []RoleType{Yalo}))
-	srv.POST(managerOptions.WebhookWhatsapp, app.webhook)
+	srv.POST(fmt.Sprintf("%s/chats/proactive", apiVersion), app.authorizeMiddleware(app.createProactiveChat, []RoleType{Salesforce}))
+	srv.POST(fmt.Sprintf("%s/chats/close", apiVersion), app.authorizeMiddleware(app.closeChats, []RoleType{Yalo}))
+	srv.GET(fmt.Sprintf("%s/chats", apiVersion), app.authorizeMiddleware(app.listChats, []RoleType{Yalo}))
+	srv.GET(fmt.Sprintf("%s/chats/:user_id", apiVersion), app.authorizeMiddleware(app.getChatStatus, []RoleType{Yalo}))
+	srv.GET(fmt.Sprintf("%s/chat-requests/:request_id", apiVersion), app.authorizeMiddleware(app.getChatRequest, []RoleType{Yalo}))
+	srv.POST(fmt.Sprintf("%s/messages", apiVersion), app.authorizeMiddleware(app.sendMessageToUser, []RoleType{Salesforce}))
+	srv.GET(fmt.Sprintf("%s/webhooks/deliveries", apiVersion), app.authorizeMiddleware(app.listWebhookDeliveries, []RoleType{Yalo}))
+	srv.POST(managerOptions.WebhookWhatsapp, app.verifySignatureMiddleware(app.webhook))
 	srv.GET(fmt.Sprintf("%s/context/:user_id", apiVersion), app.authorizeMiddleware(app.getContext, []RoleType{Yalo}))
-	srv.POST(managerOptions.WebhookFacebook, app.webhookFB)
+	srv.POST(managerOptions.WebhookFacebook, app.verifySignatureMiddleware(app.webhookFB))
 	srv.DELETE(fmt.Sprintf("%s/chat/finish/:user_id", apiVersion), app.authorizeMiddleware(app.finishChat, []RoleType{Yalo}))
 	srv.POST(fmt.Sprintf("%s/integrations/webhook/register/:provider", apiVersion), app.authorizeMiddleware(app.registerWebhook, []RoleType{Yalo}))
 	srv.DELETE(fmt.Sprintf("%s/integrations/webhook/remove/:provider", apiVersion), app.authorizeMiddleware(app.removeWebhook, []RoleType{Yalo}))
@@ -106,3 +175,33 @@ func API(srv *ddrouter.Router, managerOptions *manage.ManagerOptions, apiConfig
 	}
 
 }
+
+// newSignatureCache returns the cache of the signatures of the webhooks shared by the instances, it's nil when the
+// replays are not checked
+func newSignatureCache(managerOptions *manage.ManagerOptions, apiConfig ApiConfig) cache.ISignatureCache {
+	if apiConfig.IntegrationsSignature == "" || apiConfig.SignatureReplayWindow <= 0 {
+		return nil
+	}
+
+	redisCache, err := cache.NewRedisCache(&managerOptions.RedisOptions)
+	if err != nil {
+		logrus.WithError(err).Error("Error initializing the redis cache of the webhook signatures")
+	}
+	if redisCache == nil {
+		return nil
+	}
+	return cache.NewSignatureCache(redisCache)
+}
+
+// newRefreshTokenCache returns the cache of the refresh tokens used in all the instances, it's nil when redis
+// is not available
+func newRefreshTokenCache(managerOptions *manage.ManagerOptions) cache.IRefreshTokenCache {
+	redisCache, err := cache.NewRedisCache(&managerOptions.RedisOptions)
+	if err != nil {
+		logrus.WithError(err).Error("Error initializing the redis cache of the refresh tokens")
+	}
+	if redisCache == nil {
+		return nil
+	}
+	return cache.NewRefreshTokenCache(redisCache)
+}
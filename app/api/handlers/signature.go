@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+const (
+	signatureHeader = "X-Yalochat-Signature"
+	timestampHeader = "X-Yalochat-Timestamp"
+	signaturePrefix = "sha256="
+	// maxWebhookBodySize is the maximum size of the body of the webhook requests that is read to verify the signature
+	maxWebhookBodySize = 1 << 20
+)
+
+// signatureVerifier verifies the HMAC-SHA256 signature of the requests of the Integrations API, the signature is
+// the hex of the HMAC of "<timestamp>.<body>" with the secret, the timestamp is in unix seconds.
+// The Integrations API does not publish a signature spec for its webhooks, the headers and the "<timestamp>.<body>"
+// scheme are defined by this app, they are the ones that it uses to sign its callbacks and lifecycle webhooks.
+// The webhook of Integrations must be configured to sign the requests with them, that's why the report-only mode
+// is the default
+type signatureVerifier struct {
+	secret []byte
+	client string
+	// clockSkew is the maximum difference between the timestamp of the request and the clock of the app
+	clockSkew time.Duration
+	// replayWindow is the time that the signature of a processed request is remembered in redis to reject the
+	// requests that are sent again
+	replayWindow   time.Duration
+	signatureCache cache.ISignatureCache
+	// reportOnly logs the invalid requests without rejecting them
+	reportOnly bool
+	now        func() time.Time
+}
+
+func newSignatureVerifier(secret, client string, clockSkew, replayWindow time.Duration, reportOnly bool,
+	signatureCache cache.ISignatureCache) *signatureVerifier {
+	return &signatureVerifier{
+		secret:         []byte(secret),
+		client:         client,
+		clockSkew:      clockSkew,
+		replayWindow:   replayWindow,
+		signatureCache: signatureCache,
+		reportOnly:     reportOnly,
+		now:            time.Now,
+	}
+}
+
+// verifySignatureMiddleware rejects the requests without a valid signature, the requests are not verified
+// when there is no secret
+func (app *App) verifySignatureMiddleware(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		verifier := app.SignatureVerifier
+		if verifier == nil || len(verifier.secret) == 0 {
+			next(w, req, params)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBodySize))
+		if err != nil {
+			helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		err = verifier.verify(req.Header.Get(signatureHeader), req.Header.Get(timestampHeader), body)
+		if err != nil {
+			logFields := logrus.Fields{
+				"path":       req.URL.Path,
+				"reportOnly": verifier.reportOnly,
+			}
+			if verifier.reportOnly {
+				logrus.WithFields(logFields).WithError(err).Warn("Invalid webhook signature")
+				next(w, req, params)
+				return
+			}
+			logrus.WithFields(logFields).WithError(err).Error("Invalid webhook signature")
+			helpers.WriteFailedResponse(w, http.StatusUnauthorized, helpers.ErrorMessage("invalid signature", err))
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, req, params)
+		if recorder.status >= http.StatusInternalServerError {
+			verifier.forgetSignature(req.Header.Get(signatureHeader))
+		}
+	})
+}
+
+// statusRecorder keeps the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (v *signatureVerifier) verify(signature, timestamp string, body []byte) error {
+	if signature == "" || timestamp == "" {
+		return fmt.Errorf("the headers %s and %s are required", signatureHeader, timestampHeader)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %s", timestamp)
+	}
+
+	now := v.now()
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > v.clockSkew {
+		return fmt.Errorf("the timestamp %s is out of the clock skew of %s", timestamp, v.clockSkew)
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return errors.New("the signature must be hexadecimal")
+	}
+
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return errors.New("the signature does not match")
+	}
+
+	return v.checkReplay(hex.EncodeToString(received))
+}
+
+// checkReplay rejects the signatures that were received by any instance in the replay window, the requests are
+// accepted when redis fails because the signature is already valid. The signature is stored before the request is
+// processed so that the concurrent replays are rejected too, it is forgotten when the request fails
+func (v *signatureVerifier) checkReplay(signature string) error {
+	if v.replayWindow <= 0 || v.signatureCache == nil {
+		return nil
+	}
+
+	stored, err := v.signatureCache.StoreSignature(v.client, signature, v.replayWindow)
+	if err != nil {
+		logrus.WithError(err).Error("Could not store the webhook signature to check the replays")
+		return nil
+	}
+	if !stored {
+		return errors.New("the request was already received")
+	}
+	return nil
+}
+
+// forgetSignature deletes the signature of a request that failed with a server error, so that the retry of the
+// request is processed instead of being rejected as a replay
+func (v *signatureVerifier) forgetSignature(signature string) {
+	if v.replayWindow <= 0 || v.signatureCache == nil {
+		return
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return
+	}
+	if err := v.signatureCache.DeleteSignature(v.client, hex.EncodeToString(received)); err != nil {
+		logrus.WithError(err).Error("Could not delete the webhook signature of the failed request")
+	}
+}
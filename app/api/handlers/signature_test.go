@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/base/cache"
+)
+
+const signatureSecret = "integrationsSignature"
+
+func sign(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignatureMiddleware(t *testing.T) {
+	now := time.Unix(1636646674, 0)
+	body := `{"id":"id","type":"text","from":"5555555555","text":{"body":"Hello"}}`
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	m, s := cache.CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	redisCache, _ := cache.NewRedisCache(&cache.RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	})
+	signatureCache := cache.NewSignatureCache(redisCache)
+
+	newApp := func(secret string, reportOnly bool) *App {
+		m.FlushAll()
+		verifier := newSignatureVerifier(secret, "client", 5*time.Minute, 10*time.Minute, reportOnly, signatureCache)
+		verifier.now = func() time.Time { return now }
+		return &App{SignatureVerifier: verifier}
+	}
+
+	serve := func(app *App, signature, timestamp string) (*httptest.ResponseRecorder, string) {
+		var received string
+		handler := app.verifySignatureMiddleware(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			data, _ := ioutil.ReadAll(r.Body)
+			received = string(data)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req, _ := http.NewRequest("POST", "/v1/integrations/whatsapp/webhook", strings.NewReader(body))
+		if signature != "" {
+			req.Header.Set(signatureHeader, signature)
+		}
+		if timestamp != "" {
+			req.Header.Set(timestampHeader, timestamp)
+		}
+		response := httptest.NewRecorder()
+		handler(response, req, nil)
+		return response, received
+	}
+
+	t.Run("Should accept a valid signature", func(t *testing.T) {
+		response, received := serve(newApp(signatureSecret, false), sign(signatureSecret, timestamp, body), timestamp)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, body, received)
+	})
+
+	t.Run("Should accept a valid signature with the prefix", func(t *testing.T) {
+		response, _ := serve(newApp(signatureSecret, false), signaturePrefix+sign(signatureSecret, timestamp, body), timestamp)
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Should accept a timestamp in the clock skew", func(t *testing.T) {
+		skewed := strconv.FormatInt(now.Add(-4*time.Minute).Unix(), 10)
+		response, _ := serve(newApp(signatureSecret, false), sign(signatureSecret, skewed, body), skewed)
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Should not verify without a secret", func(t *testing.T) {
+		response, received := serve(newApp("", false), "", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, body, received)
+	})
+
+	t.Run("Should reject the invalid signatures", func(t *testing.T) {
+		old := strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10)
+		future := strconv.FormatInt(now.Add(6*time.Minute).Unix(), 10)
+		testCases := []struct {
+			name      string
+			signature string
+			timestamp string
+			error     string
+		}{
+			{"without headers", "", "", "the headers X-Yalochat-Signature and X-Yalochat-Timestamp are required"},
+			{"invalid timestamp", sign(signatureSecret, timestamp, body), "yesterday", "invalid timestamp: yesterday"},
+			{"old timestamp", sign(signatureSecret, old, body), old, "is out of the clock skew of 5m0s"},
+			{"future timestamp", sign(signatureSecret, future, body), future, "is out of the clock skew of 5m0s"},
+			{"not hexadecimal", "signature", timestamp, "the signature must be hexadecimal"},
+			{"other secret", sign("other", timestamp, body), timestamp, "the signature does not match"},
+			{"other timestamp", sign(signatureSecret, old, body), timestamp, "the signature does not match"},
+		}
+
+		for _, tc := range testCases {
+			response, received := serve(newApp(signatureSecret, false), tc.signature, tc.timestamp)
+			assert.Equal(t, http.StatusUnauthorized, response.Code, tc.name)
+			assert.Contains(t, response.Body.String(), tc.error, tc.name)
+			assert.Empty(t, received, tc.name)
+		}
+	})
+
+	t.Run("Should reject a replayed request", func(t *testing.T) {
+		app := newApp(signatureSecret, false)
+		signature := sign(signatureSecret, timestamp, body)
+
+		response, _ := serve(app, signature, timestamp)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		response, _ = serve(app, signature, timestamp)
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Contains(t, response.Body.String(), "the request was already received")
+	})
+
+	t.Run("Should accept the retry of a request that failed", func(t *testing.T) {
+		app := newApp(signatureSecret, false)
+		signature := sign(signatureSecret, timestamp, body)
+		status := http.StatusInternalServerError
+		handler := app.verifySignatureMiddleware(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			w.WriteHeader(status)
+		})
+		serveStatus := func() int {
+			req, _ := http.NewRequest("POST", "/v1/integrations/whatsapp/webhook", strings.NewReader(body))
+			req.Header.Set(signatureHeader, signature)
+			req.Header.Set(timestampHeader, timestamp)
+			response := httptest.NewRecorder()
+			handler(response, req, nil)
+			return response.Code
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, serveStatus())
+		status = http.StatusOK
+		assert.Equal(t, http.StatusOK, serveStatus())
+		assert.Equal(t, http.StatusUnauthorized, serveStatus())
+	})
+
+	t.Run("Should reject a request replayed to another instance", func(t *testing.T) {
+		signature := sign(signatureSecret, timestamp, body)
+
+		response, _ := serve(newApp(signatureSecret, false), signature, timestamp)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		other := newSignatureVerifier(signatureSecret, "client", 5*time.Minute, 10*time.Minute, false, signatureCache)
+		other.now = func() time.Time { return now }
+		response, _ = serve(&App{SignatureVerifier: other}, signature, timestamp)
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Should forget the signatures after the replay window", func(t *testing.T) {
+		verifier := newApp(signatureSecret, false).SignatureVerifier
+
+		assert.NoError(t, verifier.checkReplay("signature"))
+		m.FastForward(9 * time.Minute)
+		assert.Error(t, verifier.checkReplay("signature"))
+		m.FastForward(2 * time.Minute)
+		assert.NoError(t, verifier.checkReplay("signature"))
+	})
+
+	t.Run("Should accept the request when redis fails", func(t *testing.T) {
+		verifier := newApp(signatureSecret, false).SignatureVerifier
+		m.SetError("server is down")
+		defer m.SetError("")
+
+		assert.NoError(t, verifier.checkReplay("signature"))
+	})
+
+	t.Run("Should reject a body larger than the maximum", func(t *testing.T) {
+		handler := newApp(signatureSecret, false).verifySignatureMiddleware(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			w.WriteHeader(http.StatusOK)
+		})
+		req, _ := http.NewRequest("POST", "/v1/integrations/whatsapp/webhook", strings.NewReader(strings.Repeat("a", maxWebhookBodySize+1)))
+		response := httptest.NewRecorder()
+
+		handler(response, req, nil)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("Should accept the invalid signatures in report-only mode", func(t *testing.T) {
+		response, received := serve(newApp(signatureSecret, true), sign("other", timestamp, body), timestamp)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, body, received)
+	})
+}
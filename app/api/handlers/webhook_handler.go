@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -14,11 +15,20 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
 
+	"yalochat.com/salesforce-integration/app/manage"
 	"yalochat.com/salesforce-integration/base/helpers"
 	"yalochat.com/salesforce-integration/base/models"
 )
 
-const insertError = "There was an error inserting integration message"
+const (
+	insertError       = "There was an error inserting integration message"
+	statusError       = "There was an error updating the status of the message"
+	totalCountHeader  = "X-Total-Count"
+	contextFormatJSON = "json"
+	contextFormatText = "text"
+	contextSenderUser = "user"
+	contextSenderBot  = "bot"
+)
 
 // webhook to save messages from integrations API
 func (app *App) webhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -45,7 +55,9 @@ func (app *App) webhook(w http.ResponseWriter, r *http.Request, params httproute
 		return
 	}
 
-	if strings.Contains(app.IgnoreMessageTypes, integrationsRequest.Type) {
+	// the statuses of the messages are always processed to track their delivery, even when their type is ignored
+	isStatus := integrationsRequest.Type == constants.StatusType || integrationsRequest.Type == constants.NotificationStatusType
+	if !isStatus && strings.Contains(app.IgnoreMessageTypes, integrationsRequest.Type) {
 		span.SetTag("typeMessage", integrationsRequest.Type)
 		span.SetTag(ext.HTTPCode, http.StatusOK)
 		message := fmt.Sprintf("message type: %s was skipped", integrationsRequest.Type)
@@ -65,6 +77,20 @@ func (app *App) webhook(w http.ResponseWriter, r *http.Request, params httproute
 		return
 	}
 
+	if isStatus {
+		if err := app.ManageManager.UpdateMessageStatus(r.Context(), &integrationsRequest); err != nil {
+			errorMessage := helpers.ErrorMessage(statusError, err)
+			logrus.WithFields(logFields).WithError(err).Error(errorMessage)
+			span.SetTag(ext.Error, err)
+			span.SetTag(ext.HTTPCode, http.StatusInternalServerError)
+			helpers.WriteFailedResponse(w, http.StatusInternalServerError, errorMessage)
+			return
+		}
+		span.SetTag(ext.HTTPCode, http.StatusOK)
+		helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "status updated"})
+		return
+	}
+
 	err := app.ManageManager.SaveContext(r.Context(), &integrationsRequest)
 	if err != nil {
 		errorMessage := helpers.ErrorMessage(insertError, err)
@@ -80,12 +106,62 @@ func (app *App) webhook(w http.ResponseWriter, r *http.Request, params httproute
 	helpers.WriteSuccessResponse(w, helpers.SuccessResponse{Message: "insert success"})
 }
 
+// getContext returns the context of the user, it can be filtered with the query params:
+// from and to (timestamps in milliseconds), sender=user|bot, limit and offset.
+// With format=text it returns the context as the agents see it
 func (app *App) getContext(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	userID := params.ByName("user_id")
 
-	ctx := app.ManageManager.GetContextByUserID(userID)
+	filter, err := getContextFilter(r)
+	if err != nil {
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, helpers.ErrorMessage(helpers.InvalidPayload, err))
+		return
+	}
+
+	ctx, total := app.ManageManager.FilterContextByUserID(userID, filter)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+
+	switch r.URL.Query().Get("format") {
+	case "", contextFormatJSON:
+		helpers.WriteSuccessResponse(w, ctx)
+	case contextFormatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(app.ManageManager.RenderContextByUserID(userID, ctx)))
+	default:
+		helpers.WriteFailedResponse(w, http.StatusBadRequest, fmt.Sprintf("%s : invalid format %s", helpers.InvalidPayload, r.URL.Query().Get("format")))
+	}
+}
+
+// getContextFilter reads the filter of the context from the query params
+func getContextFilter(r *http.Request) (manage.ContextFilter, error) {
+	filter := manage.ContextFilter{}
+	query := r.URL.Query()
+	var err error
+
+	switch sender := query.Get("sender"); sender {
+	case "", contextSenderUser, contextSenderBot:
+		filter.Sender = sender
+	default:
+		return filter, fmt.Errorf("invalid sender: %s", sender)
+	}
+
+	if from := query.Get("from"); from != "" {
+		filter.From, err = strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %s", from)
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		filter.To, err = strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %s", to)
+		}
+	}
 
-	helpers.WriteSuccessResponse(w, ctx)
+	filter.Limit, filter.Offset, err = helpers.GetLimitAndOffset(r)
+	return filter, err
 }
 
 // webhookFB to save messages from integrations API
@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	ddrouter "gopkg.in/DataDog/dd-trace-go.v1/contrib/julienschmidt/httprouter"
 	"yalochat.com/salesforce-integration/app/api/handlers/mocks"
+	"yalochat.com/salesforce-integration/app/manage"
 	"yalochat.com/salesforce-integration/base/cache"
 	"yalochat.com/salesforce-integration/base/helpers"
 	"yalochat.com/salesforce-integration/base/models"
@@ -63,11 +64,12 @@ func TestApp_webhook(t *testing.T) {
 
 	})
 
-	t.Run("Should skip status message", func(t *testing.T) {
+	t.Run("Should skip the ignored message types", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
 		body := models.IntegrationsRequest{
 			ID:        "",
 			Timestamp: "1234556",
-			Type:      constants.StatusType,
+			Type:      constants.ImageType,
 			From:      "5555555555",
 			To:        "",
 		}
@@ -75,56 +77,110 @@ func TestApp_webhook(t *testing.T) {
 		binBody, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		getApp().IgnoreMessageTypes = constants.StatusType
+		getApp().ManageManager = managerMock
+		getApp().IgnoreMessageTypes = fmt.Sprintf("%s,%s", constants.StatusType, constants.ImageType)
 
 		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(binBody))
 		req.Header.Add("x-yalochat-signature", "secret")
 		response := httptest.NewRecorder()
-		expectedLog := helpers.SuccessResponse{Message: "message type: status was skipped"}
+		expectedLog := helpers.SuccessResponse{Message: "message type: image was skipped"}
 		binexpectedLog, err := json.Marshal(expectedLog)
 		assert.NoError(t, err)
 
 		handler.ServeHTTP(response, req)
 
-		if response.Code != http.StatusOK {
-			t.Errorf("Response should be %v, but it answer with %v ", http.StatusOK, response.Code)
-		}
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), string(binexpectedLog))
+		managerMock.AssertNotCalled(t, "SaveContext", mock.Anything, mock.Anything)
+	})
 
-		if !strings.Contains(response.Body.String(), string(binexpectedLog)) {
-			t.Errorf("Response should be %v, but it answer with %v ", expectedLog, response.Body.String())
+	t.Run("Should update the status of the message when its type is ignored", func(t *testing.T) {
+		for _, statusType := range []string{constants.StatusType, constants.NotificationStatusType} {
+			managerMock := new(mocks.ManagerI)
+			body := models.IntegrationsRequest{
+				ID:        "wamid.1",
+				Timestamp: "1234556",
+				Type:      statusType,
+				From:      "5555555555",
+				Status:    "read",
+			}
+
+			binBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+
+			managerMock.On("UpdateMessageStatus", mock.Anything, &body).Return(nil).Once()
+			getApp().ManageManager = managerMock
+			getApp().IgnoreMessageTypes = fmt.Sprintf("%s,%s", constants.StatusType, constants.NotificationStatusType)
+
+			req, _ := http.NewRequest("POST", url, bytes.NewBuffer(binBody))
+			req.Header.Add("x-yalochat-signature", "secret")
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusOK, response.Code, statusType)
+			assert.Contains(t, response.Body.String(), "status updated", statusType)
+			managerMock.AssertExpectations(t)
 		}
 	})
 
-	t.Run("Should skip notification-status message", func(t *testing.T) {
+	t.Run("Should update the status of the message", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
 		body := models.IntegrationsRequest{
-			ID:        "",
+			ID:        "wamid.1",
 			Timestamp: "1234556",
-			Type:      constants.NotificationStatusType,
+			Type:      constants.StatusType,
 			From:      "5555555555",
-			To:        "",
+			Status:    "delivered",
 		}
 
 		binBody, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		getApp().IgnoreMessageTypes = fmt.Sprintf("%s,%s", constants.StatusType, constants.NotificationStatusType)
+		managerMock.On("UpdateMessageStatus", mock.Anything, &body).Return(nil).Once()
+		getApp().ManageManager = managerMock
+		getApp().IgnoreMessageTypes = ""
 
 		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(binBody))
 		req.Header.Add("x-yalochat-signature", "secret")
 		response := httptest.NewRecorder()
-		expectedLog := helpers.SuccessResponse{Message: "message type: notification-status was skipped"}
+		expectedLog := helpers.SuccessResponse{Message: "status updated"}
 		binexpectedLog, err := json.Marshal(expectedLog)
 		assert.NoError(t, err)
 
 		handler.ServeHTTP(response, req)
 
-		if response.Code != http.StatusOK {
-			t.Errorf("Response should be %v, but it answer with %v ", http.StatusOK, response.Code)
-		}
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), string(binexpectedLog))
+		managerMock.AssertNotCalled(t, "SaveContext", mock.Anything, mock.Anything)
+		managerMock.AssertExpectations(t)
+	})
 
-		if !strings.Contains(response.Body.String(), string(binexpectedLog)) {
-			t.Errorf("Response should be %v, but it answer with %v ", expectedLog, response.Body.String())
+	t.Run("Should fail when the status of the message could not be updated", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		body := models.IntegrationsRequest{
+			ID:        "wamid.1",
+			Timestamp: "1234556",
+			Type:      constants.NotificationStatusType,
+			From:      "5555555555",
+			Status:    "failed",
 		}
+
+		binBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		managerMock.On("UpdateMessageStatus", mock.Anything, &body).Return(assert.AnError).Once()
+		getApp().ManageManager = managerMock
+		getApp().IgnoreMessageTypes = ""
+
+		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(binBody))
+		req.Header.Add("x-yalochat-signature", "secret")
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+		managerMock.AssertExpectations(t)
 	})
 
 	t.Run("Should return error validate payload", func(t *testing.T) {
@@ -419,7 +475,7 @@ func TestGetContext(t *testing.T) {
 				Text:      "test",
 			},
 		}
-		managerMock.On("GetContextByUserID", userID).Return(expected).Once()
+		managerMock.On("FilterContextByUserID", userID, manage.ContextFilter{}).Return(expected, 1).Once()
 		getApp().ManageManager = managerMock
 
 		req, _ := http.NewRequest("GET", urlTest, nil)
@@ -435,6 +491,103 @@ func TestGetContext(t *testing.T) {
 		}
 
 		assert.Equal(t, string(expectedBin), response.Body.String())
+		assert.Equal(t, "1", response.Header().Get(totalCountHeader))
+	})
+
+	t.Run("Should filter the context", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+
+		expected := []cache.Context{
+			{
+				UserID:    userID,
+				Timestamp: 111111,
+				Text:      "test",
+				From:      "user",
+			},
+		}
+		filter := manage.ContextFilter{Sender: "user", From: 111111, To: 222222, Limit: 1, Offset: 2}
+		managerMock.On("FilterContextByUserID", userID, filter).Return(expected, 5).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", urlTest+"?sender=user&from=111111&to=222222&limit=1&offset=2", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		expectedBin, err := json.Marshal(expected)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, string(expectedBin), response.Body.String())
+		assert.Equal(t, "5", response.Header().Get(totalCountHeader))
+	})
+
+	t.Run("Should filter the context by timestamps", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+
+		filter := manage.ContextFilter{From: 111111, To: 222222}
+		managerMock.On("FilterContextByUserID", userID, filter).Return([]cache.Context{}, 0).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", urlTest+"?from=111111&to=222222", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "[]", response.Body.String())
+		assert.Equal(t, "0", response.Header().Get(totalCountHeader))
+	})
+
+	t.Run("Should return the context as text", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+
+		expected := []cache.Context{
+			{
+				UserID:    userID,
+				Timestamp: 111111,
+				Text:      "test",
+			},
+		}
+		managerMock.On("FilterContextByUserID", userID, manage.ContextFilter{}).Return(expected, 1).Once()
+		managerMock.On("RenderContextByUserID", userID, expected).Return("Bot [01-01-1970 00:01:51]:test\n\n").Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", urlTest+"?format=text", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", response.Header().Get("Content-Type"))
+		assert.Equal(t, "Bot [01-01-1970 00:01:51]:test\n\n", response.Body.String())
+	})
+
+	t.Run("Should return bad request with invalid params", func(t *testing.T) {
+		for _, query := range []string{"?from=yesterday", "?from=user", "?to=today", "?sender=agent", "?limit=-1", "?offset=a"} {
+			managerMock := new(mocks.ManagerI)
+			getApp().ManageManager = managerMock
+
+			req, _ := http.NewRequest("GET", urlTest+query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assert.Equal(t, http.StatusBadRequest, response.Code, query)
+			managerMock.AssertNotCalled(t, "FilterContextByUserID", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Should return bad request with an invalid format", func(t *testing.T) {
+		managerMock := new(mocks.ManagerI)
+		managerMock.On("FilterContextByUserID", userID, manage.ContextFilter{}).Return([]cache.Context{}, 0).Once()
+		getApp().ManageManager = managerMock
+
+		req, _ := http.NewRequest("GET", urlTest+"?format=xml", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", yaloTokenTest))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
 	})
 }
 
@@ -6,85 +6,126 @@ import (
 	"strings"
 	"time"
 
+	"yalochat.com/salesforce-integration/base/clients/chat"
 	"yalochat.com/salesforce-integration/base/models"
 )
 
 // Envs represents the list of well known env vars used by the app
 type Envs struct {
-	AppName                        string                 `default:"salesforce-integration" split_words:"true"`
-	Client                         string                 `default:"salesforce" split_words:"true"`
-	Host                           string                 `required:"true" split_words:"true" default:"localhost"`
-	Port                           string                 `required:"true" split_words:"true" default:"8080"`
-	SentryDSN                      string                 `default:"" split_words:"true"`
-	Environment                    string                 `default:"dev" split_words:"true"`
-	RedisAddress                   string                 `split_words:"true"`
-	RedisMaster                    string                 `split_words:"true"`
-	RedisPassword                  string                 `split_words:"true"`
-	RedisSentinelAddress           string                 `split_words:"true"`
-	BlockedUserState               map[string]string      `required:"true" split_words:"true" default:"whatsapp:from-sf-blocked,facebook:from-sf-blocked"`
-	TimeoutState                   map[string]string      `required:"true" split_words:"true" default:"whatsapp:from-sf-timeout,facebook:from-sf-timeout"`
-	SuccessState                   map[string]string      `required:"true" split_words:"true" default:"whatsapp:from-sf-success,facebook:from-sf-success"`
-	YaloUsername                   string                 `required:"true" split_words:"true" default:"yaloUser"`
-	YaloPassword                   string                 `required:"true" split_words:"true"`
-	SalesforceUsername             string                 `required:"true" split_words:"true" default:"salesforceUser"`
-	SalesforcePassword             string                 `required:"true" split_words:"true"`
-	SecretKey                      string                 `required:"true" split_words:"true"`
-	BotrunnerUrl                   string                 `split_words:"true"`
-	BotrunnerToken                 string                 `split_words:"true" default:""`
-	BotrunnerTimeout               int                    `split_words:"true" default:"4"`
-	StudioNGUrl                    string                 `split_words:"true"`
-	StudioNGToken                  string                 `split_words:"true"`
-	StudioNGTimeout                int                    `split_words:"true" default:"4"`
-	SfcClientID                    string                 `split_words:"true"`
-	SfcClientSecret                string                 `split_words:"true"`
-	SfcUsername                    string                 `split_words:"true"`
-	SfcPassword                    string                 `split_words:"true"`
-	SfcSecurityToken               string                 `split_words:"true"`
-	SfcBaseUrl                     string                 `split_words:"true"`
-	SfcChatUrl                     string                 `split_words:"true"`
-	SfcLoginUrl                    string                 `split_words:"true"`
-	SfcApiVersion                  string                 `split_words:"true" default:"52"`
-	SfcOrganizationId              string                 `split_words:"true"`
-	SfcDeploymentId                string                 `split_words:"true"`
-	SfcRecordTypeId                string                 `split_words:"true"`
-	SfcAccountRecordTypeId         string                 `split_words:"true"`
-	SfcDefaultBirthDateAccount     string                 `split_words:"true" default:"1921-01-01T00:00:00"`
-	SfcCustomFieldsCase            map[string]string      `split_words:"true"`
-	SfcCustomFieldsContact         map[string]string      `split_words:"true"`
-	SfcCustomFieldsAccount         map[string]string      `split_words:"true"`
-	SfcSourceFlowBot               SfcSourceFlowBot       `required:"true" split_words:"true"`
-	SfcSourceFlowField             string                 `required:"true" split_words:"true" default:"source_flow_bot"`
-	SfcBlockedChatField            bool                   `split_words:"true" default:"false"`
-	SfcCodePhoneRemove             []string               `split_words:"true" default:"521,52"`
-	IgnoreMessageTypes             string                 `split_words:"true" default:"status,notification-status"`
-	IntegrationsWAChannel          string                 `split_words:"true" default:"outgoing_webhook"`
-	IntegrationsFBChannel          string                 `split_words:"true" default:"passthrough"`
-	IntegrationsWABotID            string                 `split_words:"true"`
-	IntegrationsFBBotID            string                 `split_words:"true"`
-	IntegrationsWABotJWT           string                 `split_words:"true"`
-	IntegrationsFBBotJWT           string                 `split_words:"true"`
-	IntegrationsBaseUrl            string                 `split_words:"true"`
-	IntegrationsSignature          string                 `split_words:"true"`
-	WebhookBaseUrl                 string                 `split_words:"true"`
-	IntegrationsWABotPhone         string                 `split_words:"true"`
-	IntegrationsFBBotPhone         string                 `split_words:"true"`
-	KeywordsRestart                []string               `split_words:"true" default:"coppelbot,regresar,reiniciar,restart"`
-	SpecSchedule                   string                 `split_words:"true" default:"@every 59m"`
-	MaxRetries                     int                    `split_words:"true" default:"2"`
-	CleanContextSchedule           string                 `split_words:"true" default:"0 9 * * *"`
-	IntegrationChanRateLimit       float64                `split_words:"true" default:"20"`
-	SaleforceChanRateLimit         float64                `split_words:"true" default:"20"`
-	Messages                       models.MessageTemplate `split_words:"true" required:"true" default:"{\"waitAgent\":\"Esperando un agente\",\"welcomeTemplate\":\"Hola soy %s y necesito ayuda\",\"context\":\"Contexto\",\"DescriptionCase\":\"Caso levantado por el Bot\",\"uploadImageError\":\"Imagen no enviada\",\"uploadImageSuccess\":\"**El usuario adjunto una imagen al caso**\",\"uploadFileError\":\"Archivo no enviado\",\"uploadFileSuccess\":\"**El usuario adjunto un archivo al caso**\",\"queuePosition\":\"Posici\u00F3n en la cola\",\"waitTime\":\"Tiempo de espera\",\"firstNameContact\":\"Contacto Bot - \",\"clientLabel\":\"Cliente\",\"botLabel\":\"Bot\",\"replyToTemplate\":\"Respuesta de => [%s] \\n \\n Mensaje enviado => %s\",\"agentLabel\":\"Agente\"}"`
-	Timezone                       string                 `required:"true" default:"America/Mexico_City"`
-	SendImageNameInMessage         bool                   `split_words:"true" default:"false"`
-	KafkaHost                      string                 `required:"true" split_words:"true"`
-	KafkaPort                      string                 `required:"true" split_words:"true"`
-	KafkaUser                      string                 `required:"true" split_words:"true"`
-	KafkaPassword                  string                 `required:"true" split_words:"true"`
-	KafkaTopic                     string                 `required:"true" split_words:"true"`
-	UseProfile                     bool                   `split_words:"true" default:"false"`
-	SleepLongPollling              time.Duration          `split_words:"true" default:"3s"`
-	SfcCustomFieldsToSearchContact map[string]string      `split_words:"true"`
+	AppName                        string                  `default:"salesforce-integration" split_words:"true"`
+	Client                         string                  `default:"salesforce" split_words:"true"`
+	Host                           string                  `required:"true" split_words:"true" default:"localhost"`
+	Port                           string                  `required:"true" split_words:"true" default:"8080"`
+	SentryDSN                      string                  `default:"" split_words:"true"`
+	Environment                    string                  `default:"dev" split_words:"true"`
+	RedisAddress                   string                  `split_words:"true"`
+	RedisMaster                    string                  `split_words:"true"`
+	RedisPassword                  string                  `split_words:"true"`
+	RedisSentinelAddress           string                  `split_words:"true"`
+	BlockedUserState               map[string]string       `required:"true" split_words:"true" default:"whatsapp:from-sf-blocked,facebook:from-sf-blocked"`
+	TimeoutState                   map[string]string       `required:"true" split_words:"true" default:"whatsapp:from-sf-timeout,facebook:from-sf-timeout"`
+	SuccessState                   map[string]string       `required:"true" split_words:"true" default:"whatsapp:from-sf-success,facebook:from-sf-success"`
+	ProactiveChatState             map[string]string       `split_words:"true" default:"whatsapp:from-sf-proactive,facebook:from-sf-proactive"`
+	YaloUsername                   string                  `required:"true" split_words:"true" default:"yaloUser"`
+	YaloPassword                   string                  `required:"true" split_words:"true"`
+	SalesforceUsername             string                  `required:"true" split_words:"true" default:"salesforceUser"`
+	SalesforcePassword             string                  `required:"true" split_words:"true"`
+	SecretKey                      string                  `required:"true" split_words:"true"`
+	SecretKeyID                    string                  `split_words:"true" default:"v1"`
+	PreviousSecretKeys             map[string]string       `split_words:"true"`
+	AccessTokenTTL                 time.Duration           `split_words:"true" default:"1h"`
+	RefreshTokenTTL                time.Duration           `split_words:"true" default:"0"`
+	AllowLegacyTokens              bool                    `split_words:"true" default:"false"`
+	BotrunnerUrl                   string                  `split_words:"true"`
+	BotrunnerToken                 string                  `split_words:"true" default:""`
+	BotrunnerTimeout               int                     `split_words:"true" default:"4"`
+	StudioNGUrl                    string                  `split_words:"true"`
+	StudioNGToken                  string                  `split_words:"true"`
+	StudioNGTimeout                int                     `split_words:"true" default:"4"`
+	SfcClientID                    string                  `split_words:"true"`
+	SfcClientSecret                string                  `split_words:"true"`
+	SfcUsername                    string                  `split_words:"true"`
+	SfcPassword                    string                  `split_words:"true"`
+	SfcSecurityToken               string                  `split_words:"true"`
+	SfcBaseUrl                     string                  `split_words:"true"`
+	SfcChatUrl                     string                  `split_words:"true"`
+	SfcLoginUrl                    string                  `split_words:"true"`
+	SfcApiVersion                  string                  `split_words:"true" default:"52"`
+	SfcOrganizationId              string                  `split_words:"true"`
+	SfcDeploymentId                string                  `split_words:"true"`
+	SfcRecordTypeId                string                  `split_words:"true"`
+	SfcAccountRecordTypeId         string                  `split_words:"true"`
+	SfcDefaultBirthDateAccount     string                  `split_words:"true" default:"1921-01-01T00:00:00"`
+	SfcCustomFieldsCase            map[string]string       `split_words:"true"`
+	SfcCustomFieldsContact         map[string]string       `split_words:"true"`
+	SfcCustomFieldsAccount         map[string]string       `split_words:"true"`
+	SfcSourceFlowBot               SfcSourceFlowBot        `required:"true" split_words:"true"`
+	SfcSourceFlowField             string                  `required:"true" split_words:"true" default:"source_flow_bot"`
+	SfcBlockedChatField            bool                    `split_words:"true" default:"false"`
+	SfcCodePhoneRemove             []string                `split_words:"true" default:"521,52"`
+	SfcMaxFileSize                 int64                   `split_words:"true" default:"26214400"`
+	AllowedFileTypes               []string                `split_words:"true"`
+	DeniedFileTypes                []string                `split_words:"true" default:"application/vnd.microsoft.portable-executable,application/x-msdownload,application/x-elf,application/x-executable,application/x-sharedlib,application/x-mach-binary,application/jar,application/java-archive,application/vnd.android.package-archive,application/javascript,application/x-sh,application/x-msi,.exe,.dll,.bat,.cmd,.com,.scr,.msi,.sh,.apk,.jar,.js,.vbs,.ps1"`
+	IgnoreMessageTypes             string                  `split_words:"true"`
+	IntegrationsWAChannel          string                  `split_words:"true" default:"outgoing_webhook"`
+	IntegrationsFBChannel          string                  `split_words:"true" default:"passthrough"`
+	IntegrationsWABotID            string                  `split_words:"true"`
+	IntegrationsFBBotID            string                  `split_words:"true"`
+	IntegrationsWABotJWT           string                  `split_words:"true"`
+	IntegrationsFBBotJWT           string                  `split_words:"true"`
+	IntegrationsBaseUrl            string                  `split_words:"true"`
+	IntegrationsSignature          string                  `split_words:"true"`
+	SignatureClockSkew             time.Duration           `split_words:"true" default:"5m"`
+	SignatureReplayWindow          time.Duration           `split_words:"true" default:"10m"`
+	SignatureReportOnly            bool                    `split_words:"true" default:"true"`
+	WebhookBaseUrl                 string                  `split_words:"true"`
+	IntegrationsWABotPhone         string                  `split_words:"true"`
+	IntegrationsFBBotPhone         string                  `split_words:"true"`
+	KeywordsRestart                []string                `split_words:"true" default:"coppelbot,regresar,reiniciar,restart"`
+	EndUserCommands                EndUserCommands         `split_words:"true"`
+	SpecSchedule                   string                  `split_words:"true" default:"@every 59m"`
+	MaxRetries                     int                     `split_words:"true" default:"2"`
+	CleanContextSchedule           string                  `split_words:"true" default:"0 9 * * *"`
+	IntegrationChanRateLimit       float64                 `split_words:"true" default:"20"`
+	SaleforceChanRateLimit         float64                 `split_words:"true" default:"20"`
+	Messages                       models.MessageTemplate  `split_words:"true" required:"true" default:"{\"waitAgent\":\"Esperando un agente\",\"welcomeTemplate\":\"Hola soy %s y necesito ayuda\",\"context\":\"Contexto\",\"DescriptionCase\":\"Caso levantado por el Bot\",\"uploadImageError\":\"Imagen no enviada\",\"uploadImageSuccess\":\"**El usuario adjunto una imagen al caso**\",\"uploadFileError\":\"Archivo no enviado\",\"uploadFileSuccess\":\"**El usuario adjunto un archivo al caso**\",\"queuePosition\":\"Posici\u00F3n en la cola\",\"waitTime\":\"Tiempo de espera\",\"firstNameContact\":\"Contacto Bot - \",\"clientLabel\":\"Cliente\",\"botLabel\":\"Bot\",\"replyToTemplate\":\"Respuesta de => [%s] \\n \\n Mensaje enviado => %s\",\"agentLabel\":\"Agente\",\"uploadFileTooLarge\":\"El archivo excede el tama\u00F1o m\u00E1ximo permitido\",\"uploadFileTypeNotAllowed\":\"El tipo de archivo no est\u00E1 permitido\",\"endUserCommandNote\":\"**El usuario finaliz\u00F3 el chat con el comando %s**\",\"caseNumberTemplate\":\"Tu n\u00FAmero de caso es {{.CaseNumber}}\",\"imageLabel\":\"Imagen\",\"audioLabel\":\"Audio\",\"documentLabel\":\"Documento\",\"transcriptTitle\":\"Conversaci\u00F3n con el bot\",\"proactiveChatTemplate\":\"Un agente quiere continuar la conversaci\u00F3n de tu caso {{.CaseNumber}}\",\"messageNotDelivered\":\"**El mensaje no fue entregado al cliente**\",\"windowClosed\":\"**Pasaron m\u00E1s de 24 horas desde el \u00FAltimo mensaje del cliente, se le envi\u00F3 una plantilla para retomar la conversaci\u00F3n y el mensaje no fue entregado**\"}"`
+	MessagesByBot                  models.MessageTemplates `split_words:"true"`
+	LanguageField                  string                  `split_words:"true" default:"language"`
+	Timezone                       string                  `required:"true" default:"America/Mexico_City"`
+	SendImageNameInMessage         bool                    `split_words:"true" default:"false"`
+	CaseNumberEvent                CaseNumberEvent         `split_words:"true" default:"ChatRequestSuccess"`
+	MessageLimits                  map[string]int          `split_words:"true" default:"whatsapp:4096,facebook:2000,salesforce:4000"`
+	SplitMessageMarkers            bool                    `split_words:"true" default:"true"`
+	ContextMediaAttachments        int                     `split_words:"true" default:"0"`
+	TranscriptFormat               string                  `split_words:"true"`
+	KafkaHost                      string                  `required:"true" split_words:"true"`
+	KafkaPort                      string                  `required:"true" split_words:"true"`
+	KafkaUser                      string                  `required:"true" split_words:"true"`
+	KafkaPassword                  string                  `required:"true" split_words:"true"`
+	KafkaTopic                     string                  `required:"true" split_words:"true"`
+	UseProfile                     bool                    `split_words:"true" default:"false"`
+	SleepLongPollling              time.Duration           `split_words:"true" default:"3s"`
+	IdempotencyTTL                 time.Duration           `split_words:"true" default:"10m"`
+	IdempotencyLockTTL             time.Duration           `split_words:"true" default:"1m"`
+	ReadinessTimeout               time.Duration           `split_words:"true" default:"3s"`
+	ReadinessCacheTTL              time.Duration           `split_words:"true" default:"10s"`
+	ReadinessNonCritical           []string                `split_words:"true"`
+	CallbackSecret                 string                  `split_words:"true"`
+	CallbackTimeout                time.Duration           `split_words:"true" default:"10s"`
+	CallbackMaxRetries             int                     `split_words:"true" default:"3"`
+	AsyncChatTimeout               time.Duration           `split_words:"true" default:"30s"`
+	LifecycleWebhooks              WebhookSubscribers      `split_words:"true"`
+	LifecycleWebhookSecret         string                  `split_words:"true"`
+	LifecycleWebhookTimeout        time.Duration           `split_words:"true" default:"10s"`
+	LifecycleWebhookMaxAttempts    int                     `split_words:"true" default:"8"`
+	LifecycleWebhookRetryDelay     time.Duration           `split_words:"true" default:"30s"`
+	LifecycleWebhookMaxDelay       time.Duration           `split_words:"true" default:"1h"`
+	LifecycleWebhookLogSize        int64                   `split_words:"true" default:"1000"`
+	LifecycleWebhookConcurrency    int                     `split_words:"true" default:"10"`
+	LifecycleTopic                 string                  `split_words:"true"`
+	MessageDeliveryTTL             time.Duration           `split_words:"true" default:"48h"`
+	WindowTemplate                 models.WindowTemplate   `split_words:"true"`
+	SfcCustomFieldsToSearchContact map[string]string       `split_words:"true"`
 }
 
 type Provider struct {
@@ -123,3 +164,82 @@ func (sd *SfcSourceFlowBot) Decode(value string) error {
 
 	return nil
 }
+
+// EndUserCommands maps the commands that the end-user can send to leave the chat with the agent,
+// to the state of the bot where the user is sent by provider
+type EndUserCommands map[string]map[string]string
+
+//Decode Decoder this function deserializes the struct by the envconfig Decoder interface implementation
+func (ec *EndUserCommands) Decode(value string) error {
+	commandMap := map[string]map[string]string{}
+
+	pairs := strings.Split(value, ";")
+	for _, pair := range pairs {
+		states := map[string]string{}
+		kvpair := strings.Split(pair, "=")
+		if len(kvpair) != 2 {
+			return fmt.Errorf("invalid map item: %q", pair)
+		}
+
+		err := json.Unmarshal([]byte(kvpair[1]), &states)
+		if err != nil {
+			return fmt.Errorf("invalid map json: %w", err)
+		}
+
+		commandMap[strings.ToLower(strings.TrimSpace(kvpair[0]))] = states
+	}
+	*ec = EndUserCommands(commandMap)
+
+	return nil
+}
+
+// CaseNumberEvent is the Live Agent event when the case number is sent to the user
+type CaseNumberEvent string
+
+// Decode Decoder this function validates the event by the envconfig Decoder interface implementation
+func (ce *CaseNumberEvent) Decode(value string) error {
+	if value != chat.ChatRequestSuccess && value != chat.ChatEstablished {
+		return fmt.Errorf("invalid case number event %q, it can be %s or %s", value, chat.ChatRequestSuccess, chat.ChatEstablished)
+	}
+	*ce = CaseNumberEvent(value)
+
+	return nil
+}
+
+// WebhookSubscriber is a system that receives the lifecycle events of the chats, it receives all the events when
+// Events is empty and the secret of the subscriber replaces the webhook secret
+type WebhookSubscriber struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// WebhookSubscribers maps the name of the subscribers to their configuration
+type WebhookSubscribers map[string]WebhookSubscriber
+
+// Decode Decoder this function deserializes the struct by the envconfig Decoder interface implementation
+func (ws *WebhookSubscribers) Decode(value string) error {
+	subscriberMap := map[string]WebhookSubscriber{}
+
+	pairs := strings.Split(value, ";")
+	for _, pair := range pairs {
+		subscriber := WebhookSubscriber{}
+		kvpair := strings.SplitN(pair, "=", 2)
+		if len(kvpair) != 2 {
+			return fmt.Errorf("invalid map item: %q", pair)
+		}
+
+		err := json.Unmarshal([]byte(kvpair[1]), &subscriber)
+		if err != nil {
+			return fmt.Errorf("invalid map json: %w", err)
+		}
+		if subscriber.URL == "" {
+			return fmt.Errorf("the subscriber %q does not have url", kvpair[0])
+		}
+
+		subscriberMap[strings.TrimSpace(kvpair[0])] = subscriber
+	}
+	*ws = WebhookSubscribers(subscriberMap)
+
+	return nil
+}
@@ -70,3 +70,131 @@ func TestSfcSourceFlowBot_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestEndUserCommands_Decode(t *testing.T) {
+	type args struct {
+		value string
+	}
+	tests := []struct {
+		name    string
+		ec      *EndUserCommands
+		args    args
+		wantErr bool
+		want    *EndUserCommands
+	}{
+		{
+			name: "success",
+			ec:   &EndUserCommands{},
+			args: args{
+				value: `Salir={"whatsapp":"from-sf-exit","facebook":"from-sf-exit"};menu={"whatsapp":"main-menu"}`,
+			},
+			wantErr: false,
+			want: &EndUserCommands{
+				"salir": {
+					"whatsapp": "from-sf-exit",
+					"facebook": "from-sf-exit",
+				},
+				"menu": {
+					"whatsapp": "main-menu",
+				},
+			},
+		},
+		{
+			name: "error parse",
+			args: args{
+				value: "test",
+			},
+			wantErr: true,
+		},
+		{
+			name: "error parse json",
+			ec:   &EndUserCommands{},
+			args: args{
+				value: "salir=test",
+			},
+			wantErr: true,
+			want:    &EndUserCommands{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ec.Decode(tt.args.value); (err != nil) != tt.wantErr {
+				t.Errorf("EndUserCommands.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, tt.ec)
+		})
+	}
+}
+
+func TestCaseNumberEvent_Decode(t *testing.T) {
+	t.Run("Should accept the events that have the case", func(t *testing.T) {
+		for _, event := range []string{"ChatRequestSuccess", "ChatEstablished"} {
+			var ce CaseNumberEvent
+			assert.NoError(t, ce.Decode(event))
+			assert.Equal(t, CaseNumberEvent(event), ce)
+		}
+	})
+
+	t.Run("Should reject the other events", func(t *testing.T) {
+		for _, event := range []string{"", "ChatEnded", "chatEstablished"} {
+			var ce CaseNumberEvent
+			assert.Error(t, ce.Decode(event), event)
+		}
+	})
+}
+
+func TestWebhookSubscribers_Decode(t *testing.T) {
+	type args struct {
+		value string
+	}
+	tests := []struct {
+		name    string
+		ws      *WebhookSubscribers
+		args    args
+		wantErr bool
+		want    *WebhookSubscribers
+	}{
+		{
+			name: "success",
+			ws:   &WebhookSubscribers{},
+			args: args{
+				value: `crm={"url":"https://crm.yalochat.com/webhooks?token=abc=","events":["chat.created","chat.ended"],"secret":"crmSecret"};analytics={"url":"https://analytics.yalochat.com/events"}`,
+			},
+			wantErr: false,
+			want: &WebhookSubscribers{
+				"crm": {
+					URL:    "https://crm.yalochat.com/webhooks?token=abc=",
+					Events: []string{"chat.created", "chat.ended"},
+					Secret: "crmSecret",
+				},
+				"analytics": {
+					URL: "https://analytics.yalochat.com/events",
+				},
+			},
+		},
+		{
+			name: "error parse",
+			args: args{
+				value: "test",
+			},
+			wantErr: true,
+		},
+		{
+			name: "error without url",
+			ws:   &WebhookSubscribers{},
+			args: args{
+				value: `crm={"events":["chat.created"]}`,
+			},
+			wantErr: true,
+			want:    &WebhookSubscribers{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ws.Decode(tt.args.value); (err != nil) != tt.wantErr {
+				t.Errorf("WebhookSubscribers.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, tt.ws)
+		})
+	}
+}
@@ -21,6 +21,20 @@ type SalesforceServiceInterface struct {
 	mock.Mock
 }
 
+// CheckToken provides a mock function with given fields: _a0
+func (_m *SalesforceServiceInterface) CheckToken(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreatCase provides a mock function with given fields: _a0, contactID, description, subject, origin, ownerID, extraData
 func (_m *SalesforceServiceInterface) CreatCase(_a0 context.Context, contactID string, description string, subject string, origin string, ownerID string, extraData map[string]interface{}) (string, error) {
 	ret := _m.Called(_a0, contactID, description, subject, origin, ownerID, extraData)
@@ -79,6 +93,27 @@ func (_m *SalesforceServiceInterface) EndChat(affinityToken string, sessionKey s
 	return r0
 }
 
+// GetCaseNumber provides a mock function with given fields: _a0, caseID
+func (_m *SalesforceServiceInterface) GetCaseNumber(_a0 context.Context, caseID string) (string, error) {
+	ret := _m.Called(_a0, caseID)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(_a0, caseID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, caseID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetMessages provides a mock function with given fields: mainSpan, affinityToken, sessionKey, ack
 func (_m *SalesforceServiceInterface) GetMessages(mainSpan ddtrace.Span, affinityToken string, sessionKey string, ack int) (*chat.MessagesResponse, *helpers.ErrorResponse) {
 	ret := _m.Called(mainSpan, affinityToken, sessionKey, ack)
@@ -104,13 +139,13 @@ func (_m *SalesforceServiceInterface) GetMessages(mainSpan ddtrace.Span, affinit
 	return r0, r1
 }
 
-// GetOrCreateContact provides a mock function with given fields: _a0, name, email, phoneNumber, extraData
-func (_m *SalesforceServiceInterface) GetOrCreateContact(_a0 context.Context, name string, email string, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error) {
-	ret := _m.Called(_a0, name, email, phoneNumber, extraData)
+// GetOrCreateContact provides a mock function with given fields: _a0, firstName, name, email, phoneNumber, extraData
+func (_m *SalesforceServiceInterface) GetOrCreateContact(_a0 context.Context, firstName string, name string, email string, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error) {
+	ret := _m.Called(_a0, firstName, name, email, phoneNumber, extraData)
 
 	var r0 *models.SfcContact
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, map[string]interface{}) *models.SfcContact); ok {
-		r0 = rf(_a0, name, email, phoneNumber, extraData)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, map[string]interface{}) *models.SfcContact); ok {
+		r0 = rf(_a0, firstName, name, email, phoneNumber, extraData)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.SfcContact)
@@ -118,8 +153,8 @@ func (_m *SalesforceServiceInterface) GetOrCreateContact(_a0 context.Context, na
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, map[string]interface{}) error); ok {
-		r1 = rf(_a0, name, email, phoneNumber, extraData)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string, map[string]interface{}) error); ok {
+		r1 = rf(_a0, firstName, name, email, phoneNumber, extraData)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -127,6 +162,34 @@ func (_m *SalesforceServiceInterface) GetOrCreateContact(_a0 context.Context, na
 	return r0, r1
 }
 
+// InsertContentInCase provides a mock function with given fields: _a0, content, title, fileName, caseID
+func (_m *SalesforceServiceInterface) InsertContentInCase(_a0 context.Context, content []byte, title string, fileName string, caseID string) error {
+	ret := _m.Called(_a0, content, title, fileName, caseID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, string, string, string) error); ok {
+		r0 = rf(_a0, content, title, fileName, caseID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// InsertCommentInCase provides a mock function with given fields: _a0, caseID, comment
+func (_m *SalesforceServiceInterface) InsertCommentInCase(_a0 context.Context, caseID string, comment string) error {
+	ret := _m.Called(_a0, caseID, comment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, caseID, comment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // InsertFileInCase provides a mock function with given fields: uri, title, mimeType, caseID
 func (_m *SalesforceServiceInterface) InsertFileInCase(uri string, title string, mimeType string, caseID string) error {
 	ret := _m.Called(uri, title, mimeType, caseID)
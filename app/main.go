@@ -77,6 +77,7 @@ func main() {
 		BotrunnerTimeout:               envs.BotrunnerTimeout,
 		TimeoutState:                   envs.TimeoutState,
 		SuccessState:                   envs.SuccessState,
+		ProactiveChatState:             envs.ProactiveChatState,
 		SfcClientID:                    envs.SfcClientID,
 		SfcClientSecret:                envs.SfcClientSecret,
 		SfcUsername:                    envs.SfcUsername,
@@ -97,6 +98,9 @@ func main() {
 		SfcSourceFlowField:             envs.SfcSourceFlowField,
 		SfcSourceFlowBot:               envs.SfcSourceFlowBot,
 		SfcCodePhoneRemove:             envs.SfcCodePhoneRemove,
+		SfcMaxFileSize:                 envs.SfcMaxFileSize,
+		AllowedFileTypes:               envs.AllowedFileTypes,
+		DeniedFileTypes:                envs.DeniedFileTypes,
 		IntegrationsUrl:                envs.IntegrationsBaseUrl,
 		IntegrationsWAChannel:          envs.IntegrationsWAChannel,
 		IntegrationsFBChannel:          envs.IntegrationsFBChannel,
@@ -110,6 +114,7 @@ func main() {
 		WebhookBaseUrl:                 envs.WebhookBaseUrl,
 		Environment:                    envs.Environment,
 		KeywordsRestart:                envs.KeywordsRestart,
+		EndUserCommands:                envs.EndUserCommands,
 		SfcBlockedChatField:            envs.SfcBlockedChatField,
 		StudioNGUrl:                    envs.StudioNGUrl,
 		StudioNGToken:                  envs.StudioNGToken,
@@ -120,14 +125,41 @@ func main() {
 		IntegrationsRateLimit:          envs.IntegrationChanRateLimit,
 		SalesforceRateLimit:            envs.SaleforceChanRateLimit,
 		Messages:                       envs.Messages,
+		MessagesByBot:                  envs.MessagesByBot,
+		LanguageField:                  envs.LanguageField,
 		Timezone:                       envs.Timezone,
 		SendImageNameInMessage:         envs.SendImageNameInMessage,
+		CaseNumberEvent:                string(envs.CaseNumberEvent),
+		MessageLimits:                  envs.MessageLimits,
+		SplitMessageMarkers:            envs.SplitMessageMarkers,
+		ContextMediaAttachments:        envs.ContextMediaAttachments,
+		TranscriptFormat:               envs.TranscriptFormat,
 		KafkaHost:                      envs.KafkaHost,
 		KafkaPort:                      envs.KafkaPort,
 		KafkaUser:                      envs.KafkaUser,
 		KafkaPassword:                  envs.KafkaPassword,
 		KafkaTopic:                     envs.KafkaTopic,
 		SleepLongPollling:              envs.SleepLongPollling,
+		IdempotencyTTL:                 envs.IdempotencyTTL,
+		IdempotencyLockTTL:             envs.IdempotencyLockTTL,
+		ReadinessTimeout:               envs.ReadinessTimeout,
+		ReadinessCacheTTL:              envs.ReadinessCacheTTL,
+		ReadinessNonCritical:           envs.ReadinessNonCritical,
+		CallbackSecret:                 envs.CallbackSecret,
+		CallbackTimeout:                envs.CallbackTimeout,
+		CallbackMaxRetries:             envs.CallbackMaxRetries,
+		AsyncChatTimeout:               envs.AsyncChatTimeout,
+		LifecycleWebhooks:              envs.LifecycleWebhooks,
+		LifecycleWebhookSecret:         envs.LifecycleWebhookSecret,
+		LifecycleWebhookTimeout:        envs.LifecycleWebhookTimeout,
+		LifecycleWebhookMaxAttempts:    envs.LifecycleWebhookMaxAttempts,
+		LifecycleWebhookRetryDelay:     envs.LifecycleWebhookRetryDelay,
+		LifecycleWebhookMaxDelay:       envs.LifecycleWebhookMaxDelay,
+		LifecycleWebhookLogSize:        envs.LifecycleWebhookLogSize,
+		LifecycleWebhookConcurrency:    envs.LifecycleWebhookConcurrency,
+		LifecycleTopic:                 envs.LifecycleTopic,
+		MessageDeliveryTTL:             envs.MessageDeliveryTTL,
+		WindowTemplate:                 envs.WindowTemplate,
 		SfcCustomFieldsToSearchContact: envs.SfcCustomFieldsToSearchContact,
 	}
 
@@ -159,6 +191,14 @@ func main() {
 		IntegrationsSignature: envs.IntegrationsSignature,
 		UseProfile:            envs.UseProfile,
 		IgnoreMessageTypes:    envs.IgnoreMessageTypes,
+		SignatureClockSkew:    envs.SignatureClockSkew,
+		SignatureReplayWindow: envs.SignatureReplayWindow,
+		SignatureReportOnly:   envs.SignatureReportOnly,
+		SecretKeyID:           envs.SecretKeyID,
+		SecretKeys:            envs.PreviousSecretKeys,
+		AccessTokenTTL:        envs.AccessTokenTTL,
+		RefreshTokenTTL:       envs.RefreshTokenTTL,
+		AllowLegacyTokens:     envs.AllowLegacyTokens,
 	})
 
 	httpServer = http.Server{
@@ -0,0 +1,157 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+// noAgentsReason is the reason of the ChatRequestFail event when there are no agents available
+const noAgentsReason = "Unavailable"
+
+// chatRequestOutcome is the status of a chat request and the reason when it was not created
+type chatRequestOutcome struct {
+	status cache.ChatRequestStatus
+	reason string
+}
+
+// CreateChatAsync registers the chat request and creates the chat in background, the outcome is stored by requestID
+// and sent to the callbackURL when there is one. A requestID that was already used returns its chat request
+// without creating another chat
+func (m *Manager) CreateChatAsync(ctx context.Context, requestID, callbackURL string, interconnection *Interconnection) (*cache.ChatRequest, error) {
+	if m.chatRequestCache == nil {
+		return nil, errors.New("the asynchronous chat requests need redis")
+	}
+
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "manager.CreateChatAsync")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, interconnection.UserID)
+	defer span.Finish()
+
+	if requestID == "" {
+		requestID = helpers.RandomString(36)
+	}
+	span.SetTag("requestId", requestID)
+
+	chatRequest := cache.ChatRequest{
+		Client:    m.client,
+		Key:       requestID,
+		UserID:    interconnection.UserID,
+		Status:    cache.ChatRequestPending,
+		Timestamp: time.Now(),
+	}
+	created, err := m.chatRequestCache.CreateAsyncChatRequest(chatRequest, m.idempotencyTTL)
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		return nil, err
+	}
+	if !created {
+		span.SetTag("replayed", true)
+		return m.chatRequestCache.RetrieveAsyncChatRequest(m.client, requestID)
+	}
+
+	go m.runChatRequest(chatRequest, callbackURL, interconnection)
+	return &chatRequest, nil
+}
+
+// GetChatRequest returns the chat request with the requestID
+func (m *Manager) GetChatRequest(requestID string) (*cache.ChatRequest, error) {
+	if m.chatRequestCache == nil {
+		return nil, constants.ErrChatRequestNotFound
+	}
+	return m.chatRequestCache.RetrieveAsyncChatRequest(m.client, requestID)
+}
+
+// runChatRequest creates the chat, stores the outcome of the request and sends it to the callbackURL
+func (m *Manager) runChatRequest(chatRequest cache.ChatRequest, callbackURL string, interconnection *Interconnection) {
+	// datadog tracing, the request that started the chat has already finished
+	span := tracer.StartSpan("manager.runChatRequest")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, interconnection.UserID)
+	span.SetTag("requestId", chatRequest.Key)
+	defer span.Finish()
+	ctx := tracer.ContextWithSpan(context.Background(), span)
+
+	logFields := logrus.Fields{
+		constants.TraceIdKey: span.Context().TraceID(),
+		constants.SpanIdKey:  span.Context().SpanID(),
+		events.UserID:        interconnection.UserID,
+		"requestId":          chatRequest.Key,
+	}
+
+	outcome := m.createChatRequest(ctx, interconnection)
+	chatRequest.Status = outcome.status
+	chatRequest.Error = outcome.reason
+	chatRequest.CaseID = interconnection.CaseID
+	chatRequest.Timestamp = time.Now()
+	span.SetTag("status", chatRequest.Status)
+	logFields["status"] = chatRequest.Status
+
+	if err := m.chatRequestCache.StoreAsyncChatRequest(chatRequest, m.idempotencyTTL); err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not store the chat request")
+	}
+	logrus.WithFields(logFields).Info("Chat request finished")
+
+	if callbackURL == "" || m.callbackClient == nil {
+		return
+	}
+	if err := m.callbackClient.Send(ctx, callbackURL, chatRequest); err != nil {
+		span.SetTag(ext.Error, err)
+		logrus.WithFields(logFields).WithError(err).Error("Could not send the chat request callback")
+	}
+}
+
+// createChatRequest creates the chat holding the lock of the user, and waits until Salesforce accepts or rejects
+// the chat. The chat is considered created when Salesforce does not answer in the chatRequestTimeout
+func (m *Manager) createChatRequest(ctx context.Context, interconnection *Interconnection) chatRequestOutcome {
+	lockToken, err := m.lockChatRequest(ctx, interconnection.UserID)
+	if errors.Is(err, constants.ErrChatRequestInProgress) {
+		return chatRequestOutcome{status: cache.ChatRequestError, reason: err.Error()}
+	}
+	if err != nil {
+		// the chat is created without the lock instead of failing when redis is not available
+		logrus.WithField(events.UserID, interconnection.UserID).WithError(err).Error("Could not lock the chat request")
+	} else {
+		defer func() {
+			if err := m.chatRequestCache.UnlockUser(m.client, interconnection.UserID, lockToken); err != nil {
+				logrus.WithField(events.UserID, interconnection.UserID).WithError(err).Error("Could not unlock the chat request")
+			}
+		}()
+	}
+
+	interconnection.chatRequestResult = make(chan chatRequestOutcome, 1)
+	if err := m.CreateChat(ctx, interconnection); err != nil {
+		if errors.Is(err, constants.ErrContactBlocked) {
+			return chatRequestOutcome{status: cache.ChatRequestBlocked, reason: err.Error()}
+		}
+		return chatRequestOutcome{status: cache.ChatRequestError, reason: err.Error()}
+	}
+
+	select {
+	case outcome := <-interconnection.chatRequestResult:
+		return outcome
+	case <-time.After(m.chatRequestTimeout):
+		return chatRequestOutcome{status: cache.ChatRequestCreated}
+	}
+}
+
+// reportChatRequest sends the outcome of the chat request to the asynchronous request that waits for it,
+// only the first outcome is sent
+func (in *Interconnection) reportChatRequest(status cache.ChatRequestStatus, reason string) {
+	if in.chatRequestResult == nil {
+		return
+	}
+	select {
+	case in.chatRequestResult <- chatRequestOutcome{status: status, reason: reason}:
+	default:
+	}
+}
@@ -0,0 +1,199 @@
+package manage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/chat"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/models"
+)
+
+func TestManager_CreateChatAsync(t *testing.T) {
+	callbackURL := "https://bot.yalochat.com/callback"
+	newInterconnection := func() *Interconnection {
+		return NewInterconnection(&NewInterconnectionParams{
+			UserID:   userID,
+			BotSlug:  botSlug,
+			Name:     name,
+			Provider: provider,
+			Email:    email,
+		})
+	}
+	isPending := func(chatRequest cache.ChatRequest) bool {
+		return chatRequest.Key == "requestID" && chatRequest.UserID == userID && chatRequest.Status == cache.ChatRequestPending
+	}
+
+	t.Run("Should report the blocked contacts to the callback", func(t *testing.T) {
+		MessagesByBot = models.MessageTemplates{botSlug: {FirstNameContact: "Contacto Coppel"}}
+		defer func() { MessagesByBot = nil }()
+		interconnection := newInterconnection()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("GetOrCreateContact", mock.Anything, "Contacto Coppel", name, email, "", mock.Anything).
+			Return(&models.SfcContact{ID: contactID, Blocked: true}, nil).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound).Once()
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", mock.Anything).Return(true, nil)
+
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("CreateAsyncChatRequest", mock.MatchedBy(isPending), time.Minute).Return(true, nil).Once()
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("StoreAsyncChatRequest", mock.MatchedBy(func(chatRequest cache.ChatRequest) bool {
+			return chatRequest.Key == "requestID" && chatRequest.Status == cache.ChatRequestBlocked
+		}), time.Minute).Return(nil).Once()
+
+		done := make(chan cache.ChatRequest, 1)
+		callbackMock := new(mocks.CallbackInterface)
+		callbackMock.On("Send", mock.Anything, callbackURL, mock.Anything).Run(func(args mock.Arguments) {
+			done <- args.Get(2).(cache.ChatRequest)
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			interconnectionMap:    cache.New(),
+			BotrunnnerClient:      botRunnerMock,
+			chatRequestCache:      chatRequestCacheMock,
+			callbackClient:        callbackMock,
+			idempotencyTTL:        time.Minute,
+			idempotencyLockTTL:    time.Second,
+			chatRequestTimeout:    time.Second,
+		}
+		chatRequest, err := manager.CreateChatAsync(context.Background(), "requestID", callbackURL, interconnection)
+
+		assert.NoError(t, err)
+		assert.Equal(t, cache.ChatRequestPending, chatRequest.Status)
+		select {
+		case result := <-done:
+			assert.Equal(t, cache.ChatRequestBlocked, result.Status)
+			assert.Equal(t, "could not create chat in salesforce: this contact is blocked", result.Error)
+		case <-time.After(5 * time.Second):
+			t.Fatal("The callback was not sent")
+		}
+		chatRequestCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should report no agents when Salesforce rejects the chat", func(t *testing.T) {
+		SfcOrganizationID = organizationID
+		SfcDeploymentID = deploymentID
+		interconnection := newInterconnection()
+
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("GetOrCreateContact", mock.Anything, "", name, email, "", mock.Anything).
+			Return(&models.SfcContact{ID: contactID}, nil).Once()
+		salesforceMock.On("CreatCase", mock.Anything, contactID, mock.Anything, mock.Anything, provider, mock.Anything, mock.Anything).
+			Return(caseID, nil).Once()
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).Return(caseNumber, nil).Once()
+		salesforceMock.On("CreatChat", mock.Anything, name, organizationID, deploymentID, mock.Anything, caseID, contactID).
+			Return(&chat.SessionResponse{AffinityToken: affinityToken, Key: sessionKey, Id: "sessionID"}, nil).Once()
+		salesforceMock.On("GetMessages", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(&chat.MessagesResponse{Messages: []chat.MessageObject{
+				{Type: chat.ChatRequestFail, Message: chat.Message{Reason: noAgentsReason}},
+			}}, nil).Once()
+		salesforceMock.On("GetMessages", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(&chat.MessagesResponse{}, nil)
+
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound)
+		interconnectionMock.On("StoreInterconnection", mock.Anything).Return(nil)
+		interconnectionMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client}, string(Failed), string(Closed), string(Failed)).
+			Return(true, nil)
+		contextMock := new(mocks.IContextCache)
+		contextMock.On("RetrieveContextFromSet", client, userID).Return([]cache.Context{})
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", mock.Anything).Return(true, nil)
+
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			contextcache:          contextMock,
+			interconnectionMap:    cache.New(),
+			BotrunnnerClient:      botRunnerMock,
+			finishInterconnection: make(chan *Interconnection, 1),
+			chatRequestCache:      chatRequestCacheMock,
+			idempotencyLockTTL:    time.Second,
+			chatRequestTimeout:    5 * time.Second,
+		}
+		outcome := manager.createChatRequest(context.Background(), interconnection)
+
+		assert.Equal(t, cache.ChatRequestNoAgents, outcome.status)
+		assert.Equal(t, "event [ChatRequestFail] : [Unavailable]", outcome.reason)
+		assert.Equal(t, caseID, interconnection.CaseID)
+	})
+
+	t.Run("Should return the chat request of the request ID", func(t *testing.T) {
+		stored := &cache.ChatRequest{Client: client, Key: "requestID", UserID: userID, Status: cache.ChatRequestCreated}
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("CreateAsyncChatRequest", mock.MatchedBy(isPending), time.Minute).Return(false, nil).Once()
+		chatRequestCacheMock.On("RetrieveAsyncChatRequest", client, "requestID").Return(stored, nil).Once()
+
+		manager := &Manager{client: client, chatRequestCache: chatRequestCacheMock, idempotencyTTL: time.Minute}
+		chatRequest, err := manager.CreateChatAsync(context.Background(), "requestID", "", newInterconnection())
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored, chatRequest)
+		chatRequestCacheMock.AssertNotCalled(t, "LockUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return an error without the chat request cache", func(t *testing.T) {
+		manager := &Manager{client: client}
+
+		_, err := manager.CreateChatAsync(context.Background(), "requestID", "", newInterconnection())
+
+		assert.Error(t, err)
+	})
+}
+
+func TestManager_GetChatRequest(t *testing.T) {
+	t.Run("Should return the chat request", func(t *testing.T) {
+		stored := &cache.ChatRequest{Client: client, Key: "requestID", Status: cache.ChatRequestNoAgents}
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("RetrieveAsyncChatRequest", client, "requestID").Return(stored, nil).Once()
+
+		manager := &Manager{client: client, chatRequestCache: chatRequestCacheMock}
+		chatRequest, err := manager.GetChatRequest("requestID")
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored, chatRequest)
+	})
+
+	t.Run("Should return not found without the chat request cache", func(t *testing.T) {
+		manager := &Manager{client: client}
+
+		_, err := manager.GetChatRequest("requestID")
+
+		assert.ErrorIs(t, err, constants.ErrChatRequestNotFound)
+	})
+}
+
+func TestInterconnection_reportChatRequest(t *testing.T) {
+	t.Run("Should send only the first outcome", func(t *testing.T) {
+		interconnection := &Interconnection{chatRequestResult: make(chan chatRequestOutcome, 1)}
+
+		interconnection.reportChatRequest(cache.ChatRequestCreated, "")
+		interconnection.reportChatRequest(cache.ChatRequestError, "the chat finished with status CLOSED")
+
+		assert.Equal(t, chatRequestOutcome{status: cache.ChatRequestCreated}, <-interconnection.chatRequestResult)
+	})
+
+	t.Run("Should not block without an asynchronous request", func(t *testing.T) {
+		interconnection := &Interconnection{}
+
+		interconnection.reportChatRequest(cache.ChatRequestCreated, "")
+	})
+}
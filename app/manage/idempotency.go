@@ -0,0 +1,121 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+// chatRequestLockWait is the time between the attempts to take the lock of the user
+var chatRequestLockWait = 200 * time.Millisecond
+
+// CreateChatIdempotent creates the chat only once by idempotency key, the retries of a request return the original
+// result and the concurrent requests of the same user wait until the first one ends.
+// The results are stored by user, so the same idempotencyKey of another user does not return them.
+// Without idempotencyKey the key is the bot, and the result is only returned while the chat is open,
+// so the user can request a new chat when the previous one failed or ended
+func (m *Manager) CreateChatIdempotent(ctx context.Context, idempotencyKey string, interconnection *Interconnection) error {
+	if m.chatRequestCache == nil {
+		return m.CreateChat(ctx, interconnection)
+	}
+
+	// datadog tracing
+	span, ctx := tracer.StartSpanFromContext(ctx, "manager.CreateChatIdempotent")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, interconnection.UserID)
+	defer span.Finish()
+
+	key := idempotencyKey
+	if key == "" {
+		key = interconnection.BotSlug
+	}
+	span.SetTag("idempotencyKey", key)
+	logFields := logrus.Fields{
+		events.UserID:    interconnection.UserID,
+		"idempotencyKey": key,
+	}
+
+	lockToken, err := m.lockChatRequest(ctx, interconnection.UserID)
+	if err != nil {
+		if errors.Is(err, constants.ErrChatRequestInProgress) || ctx.Err() != nil {
+			span.SetTag(ext.Error, err)
+			return err
+		}
+		// the chat is created without idempotency instead of failing when redis is not available
+		logrus.WithFields(logFields).WithError(err).Error("Could not lock the chat request")
+		return m.CreateChat(ctx, interconnection)
+	}
+	defer func() {
+		if err := m.chatRequestCache.UnlockUser(m.client, interconnection.UserID, lockToken); err != nil {
+			logrus.WithFields(logFields).WithError(err).Error("Could not unlock the chat request")
+		}
+	}()
+
+	chatRequest, err := m.chatRequestCache.RetrieveChatRequest(m.client, interconnection.UserID, key)
+	if err != nil && !errors.Is(err, constants.ErrChatRequestNotFound) {
+		logrus.WithFields(logFields).WithError(err).Error("Could not retrieve the chat request")
+	}
+	if chatRequest != nil && chatRequest.Status == cache.ChatRequestPending {
+		err := fmt.Errorf("%w with the key %s", constants.ErrChatRequestInProgress, key)
+		span.SetTag(ext.Error, err)
+		return err
+	}
+	if chatRequest != nil && (idempotencyKey != "" || m.ValidateUserID(ctx, interconnection.UserID) != nil) {
+		logrus.WithFields(logFields).Info("Chat request already processed")
+		span.SetTag("replayed", true)
+		if chatRequest.Error != "" {
+			return errors.New(chatRequest.Error)
+		}
+		return nil
+	}
+
+	err = m.CreateChat(ctx, interconnection)
+
+	chatRequest = &cache.ChatRequest{
+		Client:    m.client,
+		Key:       key,
+		UserID:    interconnection.UserID,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		chatRequest.Error = err.Error()
+	}
+	if errStore := m.chatRequestCache.StoreChatRequest(*chatRequest, m.idempotencyTTL); errStore != nil {
+		logrus.WithFields(logFields).WithError(errStore).Error("Could not store the chat request")
+	}
+	return err
+}
+
+// lockChatRequest waits until it takes the lock of the user or the lock expires, it returns the token of the lock
+// that releases it
+func (m *Manager) lockChatRequest(ctx context.Context, userID string) (string, error) {
+	token := helpers.RandomString(36)
+	deadline := time.Now().Add(m.idempotencyLockTTL)
+	for {
+		ok, err := m.chatRequestCache.LockUser(m.client, userID, token, m.idempotencyLockTTL)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return token, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%w for the user %s", constants.ErrChatRequestInProgress, userID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(chatRequestLockWait):
+		}
+	}
+}
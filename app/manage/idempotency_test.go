@@ -0,0 +1,202 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+func TestManager_CreateChatIdempotent(t *testing.T) {
+	derivedKey := botSlug
+	activeSession := &cache.Interconnection{UserID: userID, Client: client, Status: string(Active)}
+
+	newManager := func(chatRequestCache cache.IChatRequestCache, interconnectionsCache cache.IInterconnectionCache) *Manager {
+		return &Manager{
+			client:                client,
+			chatRequestCache:      chatRequestCache,
+			interconnectionsCache: interconnectionsCache,
+			interconnectionMap:    cache.New(),
+			SalesforceService:     new(mocks.SalesforceServiceInterface),
+			idempotencyTTL:        time.Minute,
+			idempotencyLockTTL:    time.Second,
+		}
+	}
+	newInterconnection := func() *Interconnection {
+		return NewInterconnection(&NewInterconnectionParams{UserID: userID, BotSlug: botSlug, Provider: provider})
+	}
+
+	t.Run("Should return the original result of the idempotency key", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, "requestKey").
+			Return(&cache.ChatRequest{Client: client, Key: "requestKey", UserID: userID}, nil).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.NoError(t, err)
+		interconnectionMock.AssertNotCalled(t, "RetrieveInterconnection", mock.Anything)
+		chatRequestCacheMock.AssertNotCalled(t, "StoreChatRequest", mock.Anything, mock.Anything)
+		chatRequestCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should release the lock with the token that took it", func(t *testing.T) {
+		var lockToken string
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).
+			Run(func(args mock.Arguments) { lockToken = args.String(2) }).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.MatchedBy(func(token string) bool {
+			return token != "" && token == lockToken
+		})).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, "requestKey").
+			Return(&cache.ChatRequest{Client: client, Key: "requestKey", UserID: userID}, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, new(mocks.IInterconnectionCache))
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.NoError(t, err)
+		chatRequestCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return the original error of the idempotency key", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, "requestKey").
+			Return(&cache.ChatRequest{Client: client, Key: "requestKey", UserID: userID, Error: "could not create chat"}, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, new(mocks.IInterconnectionCache))
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.EqualError(t, err, "could not create chat")
+	})
+
+	t.Run("Should return an error while the asynchronous request of the key is pending", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, "requestKey").
+			Return(&cache.ChatRequest{Client: client, Key: "requestKey", UserID: userID, Status: cache.ChatRequestPending}, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, new(mocks.IInterconnectionCache))
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.ErrorIs(t, err, constants.ErrChatRequestInProgress)
+	})
+
+	t.Run("Should create the chat and store the result", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, "requestKey").
+			Return(nil, constants.ErrChatRequestNotFound).Once()
+		chatRequestCacheMock.On("StoreChatRequest", mock.MatchedBy(func(chatRequest cache.ChatRequest) bool {
+			return chatRequest.Key == "requestKey" && chatRequest.UserID == userID &&
+				strings.Contains(chatRequest.Error, "session exists in redis with this userID")
+		}), time.Minute).Return(nil).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(activeSession, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.Error(t, err)
+		chatRequestCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return the result of the user and bot while the chat is open", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, derivedKey).
+			Return(&cache.ChatRequest{Client: client, Key: derivedKey, UserID: userID}, nil).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(activeSession, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "", newInterconnection())
+
+		assert.NoError(t, err)
+		chatRequestCacheMock.AssertNotCalled(t, "StoreChatRequest", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should create a new chat of the user and bot when the previous one ended", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(true, nil).Once()
+		chatRequestCacheMock.On("UnlockUser", client, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		chatRequestCacheMock.On("RetrieveChatRequest", client, userID, derivedKey).
+			Return(&cache.ChatRequest{Client: client, Key: derivedKey, UserID: userID}, nil).Once()
+		chatRequestCacheMock.On("StoreChatRequest", mock.Anything, time.Minute).Return(nil).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound).Once()
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(activeSession, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "", newInterconnection())
+
+		assert.Error(t, err)
+		interconnectionMock.AssertNumberOfCalls(t, "RetrieveInterconnection", 2)
+		chatRequestCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return an error when the request of the user is in progress", func(t *testing.T) {
+		lockWait := chatRequestLockWait
+		chatRequestLockWait = time.Millisecond
+		defer func() {
+			chatRequestLockWait = lockWait
+		}()
+
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), 10*time.Millisecond).Return(false, nil)
+		interconnectionMock := new(mocks.IInterconnectionCache)
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		manager.idempotencyLockTTL = 10 * time.Millisecond
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.ErrorIs(t, err, constants.ErrChatRequestInProgress)
+		chatRequestCacheMock.AssertNotCalled(t, "UnlockUser", mock.Anything, mock.Anything, mock.Anything)
+		interconnectionMock.AssertNotCalled(t, "RetrieveInterconnection", mock.Anything)
+	})
+
+	t.Run("Should create the chat when redis fails", func(t *testing.T) {
+		chatRequestCacheMock := new(mocks.IChatRequestCache)
+		chatRequestCacheMock.On("LockUser", client, userID, mock.AnythingOfType("string"), time.Second).Return(false, errors.New("connection refused")).Once()
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(activeSession, nil).Once()
+
+		manager := newManager(chatRequestCacheMock, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.Error(t, err)
+		interconnectionMock.AssertExpectations(t)
+		chatRequestCacheMock.AssertNotCalled(t, "StoreChatRequest", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should create the chat without the chat request cache", func(t *testing.T) {
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(activeSession, nil).Once()
+
+		manager := newManager(nil, interconnectionMock)
+		err := manager.CreateChatIdempotent(context.Background(), "requestKey", newInterconnection())
+
+		assert.Error(t, err)
+		interconnectionMock.AssertExpectations(t)
+	})
+}
@@ -23,6 +23,8 @@ import (
 	"yalochat.com/salesforce-integration/base/clients/integrations"
 	"yalochat.com/salesforce-integration/base/clients/studiong"
 	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/metrics"
+	"yalochat.com/salesforce-integration/base/models"
 )
 
 // Status that an interconnection can have
@@ -54,7 +56,9 @@ type Interconnection struct {
 	Email                string                              `json:"email"`
 	PhoneNumber          string                              `json:"phoneNumber"`
 	CaseID               string                              `json:"caseId"`
+	CaseNumber           string                              `json:"caseNumber"`
 	Context              string                              `json:"-"`
+	LastUserMessageAt    time.Time                           `json:"-"`
 	ExtraData            map[string]interface{}              `json:"extraData"`
 	finishChannel        chan *Interconnection               `json:"-"`
 	BotrunnnerClient     botrunner.BotRunnerInterface        `json:"-"`
@@ -71,6 +75,17 @@ type Interconnection struct {
 	SleepLongPolling time.Duration
 	// ack is a sequencing mechanism that allows you to poll for messages on the Live Agent server
 	ack int
+	// agentName and agentID are the name and the ID of the agent that accepted the chat
+	agentName string
+	agentID   string
+	// proactive is true when an agent started the chat, the user is not told to wait for an agent
+	proactive bool
+	// chatRequestResult receives the outcome of the chat request when an asynchronous request waits for it
+	chatRequestResult chan chatRequestOutcome
+	// lifecycle publishes the lifecycle events of the chat
+	lifecycle lifecyclePublisher
+	// windowTemplateSentAt is the time when the user was invited to continue the chat after the window closed
+	windowTemplateSentAt time.Time
 }
 
 type InterconnectionMessageQueue struct {
@@ -90,7 +105,8 @@ type Message struct {
 	ID            string      `json:"id"`
 	MainSpan      tracer.Span `json:"-"`
 	Text          string      `json:"text"`
-	ImageUrl      string      `json:"imageUrl"`
+	MediaURL      string      `json:"mediaUrl"`
+	MediaType     string      `json:"mediaType"`
 	UserID        string      `json:"userID"`
 	SessionKey    string      `json:"sessionKey"`
 	AffinityToken string      `json:"affinityToken"`
@@ -167,8 +183,10 @@ func (in *Interconnection) handleLongPolling() {
 
 	in.runnigLongPolling = true
 	for in.runnigLongPolling {
+		started := time.Now()
 		response, errorResponse := in.SalesforceService.
 			GetMessages(mainSpan, in.AffinityToken, in.SessionKey, in.ack)
+		in.observeLongPolling(started, errorResponse)
 		if errorResponse != nil {
 			// fmt.Println("interconnection.errorResponse: ", errorResponse.Error.Error())
 			switch errorResponse.StatusCode {
@@ -179,7 +197,7 @@ func (in *Interconnection) handleLongPolling() {
 				<-time.After(in.SleepLongPolling)
 			case http.StatusForbidden:
 				go ChangeToState(in.UserID, in.BotSlug, TimeoutState[string(in.Provider)], in.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, in.StudioNG, in.isStudioNGFlow)
-				in.finishLongPolling(Closed)
+				in.finishLongPolling(Closed, sessionExpiredReason, "")
 				logrus.WithFields(logFields).Error("StatusForbidden")
 				mainSpan.SetTag(ext.Error, errorResponse.Error)
 				mainSpan.SetTag(events.StatusSalesforce, errorResponse.StatusCode)
@@ -191,7 +209,7 @@ func (in *Interconnection) handleLongPolling() {
 				reconnect, err := in.SalesforceService.ReconnectSession(in.SessionKey, strconv.Itoa(in.offset))
 				if err != nil {
 					go ChangeToState(in.UserID, in.BotSlug, TimeoutState[string(in.Provider)], in.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, in.StudioNG, in.isStudioNGFlow)
-					in.finishLongPolling(Closed)
+					in.finishLongPolling(Closed, connectionLostReason, "")
 					logrus.WithFields(logFields).WithError(err).Error("Reconnect session failed")
 					mainSpan.SetTag(ext.Error, err)
 					continue
@@ -219,7 +237,7 @@ func (in *Interconnection) handleLongPolling() {
 					in.StudioNG,
 					in.isStudioNGFlow,
 				)
-				in.finishLongPolling(Closed)
+				in.finishLongPolling(Closed, connectionLostReason, "")
 				mainSpan.SetTag(ext.Error, errorResponse.Error)
 				mainSpan.SetTag(events.StatusSalesforce, errorResponse.StatusCode)
 			}
@@ -241,6 +259,20 @@ func (in *Interconnection) handleLongPolling() {
 	}
 }
 
+// observeLongPolling measures the request of the long polling by the status of the response, the requests without
+// response, like the timeouts, have the status error
+func (in *Interconnection) observeLongPolling(started time.Time, errorResponse *helpers.ErrorResponse) {
+	status := strconv.Itoa(http.StatusOK)
+	if errorResponse != nil {
+		status = "error"
+		if errorResponse.StatusCode != 0 {
+			status = strconv.Itoa(errorResponse.StatusCode)
+		}
+	}
+	metrics.LongPollingDuration.WithLabelValues(string(in.Provider), in.BotSlug, status).
+		Observe(time.Since(started).Seconds())
+}
+
 func (in *Interconnection) checkEvent(mainSpan tracer.Span, event *chat.MessageObject) {
 	// datadog tracing
 	spanContext := events.GetSpanContextFromSpan(mainSpan)
@@ -260,36 +292,62 @@ func (in *Interconnection) checkEvent(mainSpan tracer.Span, event *chat.MessageO
 	case chat.ChatRequestFail:
 		logrus.WithFields(logFields).Infof("Event [%s] : [%s]", chat.ChatRequestFail, event.Message.Reason)
 		mainSpan.SetTag(ext.Error, fmt.Errorf("event [%s] : [%s]", chat.ChatRequestFail, event.Message.Reason))
+		status, reason := cache.ChatRequestError, requestFailedReason
+		if event.Message.Reason == noAgentsReason {
+			status, reason = cache.ChatRequestNoAgents, string(cache.ChatRequestNoAgents)
+		}
+		in.reportChatRequest(status, fmt.Sprintf("event [%s] : [%s]", chat.ChatRequestFail, event.Message.Reason))
 		go ChangeToState(in.UserID, in.BotSlug, TimeoutState[string(in.Provider)], in.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, in.StudioNG, in.isStudioNGFlow)
-		in.finishLongPolling(Failed)
+		in.finishLongPolling(Failed, reason, "")
 	case chat.ChatRequestSuccess:
 		logrus.WithFields(logFields).Infof("Event [%s]", chat.ChatRequestSuccess)
-		if Messages.WaitAgent != "" {
+		in.reportChatRequest(cache.ChatRequestCreated, "")
+		in.publishQueue(ChatQueuedEvent, event.Message.QueuePosition, event.Message.EstimatedWaitTime)
+		variables := in.templateVariables()
+		variables.QueuePosition = event.Message.QueuePosition
+		variables.WaitTime = event.Message.EstimatedWaitTime
+		if waitAgent := in.renderMessage(span, in.messages().WaitAgent, variables); waitAgent != "" && !in.proactive {
 			in.sendMessageToQueue(span,
 				helpers.RandomString(36),
-				Messages.WaitAgent,
+				waitAgent,
 				constants.SendMessageToUser)
 		}
+		if CaseNumberEvent == chat.ChatRequestSuccess {
+			in.sendCaseNumber(span)
+		}
 		//in.integrationsChannel <- NewIntegrationsMessage(in.UserID, fmt.Sprintf("%s : %v", Messages.QueuePosition, event.Message.QueuePosition), in.Provider)
 		//in.integrationsChannel <- NewIntegrationsMessage(in.UserID, fmt.Sprintf("%s : %vs", Messages.WaitTime, event.Message.EstimatedWaitTime), in.Provider)
 	case chat.ChatEstablished:
 		logrus.WithFields(logFields).Infof("Event [%s]", event.Type)
+		in.agentName = event.Message.Name
+		in.agentID = event.Message.UserId
 		in.ActiveChat(span)
+		in.publishLifecycle(ChatAcceptedEvent, "")
+		if CaseNumberEvent == chat.ChatEstablished {
+			in.sendCaseNumber(span)
+		}
+	case chat.ChatTransferred:
+		logrus.WithFields(logFields).Infof("Event [%s]", event.Type)
+		in.agentName = event.Message.Name
+		in.agentID = event.Message.UserId
+		in.publishLifecycle(ChatTransferredEvent, "")
 	case chat.ChatMessage:
 		logrus.WithFields(logFields).Infof("Message from salesforce : %s", event.Message.Text)
+		messageID := helpers.RandomString(36)
 		in.sendMessageToQueue(span,
-			helpers.RandomString(36),
+			messageID,
 			event.Message.Text,
 			constants.SendMessageToUser)
+		in.publishMessage(ChatMessageOutEvent, messageID, constants.TextType, event.Message.Text)
 	case chat.QueueUpdate:
 		logrus.WithFields(logFields).Infof("Event [%s]", chat.QueueUpdate)
+		in.publishQueue(ChatQueueUpdatedEvent, event.Message.Position, event.Message.EstimatedWaitTime)
 		/*if event.Message.QueuePosition > 0 {
 			in.integrationsChannel <- NewIntegrationsMessage(in.UserID, fmt.Sprintf("%s : %v", Messages.QueuePosition, event.Message.QueuePosition), in.Provider)
 			in.integrationsChannel <- NewIntegrationsMessage(in.UserID, fmt.Sprintf("%s : %vs", Messages.WaitTime, event.Message.EstimatedWaitTime), in.Provider)
 		}*/
 	case chat.ChatEnded:
-		go ChangeToState(in.UserID, in.BotSlug, SuccessState[string(in.Provider)], in.BotrunnnerClient, 0, 0, in.StudioNG, in.isStudioNGFlow)
-		in.finishLongPolling(Closed)
+		in.finishLongPolling(Closed, agentEndedReason, SuccessState[string(in.Provider)])
 	default:
 		logrus.WithFields(logFields).Infof("Event [%s]", event.Type)
 	}
@@ -303,13 +361,22 @@ func (in *Interconnection) ActiveChat(mainSpan tracer.Span) {
 		mainSpan.SetTag("SendContext", in.Context != "")
 		in.Context = ""
 	}
-	if Messages.WelcomeTemplate != "" {
-		in.sendMessageToSalesforce(NewSfMessage(mainSpan, in.AffinityToken, in.SessionKey, fmt.Sprintf(Messages.WelcomeTemplate, in.Name), in.UserID))
+	if welcomeMessage := in.renderMessage(mainSpan, in.messages().WelcomeTemplate, in.templateVariables(), in.Name); welcomeMessage != "" {
+		in.sendMessageToSalesforce(NewSfMessage(mainSpan, in.AffinityToken, in.SessionKey, welcomeMessage, in.UserID))
+	}
+}
+
+// messages returns the template set of the bot and the language of the user
+func (in *Interconnection) messages() models.MessageTemplate {
+	language := ""
+	if value, ok := in.ExtraData[LanguageField].(string); ok && LanguageField != "" {
+		language = value
 	}
+	return MessagesByBot.Get(Messages, in.BotSlug, language)
 }
 
 func convertInterconnectionCacheToInterconnection(interconnection cache.Interconnection) *Interconnection {
-	return &Interconnection{
+	in := &Interconnection{
 		UserID:        interconnection.UserID,
 		Client:        interconnection.Client,
 		SessionID:     interconnection.SessionID,
@@ -324,8 +391,17 @@ func convertInterconnectionCacheToInterconnection(interconnection cache.Intercon
 		Email:         interconnection.Email,
 		PhoneNumber:   interconnection.PhoneNumber,
 		CaseID:        interconnection.CaseID,
+		CaseNumber:    interconnection.CaseNumber,
 		ExtraData:     interconnection.ExtraData,
+
+		LastUserMessageAt:    interconnection.LastUserMessageAt,
+		windowTemplateSentAt: interconnection.WindowTemplateSentAt,
+	}
+	// the chats stored before the window was tracked take the creation as the last message of the user
+	if in.LastUserMessageAt.IsZero() && in.windowTemplateSentAt.IsZero() {
+		in.LastUserMessageAt = in.Timestamp
 	}
+	return in
 }
 
 func (in *Interconnection) updateStatusRedis(status string) {
@@ -342,6 +418,18 @@ func (in *Interconnection) updateStatusRedis(status string) {
 	}
 }
 
+// finishStatusRedis sets the status of the chat in redis unless it is already finished, it returns false when the
+// chat was finished before, the chat is taken as finished here when redis fails so its end is not lost
+func (in *Interconnection) finishStatusRedis(status InterconnectionStatus) bool {
+	finished, err := in.interconnectionCache.FinishInterconnection(cache.Interconnection{UserID: in.UserID, Client: in.Client},
+		string(status), string(Closed), string(Failed))
+	if err != nil {
+		logrus.Errorf("Could not finish interconnection userID[%s]-client[%s] in redis : [%s]", in.UserID, in.Client, err.Error())
+		return true
+	}
+	return finished
+}
+
 func (in *Interconnection) updateAffinityTokenRedis(affinityToken string) {
 	interconnectionCache, err := in.interconnectionCache.RetrieveInterconnection(cache.Interconnection{UserID: in.UserID, Client: in.Client})
 
@@ -357,6 +445,34 @@ func (in *Interconnection) updateAffinityTokenRedis(affinityToken string) {
 	}
 }
 
+func (in *Interconnection) updateLastUserMessageRedis(lastUserMessageAt time.Time) {
+	interconnectionCache, err := in.interconnectionCache.RetrieveInterconnection(cache.Interconnection{UserID: in.UserID, Client: in.Client})
+	if err != nil {
+		logrus.Errorf("Could not update last user message in interconnection userID[%s]-client[%s] from redis : [%s]", in.UserID, in.Client, err.Error())
+		return
+	}
+
+	interconnectionCache.LastUserMessageAt = lastUserMessageAt
+	err = in.interconnectionCache.StoreInterconnection(*interconnectionCache)
+	if err != nil {
+		logrus.Errorf("Could not update last user message in interconnection userID[%s]-client[%s] from redis : [%s]", in.UserID, in.Client, err.Error())
+	}
+}
+
+func (in *Interconnection) updateWindowTemplateRedis(windowTemplateSentAt time.Time) {
+	interconnectionCache, err := in.interconnectionCache.RetrieveInterconnection(cache.Interconnection{UserID: in.UserID, Client: in.Client})
+	if err != nil {
+		logrus.Errorf("Could not update window template in interconnection userID[%s]-client[%s] from redis : [%s]", in.UserID, in.Client, err.Error())
+		return
+	}
+
+	interconnectionCache.WindowTemplateSentAt = windowTemplateSentAt
+	err = in.interconnectionCache.StoreInterconnection(*interconnectionCache)
+	if err != nil {
+		logrus.Errorf("Could not update window template in interconnection userID[%s]-client[%s] from redis : [%s]", in.UserID, in.Client, err.Error())
+	}
+}
+
 func (in *Interconnection) sendMessageToSalesforce(message *Message) {
 	// datadog tracing
 	spanContext := events.GetSpanContextFromSpan(message.MainSpan)
@@ -366,10 +482,16 @@ func (in *Interconnection) sendMessageToSalesforce(message *Message) {
 	span.SetTag(events.UserID, message.UserID)
 	span.SetTag(events.SendMessage, false)
 	defer span.Finish()
-	_, err := in.SalesforceService.SendMessage(span, message.AffinityToken, message.SessionKey, chat.MessagePayload{Text: message.Text})
-	if err != nil {
-		span.SetTag(ext.Error, err)
-		logrus.WithField(events.UserID, message.UserID).Error(helpers.ErrorMessage("Error sendMessage", err))
+	labels := []string{metrics.ToSalesforce, string(in.Provider), in.BotSlug}
+	for _, text := range splitMessage(message.Text, salesforceDestination) {
+		_, err := in.SalesforceService.SendMessage(span, message.AffinityToken, message.SessionKey, chat.MessagePayload{Text: text})
+		if err != nil {
+			span.SetTag(ext.Error, err)
+			logrus.WithField(events.UserID, message.UserID).Error(helpers.ErrorMessage("Error sendMessage", err))
+			metrics.MessagesDropped.WithLabelValues(labels...).Inc()
+			continue
+		}
+		metrics.MessagesForwarded.WithLabelValues(labels...).Inc()
 	}
 	span.SetTag(events.SendMessage, true)
 	logrus.Infof("Send message to agent from salesforce : %s", message.UserID)
@@ -423,9 +545,64 @@ func (in *Interconnection) sendMessageToQueue(mainSpan tracer.Span, messageID, t
 	}
 }
 
-func (in *Interconnection) finishLongPolling(status InterconnectionStatus) {
-	go in.updateStatusRedis(string(status))
+// finishLongPolling ends the chat with the status and sends the user to the state of the bot when it is not empty,
+// the end is only published when the long polling was running and the chat was still open in redis, the chats
+// closed by the manager or by another pod are already finished
+func (in *Interconnection) finishLongPolling(status InterconnectionStatus, reason, state string) {
+	in.reportChatRequest(cache.ChatRequestError, fmt.Sprintf("the chat finished with status %s", status))
+	finished := in.finishStatusRedis(status)
 	in.Status = status
+	if in.runnigLongPolling && finished {
+		in.publishLifecycle(endedEventType(status, reason), reason)
+		if state != "" {
+			go ChangeToState(in.UserID, in.BotSlug, state, in.BotrunnnerClient, 0, 0, in.StudioNG, in.isStudioNGFlow)
+		}
+	}
 	in.runnigLongPolling = false
 	in.finishChannel <- in
 }
+
+// templateVariables returns the variables of the interconnection for the messages
+func (in *Interconnection) templateVariables() models.TemplateVariables {
+	return models.TemplateVariables{
+		Name:       in.Name,
+		CaseNumber: in.CaseNumber,
+		AgentName:  in.agentName,
+		BotSlug:    in.BotSlug,
+		Provider:   string(in.Provider),
+		ExtraData:  in.ExtraData,
+	}
+}
+
+// sendCaseNumber sends the number of the case to the user, so it can be referenced later
+func (in *Interconnection) sendCaseNumber(mainSpan tracer.Span) {
+	if in.CaseNumber == "" {
+		return
+	}
+
+	if message := in.renderMessage(mainSpan, in.messages().CaseNumberTemplate, in.templateVariables()); message != "" {
+		in.sendMessageToQueue(mainSpan,
+			helpers.RandomString(36),
+			message,
+			constants.SendMessageToUser)
+	}
+}
+
+// renderMessage returns the message with the variables, it returns an empty message when it can't be rendered
+func (in *Interconnection) renderMessage(mainSpan tracer.Span, message string, variables models.TemplateVariables, args ...interface{}) string {
+	if message == "" {
+		return ""
+	}
+
+	text, err := models.RenderMessage(message, variables, args...)
+	if err != nil {
+		mainSpan.SetTag(ext.Error, err)
+		logrus.WithFields(logrus.Fields{
+			constants.TraceIdKey: mainSpan.Context().TraceID(),
+			constants.SpanIdKey:  mainSpan.Context().SpanID(),
+			events.UserID:        in.UserID,
+		}).WithError(err).Error("Could not render the message")
+		return ""
+	}
+	return text
+}
@@ -3,6 +3,7 @@ package manage
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -15,6 +16,7 @@ import (
 	"yalochat.com/salesforce-integration/app/manage/mocks"
 	"yalochat.com/salesforce-integration/base/helpers"
 	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
 
 	"github.com/go-redis/redis"
 	"github.com/sirupsen/logrus"
@@ -485,6 +487,27 @@ func TestCheckEvent_test(t *testing.T) {
 		}
 	})
 
+	t.Run("Chat request success event received in a proactive chat", func(t *testing.T) {
+		Messages = models.MessageTemplate{WaitAgent: "Esperando Agente"}
+		event := chat.MessageObject{
+			Type:    chat.ChatRequestSuccess,
+			Message: chat.Message{},
+		}
+
+		producer := interconnection.kafkaProducer
+		producerMock := new(mocks.Producer)
+		interconnection.kafkaProducer = producerMock
+		interconnection.proactive = true
+		defer func() {
+			interconnection.kafkaProducer = producer
+			interconnection.proactive = false
+		}()
+
+		span, _ := tracer.SpanFromContext(context.Background())
+		interconnection.checkEvent(span, &event)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+
 	t.Run("Chat Established event received", func(t *testing.T) {
 		Messages = models.MessageTemplate{WelcomeTemplate: "Hola soy %s y necesito ayuda"}
 		interconnection.Context = "Contexto"
@@ -780,3 +803,107 @@ func TestInterconnection_updateAffinityTokenRedis(t *testing.T) {
 		}
 	})
 }
+
+func TestInterconnection_messages(t *testing.T) {
+	Messages = models.MessageTemplate{WaitAgent: "Esperando un agente", BotLabel: "Bot"}
+	MessagesByBot = models.MessageTemplates{
+		"coppel-bot":    {WaitAgent: "Esperando un asesor"},
+		"coppel-bot:en": {WaitAgent: "Waiting for an agent"},
+	}
+	LanguageField = "language"
+	defer func() {
+		Messages = models.MessageTemplate{}
+		MessagesByBot = nil
+		LanguageField = ""
+	}()
+
+	t.Run("Should get the messages of the bot and language", func(t *testing.T) {
+		interconnection := &Interconnection{BotSlug: "coppel-bot", ExtraData: map[string]interface{}{"language": "en"}}
+		assert.Equal(t, models.MessageTemplate{WaitAgent: "Waiting for an agent", BotLabel: "Bot"}, interconnection.messages())
+	})
+
+	t.Run("Should get the messages of the bot", func(t *testing.T) {
+		interconnection := &Interconnection{BotSlug: "coppel-bot"}
+		assert.Equal(t, models.MessageTemplate{WaitAgent: "Esperando un asesor", BotLabel: "Bot"}, interconnection.messages())
+	})
+
+	t.Run("Should get the default messages", func(t *testing.T) {
+		interconnection := &Interconnection{BotSlug: "other-bot", ExtraData: map[string]interface{}{"language": 1}}
+		assert.Equal(t, Messages, interconnection.messages())
+	})
+}
+
+func TestInterconnection_renderMessage(t *testing.T) {
+	interconnection := &Interconnection{
+		UserID:    userID,
+		Name:      "Eduardo",
+		BotSlug:   "coppel-bot",
+		Provider:  WhatsappProvider,
+		ExtraData: map[string]interface{}{"segment": "gold"},
+		agentName: "Ana",
+	}
+	span, _ := tracer.SpanFromContext(context.Background())
+
+	t.Run("Should render the message with the variables of the interconnection", func(t *testing.T) {
+		variables := interconnection.templateVariables()
+		variables.QueuePosition = 3
+		message := interconnection.renderMessage(span,
+			`{{.Name}} {{.AgentName}} {{.BotSlug}} {{.Provider}} {{extra "segment"}} {{.QueuePosition}}`,
+			variables)
+		assert.Equal(t, "Eduardo Ana coppel-bot whatsapp gold 3", message)
+	})
+
+	t.Run("Should render the message with the fmt format", func(t *testing.T) {
+		message := interconnection.renderMessage(span, "Hola soy %s y necesito ayuda", interconnection.templateVariables(), interconnection.Name)
+		assert.Equal(t, "Hola soy Eduardo y necesito ayuda", message)
+	})
+
+	t.Run("Should return an empty message when it can't be rendered", func(t *testing.T) {
+		message := interconnection.renderMessage(span, "Hola {{.LastName}}", interconnection.templateVariables())
+		assert.Empty(t, message)
+	})
+}
+
+func TestInterconnection_sendCaseNumber(t *testing.T) {
+	Messages = models.MessageTemplate{CaseNumberTemplate: "Tu caso es el {{.CaseNumber}}"}
+	defer func() {
+		Messages = models.MessageTemplate{}
+		CaseNumberEvent = ""
+	}()
+	span, _ := tracer.SpanFromContext(context.Background())
+
+	t.Run("Should send the case number at the configured event", func(t *testing.T) {
+		CaseNumberEvent = chat.ChatRequestSuccess
+		var sentMessages []string
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			message := InterconnectionMessageQueue{}
+			json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &message)
+			sentMessages = append(sentMessages, message.Params.Text)
+		}).Return(nil)
+
+		interconnection := &Interconnection{UserID: userID, CaseNumber: "00001001", kafkaProducer: producerMock}
+		interconnection.checkEvent(span, &chat.MessageObject{Type: chat.ChatRequestSuccess})
+
+		assert.Equal(t, []string{"Tu caso es el 00001001"}, sentMessages)
+	})
+
+	t.Run("Shouldn't send the case number at other event", func(t *testing.T) {
+		CaseNumberEvent = chat.ChatEstablished
+		producerMock := new(mocks.Producer)
+
+		interconnection := &Interconnection{UserID: userID, CaseNumber: "00001001", kafkaProducer: producerMock}
+		interconnection.checkEvent(span, &chat.MessageObject{Type: chat.ChatRequestSuccess})
+
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+
+	t.Run("Shouldn't send the case number when the case doesn't have it", func(t *testing.T) {
+		producerMock := new(mocks.Producer)
+
+		interconnection := &Interconnection{UserID: userID, kafkaProducer: producerMock}
+		interconnection.sendCaseNumber(span)
+
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+}
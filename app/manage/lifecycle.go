@@ -0,0 +1,190 @@
+package manage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+// LifecycleEventVersion is the version of the schema of the lifecycle events, it changes when a field changes or
+// is removed, the new fields and types don't change it
+const LifecycleEventVersion = 1
+
+// LifecycleEventType is the type of the events of the lifecycle of the chats
+type LifecycleEventType string
+
+const (
+	ChatCreatedEvent        LifecycleEventType = "chat.created"
+	ChatContactFoundEvent   LifecycleEventType = "chat.contact_found"
+	ChatContactCreatedEvent LifecycleEventType = "chat.contact_created"
+	ChatCaseCreatedEvent    LifecycleEventType = "chat.case_created"
+	ChatQueuedEvent         LifecycleEventType = "chat.queued"
+	ChatQueueUpdatedEvent   LifecycleEventType = "chat.queue_updated"
+	ChatAcceptedEvent       LifecycleEventType = "chat.accepted"
+	ChatTransferredEvent    LifecycleEventType = "chat.transferred"
+	ChatMessageInEvent      LifecycleEventType = "chat.message_in"
+	ChatMessageOutEvent     LifecycleEventType = "chat.message_out"
+	ChatEndedEvent          LifecycleEventType = "chat.ended"
+	ChatFailedEvent         LifecycleEventType = "chat.failed"
+	ChatTimeoutEvent        LifecycleEventType = "chat.timeout"
+)
+
+// webhookEventTypes are the events sent to the webhook subscribers, the steps of the creation and the messages
+// are only published in kafka
+var webhookEventTypes = map[LifecycleEventType]bool{
+	ChatCreatedEvent:     true,
+	ChatQueuedEvent:      true,
+	ChatAcceptedEvent:    true,
+	ChatTransferredEvent: true,
+	ChatEndedEvent:       true,
+	ChatFailedEvent:      true,
+	ChatTimeoutEvent:     true,
+}
+
+// Reasons of the chats that ended
+const (
+	agentEndedReason     = "agent_ended"
+	userEndedReason      = "user_ended"
+	botEndedReason       = "bot_ended"
+	closedReason         = "closed"
+	requestFailedReason  = "request_failed"
+	sessionExpiredReason = "session_expired"
+	connectionLostReason = "connection_lost"
+)
+
+// LifecycleEvent is a change in the lifecycle of a chat, it is sent to the systems subscribed to the chats
+type LifecycleEvent struct {
+	ID                string                `json:"id"`
+	Version           int                   `json:"version"`
+	Type              LifecycleEventType    `json:"type"`
+	Client            string                `json:"client"`
+	UserID            string                `json:"userId"`
+	BotSlug           string                `json:"botSlug"`
+	Provider          Provider              `json:"provider"`
+	SessionID         string                `json:"sessionId"`
+	ContactID         string                `json:"contactId,omitempty"`
+	CaseID            string                `json:"caseId"`
+	CaseNumber        string                `json:"caseNumber"`
+	AgentID           string                `json:"agentId,omitempty"`
+	AgentName         string                `json:"agentName,omitempty"`
+	Status            InterconnectionStatus `json:"status"`
+	Reason            string                `json:"reason,omitempty"`
+	QueuePosition     int                   `json:"queuePosition,omitempty"`
+	EstimatedWaitTime int                   `json:"estimatedWaitTime,omitempty"`
+	Message           *LifecycleMessage     `json:"message,omitempty"`
+	ChatCreatedAt     time.Time             `json:"chatCreatedAt"`
+	Timestamp         time.Time             `json:"timestamp"`
+}
+
+// LifecycleMessage is the metadata of a message of the chat, the content of the messages is not published
+type LifecycleMessage struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Length int    `json:"length"`
+}
+
+// lifecyclePublisher publishes the lifecycle events of the chats
+type lifecyclePublisher interface {
+	publishLifecycleEvent(event *LifecycleEvent)
+}
+
+func newLifecycleEvent(interconnection *Interconnection, eventType LifecycleEventType, reason string) *LifecycleEvent {
+	return &LifecycleEvent{
+		ID:            helpers.RandomString(36),
+		Version:       LifecycleEventVersion,
+		Type:          eventType,
+		Client:        interconnection.Client,
+		UserID:        interconnection.UserID,
+		BotSlug:       interconnection.BotSlug,
+		Provider:      interconnection.Provider,
+		SessionID:     interconnection.SessionID,
+		CaseID:        interconnection.CaseID,
+		CaseNumber:    interconnection.CaseNumber,
+		AgentID:       interconnection.agentID,
+		AgentName:     interconnection.agentName,
+		Status:        interconnection.Status,
+		Reason:        reason,
+		ChatCreatedAt: interconnection.Timestamp,
+		Timestamp:     time.Now().UTC(),
+	}
+}
+
+// endedEventType returns the event of the end of a chat, the chats rejected by Salesforce fail and the chats
+// whose session expired time out
+func endedEventType(status InterconnectionStatus, reason string) LifecycleEventType {
+	switch {
+	case status == Failed:
+		return ChatFailedEvent
+	case reason == sessionExpiredReason:
+		return ChatTimeoutEvent
+	}
+	return ChatEndedEvent
+}
+
+// publishLifecycle publishes the event of the interconnection
+func (in *Interconnection) publishLifecycle(eventType LifecycleEventType, reason string) {
+	in.emitLifecycleEvent(newLifecycleEvent(in, eventType, reason))
+}
+
+// publishQueue publishes the position of the chat in the queue of the agents
+func (in *Interconnection) publishQueue(eventType LifecycleEventType, position, waitTime int) {
+	event := newLifecycleEvent(in, eventType, "")
+	event.QueuePosition = position
+	event.EstimatedWaitTime = waitTime
+	in.emitLifecycleEvent(event)
+}
+
+// publishMessage publishes the metadata of a message between the user and the agent
+func (in *Interconnection) publishMessage(eventType LifecycleEventType, id, messageType, text string) {
+	event := newLifecycleEvent(in, eventType, "")
+	event.Message = &LifecycleMessage{ID: id, Type: messageType, Length: len([]rune(text))}
+	in.emitLifecycleEvent(event)
+}
+
+// emitLifecycleEvent sends the event to the publisher, the interconnections without publisher don't publish
+func (in *Interconnection) emitLifecycleEvent(event *LifecycleEvent) {
+	if in.lifecycle == nil {
+		return
+	}
+	in.lifecycle.publishLifecycleEvent(event)
+}
+
+// publishLifecycleEvent sends the event to the subscribers of the lifecycle of the chats
+func (m *Manager) publishLifecycleEvent(event *LifecycleEvent) {
+	if webhookEventTypes[event.Type] {
+		m.enqueueWebhooks(event)
+	}
+	m.produceLifecycleEvent(event)
+}
+
+// produceLifecycleEvent publishes the event in the lifecycle topic, the events of a user share the key so they
+// keep their order
+func (m *Manager) produceLifecycleEvent(event *LifecycleEvent) {
+	if m.lifecycleTopic == "" || m.kafkaProducer == nil {
+		return
+	}
+
+	logFields := logrus.Fields{
+		events.UserID: event.UserID,
+		"eventId":     event.ID,
+		"eventType":   event.Type,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not encode the lifecycle event")
+		return
+	}
+
+	err = m.kafkaProducer.SendMessage(kafka.KafkaMessageParams{
+		Topic: m.lifecycleTopic,
+		Msg:   payload,
+		Key:   []byte(event.UserID),
+	})
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not publish the lifecycle event in kafka")
+	}
+}
@@ -0,0 +1,349 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/app/config/envs"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/chat"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+// lifecycleRecorder keeps the lifecycle events published by the interconnections
+type lifecycleRecorder struct {
+	events []*LifecycleEvent
+}
+
+func (r *lifecycleRecorder) publishLifecycleEvent(event *LifecycleEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestInterconnection_publishLifecycle(t *testing.T) {
+	newInterconnection := func(recorder *lifecycleRecorder) *Interconnection {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", mock.Anything).Return(nil, constants.ErrInterconnectionNotFound)
+		interconnectionCacheMock.On("FinishInterconnection", mock.Anything, mock.Anything, string(Closed), string(Failed)).
+			Return(true, nil)
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", mock.Anything).Return(true, nil)
+		return &Interconnection{
+			UserID:               userID,
+			Client:               client,
+			SessionID:            sessionID,
+			Status:               OnHold,
+			Provider:             provider,
+			BotSlug:              botSlug,
+			CaseID:               caseID,
+			CaseNumber:           caseNumber,
+			Timestamp:            time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			interconnectionCache: interconnectionCacheMock,
+			BotrunnnerClient:     botRunnerMock,
+			finishChannel:        make(chan *Interconnection, 1),
+			lifecycle:            recorder,
+		}
+	}
+	span, _ := tracer.SpanFromContext(context.Background())
+
+	t.Run("Should publish the agent that accepted the chat", func(t *testing.T) {
+		Messages = models.MessageTemplate{}
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.ChatEstablished,
+			Message: chat.Message{Name: "Agent", UserId: "agentID"},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		event := recorder.events[0]
+		assert.Equal(t, ChatAcceptedEvent, event.Type)
+		assert.Equal(t, "agentID", event.AgentID)
+		assert.Equal(t, "Agent", event.AgentName)
+		assert.Equal(t, Active, event.Status)
+		assert.Equal(t, caseID, event.CaseID)
+		assert.Equal(t, caseNumber, event.CaseNumber)
+		assert.Equal(t, sessionID, event.SessionID)
+		assert.Equal(t, interconnection.Timestamp, event.ChatCreatedAt)
+		assert.NotEmpty(t, event.ID)
+	})
+
+	t.Run("Should publish the new agent of the transferred chat", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+		interconnection.agentName, interconnection.agentID = "Agent", "agentID"
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.ChatTransferred,
+			Message: chat.Message{Name: "Other agent", UserId: "otherAgentID"},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatTransferredEvent, recorder.events[0].Type)
+		assert.Equal(t, "otherAgentID", recorder.events[0].AgentID)
+		assert.Equal(t, "Other agent", recorder.events[0].AgentName)
+	})
+
+	t.Run("Should publish the queued chat", func(t *testing.T) {
+		Messages = models.MessageTemplate{}
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.ChatRequestSuccess,
+			Message: chat.Message{QueuePosition: 3, EstimatedWaitTime: 120},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatQueuedEvent, recorder.events[0].Type)
+		assert.Equal(t, 3, recorder.events[0].QueuePosition)
+		assert.Equal(t, 120, recorder.events[0].EstimatedWaitTime)
+	})
+
+	t.Run("Should publish the updates of the queue", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.QueueUpdate,
+			Message: chat.Message{Position: 1, EstimatedWaitTime: 30},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatQueueUpdatedEvent, recorder.events[0].Type)
+		assert.Equal(t, 1, recorder.events[0].QueuePosition)
+		assert.Equal(t, 30, recorder.events[0].EstimatedWaitTime)
+	})
+
+	t.Run("Should publish the metadata of the messages of the agent", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Return(nil).Once()
+		interconnection.kafkaProducer = producerMock
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.ChatMessage,
+			Message: chat.Message{Text: "¿Cómo estás?"},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatMessageOutEvent, recorder.events[0].Type)
+		assert.NotEmpty(t, recorder.events[0].Message.ID)
+		assert.Equal(t, &LifecycleMessage{ID: recorder.events[0].Message.ID, Type: "text", Length: 12},
+			recorder.events[0].Message)
+	})
+
+	t.Run("Should publish the end of the chat by the agent", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+		interconnection.runnigLongPolling = true
+
+		interconnection.checkEvent(span, &chat.MessageObject{Type: chat.ChatEnded})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatEndedEvent, recorder.events[0].Type)
+		assert.Equal(t, agentEndedReason, recorder.events[0].Reason)
+		assert.Equal(t, Closed, recorder.events[0].Status)
+	})
+
+	t.Run("Should publish the failed chat when there are no agents", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+		interconnection.runnigLongPolling = true
+
+		interconnection.checkEvent(span, &chat.MessageObject{
+			Type:    chat.ChatRequestFail,
+			Message: chat.Message{Reason: noAgentsReason},
+		})
+
+		assert.Len(t, recorder.events, 1)
+		assert.Equal(t, ChatFailedEvent, recorder.events[0].Type)
+		assert.Equal(t, "no_agents", recorder.events[0].Reason)
+		assert.Equal(t, Failed, recorder.events[0].Status)
+	})
+
+	t.Run("Should not publish the end of a chat that was already closed", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+
+		interconnection.checkEvent(span, &chat.MessageObject{Type: chat.ChatEnded})
+
+		assert.Empty(t, recorder.events)
+	})
+
+	t.Run("Should not publish the end of a chat finished by another pod", func(t *testing.T) {
+		recorder := &lifecycleRecorder{}
+		interconnection := newInterconnection(recorder)
+		interconnection.runnigLongPolling = true
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(false, nil).Once()
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		interconnection.interconnectionCache = interconnectionCacheMock
+		interconnection.BotrunnnerClient = botRunnerMock
+
+		interconnection.checkEvent(span, &chat.MessageObject{Type: chat.ChatEnded})
+
+		assert.Empty(t, recorder.events)
+		assert.Equal(t, Closed, interconnection.Status)
+		interconnectionCacheMock.AssertExpectations(t)
+		botRunnerMock.AssertNotCalled(t, "SendTo", mock.Anything)
+	})
+
+	t.Run("Should not publish without publisher", func(t *testing.T) {
+		interconnection := newInterconnection(nil)
+		interconnection.lifecycle = nil
+
+		interconnection.publishLifecycle(ChatQueuedEvent, "")
+	})
+}
+
+func TestManager_publishLifecycleEvent(t *testing.T) {
+	t.Run("Should enqueue the end of the chat finished by the bot", func(t *testing.T) {
+		interconnectionMap := cache.New()
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", mock.Anything).Return(nil, constants.ErrInterconnectionNotFound)
+		interconnectionCacheMock.On("FinishInterconnection", mock.Anything, string(Closed), string(Closed), string(Failed)).
+			Return(true, nil).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("EndChat", affinityToken, sessionKey).Return(nil).Once()
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", mock.Anything).Return(true, nil)
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("EnqueueWebhook", mock.MatchedBy(func(delivery cache.WebhookDelivery) bool {
+			var event LifecycleEvent
+			if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+				return false
+			}
+			return delivery.Subscriber == "crm" && delivery.EventType == string(ChatEndedEvent) &&
+				event.Reason == botEndedReason && event.Status == Closed && event.UserID == userID
+		})).Return(nil).Once()
+
+		interconnection := &Interconnection{
+			Client:               client,
+			UserID:               userID,
+			BotSlug:              botSlug,
+			Status:               Active,
+			Provider:             provider,
+			AffinityToken:        affinityToken,
+			SessionKey:           sessionKey,
+			interconnectionCache: interconnectionCacheMock,
+		}
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, 0)
+		interconnectionMap.Wait()
+
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    interconnectionMap,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionCacheMock,
+			BotrunnnerClient:      botRunnerMock,
+			webhookCache:          webhookCacheMock,
+			webhookSubscribers: newWebhookSubscribers(envs.WebhookSubscribers{
+				"crm": {URL: "https://crm.yalochat.com/webhooks", Events: []string{string(ChatEndedEvent)}},
+			}, "secret", time.Second),
+		}
+
+		err := manager.FinishChat(userID)
+
+		assert.NoError(t, err)
+		webhookCacheMock.AssertExpectations(t)
+	})
+}
+
+func TestEndedEventType(t *testing.T) {
+	t.Run("Should fail the chats rejected by Salesforce", func(t *testing.T) {
+		assert.Equal(t, ChatFailedEvent, endedEventType(Failed, requestFailedReason))
+	})
+
+	t.Run("Should time out the chats whose session expired", func(t *testing.T) {
+		assert.Equal(t, ChatTimeoutEvent, endedEventType(Closed, sessionExpiredReason))
+	})
+
+	t.Run("Should end the other chats", func(t *testing.T) {
+		assert.Equal(t, ChatEndedEvent, endedEventType(Closed, agentEndedReason))
+	})
+}
+
+func TestManager_produceLifecycleEvent(t *testing.T) {
+	event := &LifecycleEvent{
+		ID:        "eventID",
+		Version:   LifecycleEventVersion,
+		Type:      ChatMessageInEvent,
+		Client:    client,
+		UserID:    userID,
+		Message:   &LifecycleMessage{ID: "messageID", Type: "text", Length: 5},
+		Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Should publish the event in the lifecycle topic with the user as key", func(t *testing.T) {
+		payload, _ := json.Marshal(event)
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", kafka.KafkaMessageParams{
+			Topic: "lifecycle-topic",
+			Msg:   payload,
+			Key:   []byte(userID),
+		}).Return(nil).Once()
+		manager := &Manager{client: client, kafkaProducer: producerMock, lifecycleTopic: "lifecycle-topic"}
+
+		manager.produceLifecycleEvent(event)
+
+		producerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should not publish without lifecycle topic", func(t *testing.T) {
+		producerMock := new(mocks.Producer)
+		manager := &Manager{client: client, kafkaProducer: producerMock}
+
+		manager.produceLifecycleEvent(event)
+
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+
+	t.Run("Should publish the messages only in kafka", func(t *testing.T) {
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Return(nil).Once()
+		webhookCacheMock := new(mocks.IWebhookCache)
+		manager := &Manager{
+			client:             client,
+			kafkaProducer:      producerMock,
+			lifecycleTopic:     "lifecycle-topic",
+			webhookCache:       webhookCacheMock,
+			webhookSubscribers: map[string]webhookSubscriber{"crm": {url: "https://crm.yalochat.com/webhooks"}},
+		}
+
+		manager.publishLifecycleEvent(event)
+
+		producerMock.AssertExpectations(t)
+		webhookCacheMock.AssertNotCalled(t, "EnqueueWebhook", mock.Anything)
+	})
+}
+
+func TestManager_failChat(t *testing.T) {
+	t.Run("Should publish the chat that could not be created", func(t *testing.T) {
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.MatchedBy(func(params kafka.KafkaMessageParams) bool {
+			var event LifecycleEvent
+			if err := json.Unmarshal(params.Msg, &event); err != nil {
+				return false
+			}
+			return event.Type == ChatFailedEvent && event.Reason == caseReason && event.Status == Failed &&
+				event.Version == LifecycleEventVersion && event.UserID == userID
+		})).Return(nil).Once()
+		manager := &Manager{client: client, kafkaProducer: producerMock, lifecycleTopic: "lifecycle-topic"}
+
+		manager.failChat(&Interconnection{Client: client, UserID: userID, Provider: provider, BotSlug: botSlug}, caseReason)
+
+		producerMock.AssertExpectations(t)
+	})
+}
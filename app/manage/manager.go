@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"reflect"
 	"regexp"
 	"sort"
@@ -17,6 +18,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/health"
 	"yalochat.com/salesforce-integration/base/subscribers"
 	"yalochat.com/salesforce-integration/base/subscribers/kafka"
 
@@ -25,6 +27,7 @@ import (
 	"yalochat.com/salesforce-integration/app/services"
 	"yalochat.com/salesforce-integration/base/cache"
 	"yalochat.com/salesforce-integration/base/clients/botrunner"
+	"yalochat.com/salesforce-integration/base/clients/callback"
 	"yalochat.com/salesforce-integration/base/clients/chat"
 	"yalochat.com/salesforce-integration/base/clients/integrations"
 	"yalochat.com/salesforce-integration/base/clients/login"
@@ -33,6 +36,7 @@ import (
 	"yalochat.com/salesforce-integration/base/clients/studiong"
 	"yalochat.com/salesforce-integration/base/constants"
 	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/metrics"
 	"yalochat.com/salesforce-integration/base/models"
 )
 
@@ -43,28 +47,53 @@ var (
 	BlockedUserState    map[string]string
 	TimeoutState        map[string]string
 	SuccessState        map[string]string
+	ProactiveChatState  map[string]string
 	SfcCustomFieldsCase map[string]string
 	BotrunnerTimeout    int
 	//TODO: move a integration clients constructor
-	WAPhone                string
-	FBPhone                string
-	WebhookBaseUrl         string
-	WebhookWhatsapp        string
-	WebhookFacebook        string
-	StudioNGTimeout        int
-	CodePhoneRemove        []string
-	Messages               models.MessageTemplate
-	Timezone               string
-	SendImageNameInMessage bool
-	waitCheckEvent         time.Duration
+	WAPhone                 string
+	FBPhone                 string
+	WebhookBaseUrl          string
+	WebhookWhatsapp         string
+	WebhookFacebook         string
+	StudioNGTimeout         int
+	CodePhoneRemove         []string
+	Messages                models.MessageTemplate
+	MessagesByBot           models.MessageTemplates
+	LanguageField           string
+	Timezone                string
+	SendImageNameInMessage  bool
+	CaseNumberEvent         string
+	MessageLimits           map[string]int
+	SplitMessageMarkers     bool
+	ContextMediaAttachments int
+	TranscriptFormat        string
+	waitCheckEvent          time.Duration
 )
 
 const (
-	fromUser           = "user"
-	fromBot            = "bot"
-	defaultFieldCustom = "default"
+	fromUser              = "user"
+	fromBot               = "bot"
+	defaultFieldCustom    = "default"
+	salesforceDestination = "salesforce"
 )
 
+// ContextFilter filters the context of a user, the zero values don't filter
+type ContextFilter struct {
+	// From and To are timestamps in milliseconds
+	From   int64
+	To     int64
+	Sender string
+	Limit  int
+	Offset int
+}
+
+func (f ContextFilter) match(ctx cache.Context) bool {
+	return (f.From == 0 || ctx.Timestamp >= f.From) &&
+		(f.To == 0 || ctx.Timestamp <= f.To) &&
+		(f.Sender == "" || ctx.From == f.Sender)
+}
+
 // Manager controls the process of the app
 type Manager struct {
 	clientName                   string
@@ -76,8 +105,9 @@ type Manager struct {
 	finishInterconnection        chan *Interconnection
 	contextcache                 cache.IContextCache
 	interconnectionsCache        cache.IInterconnectionCache
+	chatRequestCache             cache.IChatRequestCache
 	environment                  string
-	keywordsRestart              []string
+	endUserCommands              envs.EndUserCommands
 	cacheMessage                 cache.IMessageCache
 	SfcSourceFlowBot             envs.SfcSourceFlowBot
 	SfcSourceFlowField           string
@@ -91,6 +121,31 @@ type Manager struct {
 	kafkaProducer                subscribers.Producer
 	KafkaTopic                   string
 	SleepLongPollling            time.Duration
+	idempotencyTTL               time.Duration
+	idempotencyLockTTL           time.Duration
+	readiness                    *health.Checker
+	callbackClient               callback.CallbackInterface
+	// chatRequestTimeout is the time that an asynchronous chat request waits for Salesforce to accept the chat
+	chatRequestTimeout time.Duration
+	webhookCache       cache.IWebhookCache
+	webhookSubscribers map[string]webhookSubscriber
+	webhookMaxAttempts int
+	webhookRetryDelay  time.Duration
+	webhookLogSize     int64
+	// webhookMaxRetryDelay caps the exponential delay of the retries of the webhooks
+	webhookMaxRetryDelay time.Duration
+	// webhookClaimTimeout is the time that a claimed webhook is hidden from the other instances
+	webhookClaimTimeout time.Duration
+	// webhookWorkers bounds the webhooks that are delivered at the same time
+	webhookWorkers chan struct{}
+	// lifecycleTopic is the kafka topic of the lifecycle events, they are not published when it is empty
+	lifecycleTopic       string
+	messageDeliveryCache cache.IMessageDeliveryCache
+	// messageDeliveryTTL is the time that the messages sent to the users are kept to match their status updates
+	messageDeliveryTTL time.Duration
+	// windowTemplate is sent to the whatsapp users whose 24-hour window is closed, the messages are sent as they are
+	// when it does not have name
+	windowTemplate models.WindowTemplate
 }
 
 // ManagerOptions holds configurations for the interactions manager
@@ -100,6 +155,7 @@ type ManagerOptions struct {
 	BlockedUserState               map[string]string
 	TimeoutState                   map[string]string
 	SuccessState                   map[string]string
+	ProactiveChatState             map[string]string
 	RedisOptions                   cache.RedisOptions
 	BotrunnerUrl                   string
 	BotrunnerToken                 string
@@ -122,6 +178,9 @@ type ManagerOptions struct {
 	SfcCustomFieldsContact         map[string]string
 	SfcCustomFieldsAccount         map[string]string
 	SfcCodePhoneRemove             []string
+	SfcMaxFileSize                 int64
+	AllowedFileTypes               []string
+	DeniedFileTypes                []string
 	IntegrationsUrl                string
 	IntegrationsWAChannel          string
 	IntegrationsFBChannel          string
@@ -137,6 +196,7 @@ type ManagerOptions struct {
 	WebhookFacebook                string
 	Environment                    string
 	KeywordsRestart                []string
+	EndUserCommands                envs.EndUserCommands
 	SfcSourceFlowBot               envs.SfcSourceFlowBot
 	SfcSourceFlowField             string
 	SfcBlockedChatField            bool
@@ -149,8 +209,15 @@ type ManagerOptions struct {
 	IntegrationsRateLimit          float64
 	SalesforceRateLimit            float64
 	Messages                       models.MessageTemplate
+	MessagesByBot                  models.MessageTemplates
+	LanguageField                  string
 	Timezone                       string
 	SendImageNameInMessage         bool
+	CaseNumberEvent                string
+	MessageLimits                  map[string]int
+	SplitMessageMarkers            bool
+	ContextMediaAttachments        int
+	TranscriptFormat               string
 	KafkaHost                      string
 	KafkaPort                      string
 	KafkaUser                      string
@@ -158,14 +225,47 @@ type ManagerOptions struct {
 	KafkaTopic                     string
 	SleepLongPollling              time.Duration
 	SfcCustomFieldsToSearchContact map[string]string
+	IdempotencyTTL                 time.Duration
+	IdempotencyLockTTL             time.Duration
+	ReadinessTimeout               time.Duration
+	ReadinessCacheTTL              time.Duration
+	ReadinessNonCritical           []string
+	CallbackSecret                 string
+	CallbackTimeout                time.Duration
+	CallbackMaxRetries             int
+	AsyncChatTimeout               time.Duration
+	LifecycleWebhooks              envs.WebhookSubscribers
+	LifecycleWebhookSecret         string
+	LifecycleWebhookTimeout        time.Duration
+	LifecycleWebhookMaxAttempts    int
+	LifecycleWebhookRetryDelay     time.Duration
+	LifecycleWebhookMaxDelay       time.Duration
+	LifecycleWebhookLogSize        int64
+	LifecycleWebhookConcurrency    int
+	LifecycleTopic                 string
+	MessageDeliveryTTL             time.Duration
+	WindowTemplate                 models.WindowTemplate
 }
 
 type ManagerI interface {
 	SaveContext(ctx context.Context, integration *models.IntegrationsRequest) error
+	UpdateMessageStatus(ctx context.Context, integration *models.IntegrationsRequest) error
 	CreateChat(ctx context.Context, interconnection *Interconnection) error
+	CreateChatIdempotent(ctx context.Context, idempotencyKey string, interconnection *Interconnection) error
+	CreateChatAsync(ctx context.Context, requestID, callbackURL string, interconnection *Interconnection) (*cache.ChatRequest, error)
+	GetChatRequest(requestID string) (*cache.ChatRequest, error)
+	CheckReadiness(ctx context.Context) health.Report
+	ListWebhookDeliveries(filter WebhookDeliveryFilter) (*WebhookDeliveryList, error)
+	CreateProactiveChat(ctx context.Context, interconnection *Interconnection, contactID, buttonID, text string) error
 	GetContextByUserID(userID string) []cache.Context
+	FilterContextByUserID(userID string, filter ContextFilter) ([]cache.Context, int)
+	RenderContextByUserID(userID string, allContext []cache.Context) string
 	SaveContextFB(ctx context.Context, integration *models.IntegrationsFacebook) error
 	FinishChat(userID string) error
+	GetChatStatus(userID string) (*ChatStatus, error)
+	ListChats(filter ChatFilter) (*ChatList, error)
+	CloseChats(filter CloseChatsFilter) (*CloseChatsReport, error)
+	SendMessageToUser(ctx context.Context, message OutboundMessage) (*OutboundMessageResult, error)
 	RegisterWebhookInIntegrations(provider string) error
 	RemoveWebhookInIntegrations(provider string) error
 }
@@ -178,6 +278,7 @@ func CreateManager(config *ManagerOptions) *Manager {
 	BlockedUserState = config.BlockedUserState
 	TimeoutState = config.TimeoutState
 	SuccessState = config.SuccessState
+	ProactiveChatState = config.ProactiveChatState
 	SfcCustomFieldsCase = config.SfcCustomFieldsCase
 	BotrunnerTimeout = config.BotrunnerTimeout
 	WAPhone = config.IntegrationsWABotPhone
@@ -189,8 +290,15 @@ func CreateManager(config *ManagerOptions) *Manager {
 	CodePhoneRemove = config.SfcCodePhoneRemove
 	isStudioNG := false
 	Messages = config.Messages
+	MessagesByBot = config.MessagesByBot
+	LanguageField = config.LanguageField
 	Timezone = config.Timezone
 	SendImageNameInMessage = config.SendImageNameInMessage
+	CaseNumberEvent = config.CaseNumberEvent
+	MessageLimits = config.MessageLimits
+	SplitMessageMarkers = config.SplitMessageMarkers
+	ContextMediaAttachments = config.ContextMediaAttachments
+	TranscriptFormat = config.TranscriptFormat
 
 	salesforceRateLimit := rate.Limit(config.SalesforceRateLimit)
 	salesforceRateLimiter := rate.NewLimiter(salesforceRateLimit, int(salesforceRateLimit)+1)
@@ -205,10 +313,16 @@ func CreateManager(config *ManagerOptions) *Manager {
 
 	var contextCache *cache.ContextCache
 	var interconnectionsCache *cache.InterconnectionCache
+	var chatRequestCache cache.IChatRequestCache
+	var webhookCache cache.IWebhookCache
+	var messageDeliveryCache cache.IMessageDeliveryCache
 
 	if redisCache != nil {
 		contextCache = cache.NewContextCache(redisCache)
 		interconnectionsCache = cache.NewInterconnectionCache(redisCache)
+		chatRequestCache = cache.NewChatRequestCache(redisCache)
+		webhookCache = cache.NewWebhookCache(redisCache)
+		messageDeliveryCache = cache.NewMessageDeliveryCache(redisCache)
 	}
 
 	sfcLoginClient := &login.SfcLoginClient{
@@ -259,7 +373,6 @@ func CreateManager(config *ManagerOptions) *Manager {
 		tokenPayload,
 		config.SfcCustomFieldsCase,
 		SfcRecordTypeID,
-		Messages.FirstNameContact,
 		config.SfcCustomFieldsContact,
 		config.SfcCustomFieldsToSearchContact,
 		config.SfcCustomFieldsAccount,
@@ -277,6 +390,11 @@ func CreateManager(config *ManagerOptions) *Manager {
 	}
 
 	salesforceService.AccountRecordTypeId = config.SfcAccountRecordTypeID
+	salesforceService.MaxFileSize = config.SfcMaxFileSize
+	salesforceService.FileTypePolicy = helpers.FileTypePolicy{
+		Allowed: config.AllowedFileTypes,
+		Denied:  config.DeniedFileTypes,
+	}
 	if config.SfcDefaultBirthDateAccount != "" {
 		salesforceService.DefaultBirthDateAccount = config.SfcDefaultBirthDateAccount
 	}
@@ -296,9 +414,10 @@ func CreateManager(config *ManagerOptions) *Manager {
 		finishInterconnection:        make(chan *Interconnection),
 		contextcache:                 contextCache,
 		interconnectionsCache:        interconnectionsCache,
+		chatRequestCache:             chatRequestCache,
 		BotrunnnerClient:             botRunnerClient,
 		environment:                  config.Environment,
-		keywordsRestart:              config.KeywordsRestart,
+		endUserCommands:              newEndUserCommands(config.EndUserCommands, config.KeywordsRestart, config.Environment),
 		SfcSourceFlowBot:             config.SfcSourceFlowBot,
 		SfcSourceFlowField:           config.SfcSourceFlowField,
 		cacheMessage:                 cache.NewMessageCache(cacheLocal),
@@ -309,6 +428,23 @@ func CreateManager(config *ManagerOptions) *Manager {
 		SalesforceChanRequestLimiter: salesforceRateLimiter,
 		KafkaTopic:                   config.KafkaTopic,
 		SleepLongPollling:            config.SleepLongPollling,
+		idempotencyTTL:               config.IdempotencyTTL,
+		idempotencyLockTTL:           config.IdempotencyLockTTL,
+		callbackClient:               callback.NewCallbackClient(config.CallbackSecret, config.CallbackTimeout, config.CallbackMaxRetries),
+		chatRequestTimeout:           config.AsyncChatTimeout,
+		webhookCache:                 webhookCache,
+		webhookSubscribers: newWebhookSubscribers(config.LifecycleWebhooks, config.LifecycleWebhookSecret,
+			config.LifecycleWebhookTimeout),
+		webhookMaxAttempts:   config.LifecycleWebhookMaxAttempts,
+		webhookRetryDelay:    config.LifecycleWebhookRetryDelay,
+		webhookMaxRetryDelay: config.LifecycleWebhookMaxDelay,
+		webhookClaimTimeout:  config.LifecycleWebhookTimeout + webhookClaimMargin,
+		webhookWorkers:       make(chan struct{}, webhookConcurrency(config.LifecycleWebhookConcurrency)),
+		webhookLogSize:       config.LifecycleWebhookLogSize,
+		lifecycleTopic:       config.LifecycleTopic,
+		messageDeliveryCache: messageDeliveryCache,
+		messageDeliveryTTL:   config.MessageDeliveryTTL,
+		windowTemplate:       config.WindowTemplate,
 	}
 
 	if config.KafkaUser != "" {
@@ -330,6 +466,9 @@ func CreateManager(config *ManagerOptions) *Manager {
 		go consumer.Start()
 	}
 
+	m.readiness = health.NewChecker(config.ReadinessTimeout, config.ReadinessCacheTTL,
+		m.readinessComponents(redisCache, config.ReadinessTimeout, config.ReadinessNonCritical)...)
+
 	// TODO: Add function restore interconnections
 	if !reflect.ValueOf(m.interconnectionsCache).IsNil() {
 		interconnections := interconnectionsCache.RetrieveAllInterconnections(config.Client)
@@ -343,6 +482,9 @@ func CreateManager(config *ManagerOptions) *Manager {
 	}
 
 	go m.handleInterconnection()
+	if webhookCache != nil && len(m.webhookSubscribers) > 0 {
+		go m.runWebhookOutbox()
+	}
 	return m
 }
 
@@ -355,7 +497,53 @@ func (m *Manager) handleInterconnection() {
 
 }
 
+// sendMessageToUser sends the message to the user, the long messages are sent in parts in order.
+// The media is sent first, in whatsapp a text that fits in one part is sent as its caption. The messages are not sent
+// when the whatsapp window of the user is closed
 func (m *Manager) sendMessageToUser(message *Message) {
+	if m.windowClosed(message) {
+		return
+	}
+
+	var texts []string
+	if message.Text != "" || message.MediaURL == "" {
+		texts = splitMessage(message.Text, string(message.Provider))
+	}
+
+	index := 0
+	if message.MediaURL != "" {
+		media := *message
+		media.Text = ""
+		if message.Provider == WhatsappProvider && len(texts) == 1 {
+			media.Text = texts[0]
+			texts = nil
+		}
+		if !m.sendMessagePartToUser(&media) {
+			return
+		}
+		index++
+	}
+
+	for _, text := range texts {
+		part := *message
+		part.Text = text
+		part.MediaURL = ""
+		if index > 0 {
+			part.ID = fmt.Sprintf("%s-%d", message.ID, index)
+		}
+		index++
+		if !m.sendMessagePartToUser(&part) {
+			return
+		}
+	}
+}
+
+func (m *Manager) sendMessagePartToUser(message *Message) bool {
+	return m.sendPayloadToUser(message, userMessagePayload(message))
+}
+
+// sendPayloadToUser sends the payload of the message to the user with retries, a nil payload is not sent
+func (m *Manager) sendPayloadToUser(message *Message, payload interface{}) bool {
 	// datadog tracing
 	spanContext := events.GetSpanContextFromSpan(message.MainSpan)
 	span := tracer.StartSpan("sendMessageToUser", tracer.ChildOf(spanContext))
@@ -367,60 +555,103 @@ func (m *Manager) sendMessageToUser(message *Message) {
 	span.SetTag(events.RetryMessage, false)
 	defer span.Finish()
 
+	labels := m.messageLabels(metrics.ToUser, message)
+	if payload == nil {
+		err := fmt.Errorf("could not send the message, provider %s is not supported", message.Provider)
+		span.SetTag(ext.Error, err)
+		logrus.WithField(events.UserID, message.UserID).Error(err.Error())
+		metrics.MessagesDropped.WithLabelValues(labels...).Inc()
+		return false
+	}
+
 	retries := 0
 	for {
-		switch message.Provider {
-		case WhatsappProvider:
-			_, err := m.IntegrationsClient.SendMessage(integrations.SendTextPayload{
+		response, err := m.IntegrationsClient.SendMessage(payload, string(message.Provider))
+		if err != nil {
+			span.SetTag(ext.Error, err)
+			logrus.WithField(events.UserID, message.UserID).Error(helpers.ErrorMessage("Error sendMessage to user", err))
+			if retries == m.maxRetries {
+				logrus.WithField(events.UserID, message.UserID).Error("Error sendMessage to user, max retries")
+				metrics.MessagesDropped.WithLabelValues(labels...).Inc()
+				return false
+			}
+			retries++
+			span.SetTag(events.RetryMessage, true)
+			metrics.MessageRetries.WithLabelValues(labels...).Inc()
+			continue
+		}
+		logrus.Infof("Send message to UserID : %s", message.UserID)
+		span.SetTag(events.SendMessage, true)
+		metrics.MessagesForwarded.WithLabelValues(labels...).Inc()
+		m.storeMessageDeliveries(message, response)
+		return true
+	}
+}
+
+// userMessagePayload returns the payload of integrations for the message, it's nil for an unknown provider
+func userMessagePayload(message *Message) interface{} {
+	switch message.Provider {
+	case WhatsappProvider:
+		if message.MediaURL == "" {
+			return integrations.SendTextPayload{
 				Id:     message.ID,
 				Type:   "text",
 				UserID: message.UserID,
 				Text:   integrations.TextMessage{Body: message.Text},
-			}, string(message.Provider))
-			if err != nil {
-				span.SetTag(ext.Error, err)
-				logrus.WithField(events.UserID, message.UserID).Error(helpers.ErrorMessage("Error sendMessage to user", err))
-				if retries == m.maxRetries {
-					logrus.WithField(events.UserID, message.UserID).Error("Error sendMessage to user, max retries")
-					return
-				}
-				retries++
-				span.SetTag(events.RetryMessage, true)
-				continue
 			}
-			logrus.Infof("Send message to UserID : %s", message.UserID)
-			span.SetTag(events.SendMessage, true)
-			return
-		case FacebookProvider:
-			_, err := m.IntegrationsClient.SendMessage(integrations.SendTextPayloadFB{
-				MessagingType: "RESPONSE",
-				Recipient: integrations.Recipient{
-					ID: message.UserID,
-				},
-				Message: integrations.Message{
-					Text: message.Text,
+		}
+
+		media := integrations.Media{Url: message.MediaURL, Caption: message.Text}
+		switch message.MediaType {
+		case ImageMedia:
+			return integrations.SendImagePayload{ID: message.ID, Type: ImageMedia, UserID: message.UserID, Image: media}
+		case VideoMedia:
+			return integrations.SendVideoPayload{Id: message.ID, Type: VideoMedia, UserID: message.UserID, Video: media}
+		case AudioMedia:
+			return integrations.SendAudioPayload{Id: message.ID, Type: AudioMedia, UserID: message.UserID, Audio: media}
+		default:
+			return integrations.SendDocumentPayload{Id: message.ID, Type: DocumentMedia, UserID: message.UserID, Document: media}
+		}
+	case FacebookProvider:
+		payload := integrations.SendTextPayloadFB{
+			MessagingType: "RESPONSE",
+			Recipient: integrations.Recipient{
+				ID: message.UserID,
+			},
+			Message: integrations.Message{
+				Text: message.Text,
+			},
+			Metadata: "YALOSOURCE:FIREHOSE",
+		}
+		if message.MediaURL != "" {
+			attachmentType := message.MediaType
+			if attachmentType != ImageMedia && attachmentType != VideoMedia && attachmentType != AudioMedia {
+				attachmentType = "file"
+			}
+			payload.Message = integrations.Message{
+				Attachment: &integrations.Attachment{
+					Type:    attachmentType,
+					Payload: integrations.AttachmentPayload{Url: message.MediaURL},
 				},
-				Metadata: "YALOSOURCE:FIREHOSE",
-			}, string(message.Provider))
-			if err != nil {
-				span.SetTag(ext.Error, err)
-				logrus.WithField(events.UserID, message.UserID).Error(helpers.ErrorMessage("Error sendMessage to user", err))
-				if retries == m.maxRetries {
-					logrus.WithField(events.UserID, message.UserID).Error("Error sendMessage to user, max retries")
-					return
-				}
-				retries++
-				span.SetTag(events.RetryMessage, true)
-				continue
 			}
-			logrus.Infof("Send message to UserID : %s", message.UserID)
-			span.SetTag(events.SendMessage, true)
-			return
 		}
+		return payload
 	}
+	return nil
 }
 
+// sendMessageToSalesforce sends the message to the agent, the long messages are sent in parts in order
 func (m *Manager) sendMessageToSalesforce(message *Message) {
+	for _, text := range splitMessage(message.Text, salesforceDestination) {
+		part := *message
+		part.Text = text
+		if !m.sendMessagePartToSalesforce(&part) {
+			return
+		}
+	}
+}
+
+func (m *Manager) sendMessagePartToSalesforce(message *Message) bool {
 	// datadog tracing
 	spanContext := events.GetSpanContextFromSpan(message.MainSpan)
 	span := tracer.StartSpan("sendMessageToSalesforce", tracer.ChildOf(spanContext))
@@ -430,6 +661,7 @@ func (m *Manager) sendMessageToSalesforce(message *Message) {
 	span.SetTag(events.SendMessage, false)
 	span.SetTag(events.RetryMessage, false)
 	defer span.Finish()
+	labels := m.messageLabels(metrics.ToSalesforce, message)
 	retries := 0
 	for {
 		_, err := m.SalesforceService.SendMessage(span, message.AffinityToken, message.SessionKey, chat.MessagePayload{Text: message.Text})
@@ -438,15 +670,18 @@ func (m *Manager) sendMessageToSalesforce(message *Message) {
 			logrus.WithField("userID", message.UserID).Error(helpers.ErrorMessage("Error sendMessage to salesforce", err))
 			if retries == m.maxRetries {
 				logrus.WithField("userID", message.UserID).Error("Error sendMessage to salesforce, max retries")
-				return
+				metrics.MessagesDropped.WithLabelValues(labels...).Inc()
+				return false
 			}
 			span.SetTag(events.RetryMessage, true)
+			metrics.MessageRetries.WithLabelValues(labels...).Inc()
 			retries++
 			continue
 		}
 		logrus.Infof("Send message to agent from salesforce : %s", message.UserID)
 		span.SetTag(events.SendMessage, true)
-		return
+		metrics.MessagesForwarded.WithLabelValues(labels...).Inc()
+		return true
 	}
 }
 
@@ -477,6 +712,7 @@ func (m *Manager) CreateChat(ctx context.Context, interconnection *Interconnecti
 		logrus.WithFields(logFields).WithError(err).Error("error ValidateUserID")
 		span.SetTag(ext.Error, err)
 		span.SetTag(ext.ErrorDetails, helpers.ErrorMessage("error ValidateUserID", err))
+		m.failChat(interconnection, sessionExistsReason)
 		return errors.New(helpers.ErrorMessage(titleMessage, err))
 	}
 
@@ -486,35 +722,56 @@ func (m *Manager) CreateChat(ctx context.Context, interconnection *Interconnecti
 
 	// We get the contact if it exists by your email or phone.
 	logrus.WithFields(logFields).Info("GetOrCreateContact")
-	contact, err := m.SalesforceService.GetOrCreateContact(ctx, interconnection.Name, interconnection.Email, interconnection.PhoneNumber, interconnection.ExtraData)
+	contact, err := m.SalesforceService.GetOrCreateContact(ctx, interconnection.messages().FirstNameContact, interconnection.Name, interconnection.Email, interconnection.PhoneNumber, interconnection.ExtraData)
 	if err != nil {
 		logrus.WithFields(logFields).WithError(err).Error("error GetOrCreateContact")
 		span.SetTag(ext.Error, err)
+		m.failChat(interconnection, contactReason)
 		go ChangeToState(interconnection.UserID, interconnection.BotSlug, TimeoutState[string(interconnection.Provider)], m.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, m.StudioNG, m.isStudioNGFlow)
 		return errors.New(helpers.ErrorMessage(titleMessage, err))
 	}
 
 	span.SetTag("Contact", contact)
 	logFields["contactId"] = contact.ID
+	contactEvent := newLifecycleEvent(interconnection, ChatContactFoundEvent, "")
+	if contact.Created {
+		contactEvent.Type = ChatContactCreatedEvent
+	}
+	contactEvent.ContactID = contact.ID
+	m.publishLifecycleEvent(contactEvent)
 	if contact.Blocked {
 		logrus.WithFields(logFields).Info("User Blocked")
 		span.SetTag(events.UserBlocked, true)
+		m.failChat(interconnection, blockedReason)
 		go ChangeToState(interconnection.UserID, interconnection.BotSlug, BlockedUserState[string(interconnection.Provider)], m.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, m.StudioNG, m.isStudioNGFlow)
-		return fmt.Errorf("%s: %s", "could not create chat in salesforce", "this contact is blocked")
+		return fmt.Errorf("%s: %w", "could not create chat in salesforce", constants.ErrContactBlocked)
 	}
 	buttonID, ownerID, subject := m.changeButtonIDAndOwnerID(interconnection.Provider, interconnection.ExtraData)
 
 	logrus.WithFields(logFields).Info("CreateCase")
-	caseId, err := m.SalesforceService.CreatCase(ctx, contact.ID, Messages.DescriptionCase, subject, string(interconnection.Provider), ownerID,
+	description := interconnection.renderMessage(span, interconnection.messages().DescriptionCase, interconnection.templateVariables())
+	caseId, err := m.SalesforceService.CreatCase(ctx, contact.ID, description, subject, string(interconnection.Provider), ownerID,
 		interconnection.ExtraData)
 	if err != nil {
 		span.SetTag(ext.Error, err)
 		logrus.WithFields(logFields).WithError(err).Error("error CreatCase")
+		m.failChat(interconnection, caseReason)
 		go ChangeToState(interconnection.UserID, interconnection.BotSlug, TimeoutState[string(interconnection.Provider)], m.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, m.StudioNG, m.isStudioNGFlow)
 		return errors.New(helpers.ErrorMessage(titleMessage, err))
 	}
 	interconnection.CaseID = caseId
 	logFields["caseId"] = caseId
+
+	// the chat can continue without the case number, it is only informative for the user
+	caseNumber, err := m.SalesforceService.GetCaseNumber(ctx, caseId)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("error GetCaseNumber")
+	}
+	interconnection.CaseNumber = caseNumber
+	logFields["caseNumber"] = caseNumber
+	caseEvent := newLifecycleEvent(interconnection, ChatCaseCreatedEvent, "")
+	caseEvent.ContactID = contact.ID
+	m.publishLifecycleEvent(caseEvent)
 	span.SetTag(events.Interconnection, fmt.Sprintf("%#v", interconnection))
 
 	//Creating chat in Salesforce
@@ -523,6 +780,7 @@ func (m *Manager) CreateChat(ctx context.Context, interconnection *Interconnecti
 	if err != nil {
 		logrus.WithFields(logFields).WithError(err).Error("error CreatChat")
 		span.SetTag(ext.Error, err)
+		m.failChat(interconnection, chatReason)
 		go ChangeToState(interconnection.UserID, interconnection.BotSlug, TimeoutState[string(interconnection.Provider)], m.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, m.StudioNG, m.isStudioNGFlow)
 		return errors.New(helpers.ErrorMessage(titleMessage, err))
 	}
@@ -535,14 +793,110 @@ func (m *Manager) CreateChat(ctx context.Context, interconnection *Interconnecti
 	interconnection.SessionKey = session.Key
 	interconnection.Status = OnHold
 	interconnection.Timestamp = time.Now()
+	interconnection.LastUserMessageAt = interconnection.Timestamp.UTC()
 
 	//Add interconection to Redis and interconnectionMap
 	logrus.WithFields(logFields).Info("AddInterconnection")
 	span.SetTag(events.Interconnection, fmt.Sprintf("%#v", interconnection))
 	m.AddInterconnection(ctx, interconnection)
+	metrics.ChatsCreated.WithLabelValues(string(interconnection.Provider), interconnection.BotSlug).Inc()
+	m.publishLifecycleEvent(newLifecycleEvent(interconnection, ChatCreatedEvent, ""))
+	return nil
+}
+
+// CreateProactiveChat starts a chat requested by an agent for an existing case and contact, the chat is routed to
+// the button of the agent. The bot is paused and the user is notified with the text or the proactiveChatTemplate,
+// in whatsapp the window template is sent instead when the user has not written in the last 24 hours
+func (m *Manager) CreateProactiveChat(ctx context.Context, interconnection *Interconnection, contactID, buttonID, text string) error {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "manager.CreateProactiveChat")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, interconnection.UserID)
+	span.SetTag(events.Provider, interconnection.Provider)
+	span.SetTag("caseId", interconnection.CaseID)
+	span.SetTag("buttonId", buttonID)
+	defer span.Finish()
+
+	titleMessage := "could not create proactive chat in salesforce"
+	interconnection.Client = m.client
+	interconnection.proactive = true
+	span.SetTag(events.Client, interconnection.Client)
+
+	logFields := logrus.Fields{
+		constants.TraceIdKey: span.Context().TraceID(),
+		constants.SpanIdKey:  span.Context().SpanID(),
+		events.UserID:        interconnection.UserID,
+		"caseId":             interconnection.CaseID,
+		"contactId":          contactID,
+	}
+
+	if err := m.ValidateUserID(ctx, interconnection.UserID); err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("error ValidateUserID")
+		span.SetTag(ext.Error, err)
+		m.failChat(interconnection, sessionExistsReason)
+		return errors.New(helpers.ErrorMessage(titleMessage, err))
+	}
+
+	cleanPrefixPhoneNumber(interconnection)
+
+	// the chat can continue without the case number, it is only informative for the user
+	caseNumber, err := m.SalesforceService.GetCaseNumber(ctx, interconnection.CaseID)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("error GetCaseNumber")
+	}
+	interconnection.CaseNumber = caseNumber
+
+	session, err := m.SalesforceService.CreatChat(ctx, interconnection.Name, SfcOrganizationID, SfcDeploymentID, buttonID, interconnection.CaseID, contactID)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("error CreatChat")
+		span.SetTag(ext.Error, err)
+		m.failChat(interconnection, chatReason)
+		return errors.New(helpers.ErrorMessage(titleMessage, err))
+	}
+
+	logFields["sessionId"] = session.Id
+	go m.getContext(interconnection)
+	interconnection.AffinityToken = session.AffinityToken
+	interconnection.SessionID = session.Id
+	interconnection.SessionKey = session.Key
+	interconnection.Status = OnHold
+	interconnection.Timestamp = time.Now()
+	interconnection.LastUserMessageAt = m.lastUserMessageAt(interconnection.UserID)
+	m.AddInterconnection(ctx, interconnection)
+	metrics.ChatsCreated.WithLabelValues(string(interconnection.Provider), interconnection.BotSlug).Inc()
+	m.publishLifecycleEvent(newLifecycleEvent(interconnection, ChatCreatedEvent, ""))
+
+	if state := ProactiveChatState[string(interconnection.Provider)]; state != "" {
+		go ChangeToState(interconnection.UserID, interconnection.BotSlug, state, m.BotrunnnerClient, 0, 0, m.StudioNG, m.isStudioNGFlow)
+	}
+
+	// whatsapp only delivers a template when the user has not written in the last 24 hours
+	if interconnection.Provider == WhatsappProvider && m.windowTemplate.Name != "" && interconnection.windowExpired(time.Now()) {
+		message := NewIntegrationsMessage(span, helpers.RandomString(36), interconnection.UserID, "", interconnection.Provider)
+		if !m.sendWindowTemplate(span, interconnection, message) {
+			logrus.WithFields(logFields).Error("Could not send the window template of the proactive chat")
+		}
+	} else {
+		if text == "" {
+			text = interconnection.renderMessage(span, interconnection.messages().ProactiveChatTemplate, interconnection.templateVariables())
+		}
+		if text != "" {
+			interconnection.sendMessageToQueue(span, helpers.RandomString(36), text, constants.SendMessageToUser)
+		}
+	}
+
+	logrus.WithFields(logFields).Info("Proactive chat created")
 	return nil
 }
 
+// failChat counts and publishes the chat that could not be created by the step that failed
+func (m *Manager) failChat(interconnection *Interconnection, reason string) {
+	chatFailed(interconnection, reason)
+	event := newLifecycleEvent(interconnection, ChatFailedEvent, reason)
+	event.Status = Failed
+	m.publishLifecycleEvent(event)
+}
+
 func cleanPrefixPhoneNumber(interconnection *Interconnection) {
 	if len(interconnection.PhoneNumber) > 10 {
 		for _, code := range CodePhoneRemove {
@@ -603,18 +957,303 @@ func (m *Manager) FinishChat(userID string) error {
 		in.interconnectionCache = m.interconnectionsCache
 	}
 
+	m.closeInterconnection(in, SuccessState[string(in.Provider)], botEndedReason)
+	return nil
+}
+
+// closeInterconnection ends the chat in salesforce and finishes the interconnection, the interconnection is finished
+// even when salesforce fails
+func (m *Manager) closeInterconnection(in *Interconnection, state, reason string) error {
 	// End chat Salesforce
 	err := m.SalesforceService.EndChat(in.AffinityToken, in.SessionKey)
 	if err != nil {
 		logrus.Errorf("could not end chat in salesforce: %s", err.Error())
 	}
 
+	m.finishChat(in, state, reason)
+	return err
+}
+
+// finishChat closes the interconnection and sends the user to the state of the bot when it is not empty, the end is
+// only published when the chat was still open in redis, so it is published once when the pod that polls the chat
+// finishes it at the same time. It returns false when the chat was already finished
+func (m *Manager) finishChat(in *Interconnection, state, reason string) bool {
 	in.runnigLongPolling = false
 
-	in.updateStatusRedis(string(Closed))
+	finished := in.finishStatusRedis(Closed)
+	in.Status = Closed
 	m.EndChat(in)
-	go ChangeToState(in.UserID, in.BotSlug, SuccessState[string(in.Provider)], m.BotrunnnerClient, 0, 0, in.StudioNG, in.isStudioNGFlow)
-	return nil
+	if !finished {
+		return false
+	}
+	m.publishLifecycleEvent(newLifecycleEvent(in, ChatEndedEvent, reason))
+	if state != "" {
+		go ChangeToState(in.UserID, in.BotSlug, state, m.BotrunnnerClient, 0, 0, in.StudioNG, in.isStudioNGFlow)
+	}
+	return true
+}
+
+// CloseChatsFilter selects the chats to close, only the ACTIVE and ON_HOLD chats are closed
+type CloseChatsFilter struct {
+	UserIDs   []string
+	Statuses  []InterconnectionStatus
+	OlderThan time.Duration
+	Provider  Provider
+	BotSlug   string
+	// BotState is the state of the bot where the users are sent, they are not sent when it is empty
+	BotState string
+	// DryRun reports the chats that match without closing them
+	DryRun bool
+}
+
+func (f CloseChatsFilter) match(chat ChatStatus, now time.Time) bool {
+	if chat.Status != Active && chat.Status != OnHold {
+		return false
+	}
+
+	if len(f.Statuses) > 0 && !containsStatus(f.Statuses, chat.Status) {
+		return false
+	}
+
+	return (f.OlderThan == 0 || now.Sub(chat.Timestamp) >= f.OlderThan) &&
+		(f.Provider == "" || chat.Provider == f.Provider) &&
+		(f.BotSlug == "" || chat.BotSlug == f.BotSlug)
+}
+
+func containsStatus(statuses []InterconnectionStatus, status InterconnectionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseChatResult is the result of closing the chat of a user
+type CloseChatResult struct {
+	UserID    string                `json:"userId"`
+	Status    InterconnectionStatus `json:"status"`
+	Timestamp time.Time             `json:"timestamp"`
+	Provider  Provider              `json:"provider"`
+	BotSlug   string                `json:"botSlug"`
+	Closed    bool                  `json:"closed"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// CloseChatsReport has the result of every chat that matched the filter
+type CloseChatsReport struct {
+	DryRun  bool              `json:"dryRun"`
+	Matched int               `json:"matched"`
+	Closed  int               `json:"closed"`
+	Failed  int               `json:"failed"`
+	Chats   []CloseChatResult `json:"chats"`
+}
+
+// CloseChats closes the chats of the client that match the filter, the users of the filter without an open chat
+// are reported as failed
+func (m *Manager) CloseChats(filter CloseChatsFilter) (*CloseChatsReport, error) {
+	interconnections := m.interconnectionsCache.RetrieveAllInterconnections(m.client)
+	if interconnections == nil {
+		return nil, errors.New("could not retrieve the interconnections from redis")
+	}
+
+	users := make(map[string]bool, len(filter.UserIDs))
+	for _, userID := range filter.UserIDs {
+		users[userID] = false
+	}
+
+	now := time.Now()
+	report := &CloseChatsReport{DryRun: filter.DryRun, Chats: []CloseChatResult{}}
+	for _, interconnection := range *interconnections {
+		in := convertInterconnectionCacheToInterconnection(interconnection)
+		chat := newChatStatus(in)
+		if _, ok := users[chat.UserID]; len(users) > 0 && !ok {
+			continue
+		}
+		if !filter.match(*chat, now) {
+			continue
+		}
+		users[chat.UserID] = true
+
+		result := CloseChatResult{
+			UserID:    chat.UserID,
+			Status:    chat.Status,
+			Timestamp: chat.Timestamp,
+			Provider:  chat.Provider,
+			BotSlug:   chat.BotSlug,
+		}
+		report.Matched++
+		if !filter.DryRun {
+			if local, ok := m.validInterconnection(in.UserID); ok {
+				in = local
+			} else {
+				in.interconnectionCache = m.interconnectionsCache
+				in.StudioNG = m.StudioNG
+				in.isStudioNGFlow = m.isStudioNGFlow
+			}
+
+			// the chat stays open when salesforce fails, so it can be closed again
+			if err := m.SalesforceService.EndChat(in.AffinityToken, in.SessionKey); err != nil {
+				result.Error = helpers.ErrorMessage("could not end chat in salesforce", err)
+				report.Failed++
+				logrus.WithFields(logrus.Fields{
+					events.UserID: chat.UserID,
+					"status":      chat.Status,
+				}).WithError(err).Error("Chat not closed by the bulk close")
+			} else {
+				m.finishChat(in, filter.BotState, closedReason)
+				result.Closed = true
+				report.Closed++
+				logrus.WithFields(logrus.Fields{
+					events.UserID: chat.UserID,
+					"status":      chat.Status,
+				}).Info("Chat closed by the bulk close")
+			}
+		}
+		report.Chats = append(report.Chats, result)
+	}
+
+	for _, userID := range filter.UserIDs {
+		if !users[userID] {
+			report.Failed++
+			report.Chats = append(report.Chats, CloseChatResult{
+				UserID: userID,
+				Error:  "this contact does not have an open interconnection that matches the filter",
+			})
+		}
+	}
+	return report, nil
+}
+
+// ChatStatus is the status of the chat of a user
+type ChatStatus struct {
+	UserID     string                `json:"userId"`
+	Client     string                `json:"client"`
+	Status     InterconnectionStatus `json:"status"`
+	Timestamp  time.Time             `json:"timestamp"`
+	Provider   Provider              `json:"provider"`
+	BotSlug    string                `json:"botSlug"`
+	CaseID     string                `json:"caseId"`
+	CaseNumber string                `json:"caseNumber"`
+	// OwnsLongPolling is true when this instance is polling the messages of the agent
+	OwnsLongPolling bool `json:"ownsLongPolling"`
+}
+
+// GetChatStatus returns the status of the chat of the user, it looks for the chat in this instance and then in redis
+func (m *Manager) GetChatStatus(userID string) (*ChatStatus, error) {
+	if in, ok := m.validInterconnection(userID); ok {
+		status := newChatStatus(in)
+		status.OwnsLongPolling = in.runnigLongPolling
+		return status, nil
+	}
+
+	interconnection, err := m.interconnectionsCache.RetrieveInterconnection(cache.Interconnection{
+		UserID: userID,
+		Client: m.client,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newChatStatus(convertInterconnectionCacheToInterconnection(*interconnection)), nil
+}
+
+func newChatStatus(interconnection *Interconnection) *ChatStatus {
+	return &ChatStatus{
+		UserID:     interconnection.UserID,
+		Client:     interconnection.Client,
+		Status:     interconnection.Status,
+		Timestamp:  interconnection.Timestamp,
+		Provider:   interconnection.Provider,
+		BotSlug:    interconnection.BotSlug,
+		CaseID:     interconnection.CaseID,
+		CaseNumber: interconnection.CaseNumber,
+	}
+}
+
+// ChatFilter filters and paginates the chats, the zero values don't filter
+type ChatFilter struct {
+	Status   InterconnectionStatus
+	Provider Provider
+	BotSlug  string
+	// MinAge and MaxAge are the time since the chat was created
+	MinAge time.Duration
+	MaxAge time.Duration
+	// Ascending sorts the oldest chats first, by default the newest chats are first
+	Ascending bool
+	Page      int64
+	Size      int64
+}
+
+func (f ChatFilter) match(chat ChatStatus, now time.Time) bool {
+	age := now.Sub(chat.Timestamp)
+	return (f.Provider == "" || chat.Provider == f.Provider) &&
+		(f.BotSlug == "" || chat.BotSlug == f.BotSlug) &&
+		(f.MinAge == 0 || age >= f.MinAge) &&
+		(f.MaxAge == 0 || age <= f.MaxAge)
+}
+
+// ChatList is a page of the chats, Counts has the chats by status that match the filters without the status filter
+type ChatList struct {
+	Chats  []ChatStatus                  `json:"chats"`
+	Total  int                           `json:"total"`
+	Page   int64                         `json:"page"`
+	Size   int64                         `json:"size"`
+	Counts map[InterconnectionStatus]int `json:"counts"`
+}
+
+// ListChats returns the chats of the client stored in redis that match the filter
+func (m *Manager) ListChats(filter ChatFilter) (*ChatList, error) {
+	interconnections := m.interconnectionsCache.RetrieveAllInterconnections(m.client)
+	if interconnections == nil {
+		return nil, errors.New("could not retrieve the interconnections from redis")
+	}
+
+	now := time.Now()
+	list := &ChatList{
+		Chats:  []ChatStatus{},
+		Page:   filter.Page,
+		Size:   filter.Size,
+		Counts: map[InterconnectionStatus]int{},
+	}
+	for _, interconnection := range *interconnections {
+		chat := newChatStatus(convertInterconnectionCacheToInterconnection(interconnection))
+		if !filter.match(*chat, now) {
+			continue
+		}
+
+		list.Counts[chat.Status]++
+		if filter.Status != "" && chat.Status != filter.Status {
+			continue
+		}
+
+		if in, ok := m.validInterconnection(chat.UserID); ok {
+			chat.OwnsLongPolling = in.runnigLongPolling
+		}
+		list.Chats = append(list.Chats, *chat)
+	}
+
+	sort.SliceStable(list.Chats, func(i, j int) bool {
+		if filter.Ascending {
+			return list.Chats[i].Timestamp.Before(list.Chats[j].Timestamp)
+		}
+		return list.Chats[i].Timestamp.After(list.Chats[j].Timestamp)
+	})
+
+	list.Total = len(list.Chats)
+	if filter.Page > 0 && filter.Size > 0 {
+		start := (filter.Page - 1) * filter.Size
+		if start >= int64(list.Total) {
+			list.Chats = []ChatStatus{}
+			return list, nil
+		}
+		end := start + filter.Size
+		if end > int64(list.Total) {
+			end = int64(list.Total)
+		}
+		list.Chats = list.Chats[start:end]
+	}
+	return list, nil
 }
 
 func (m *Manager) ValidateUserID(ctx context.Context, userID string) error {
@@ -669,6 +1308,7 @@ func (m *Manager) AddInterconnection(ctx context.Context, interconnection *Inter
 	interconnection.kafkaProducer = m.kafkaProducer
 	interconnection.KafkaTopic = m.KafkaTopic
 	interconnection.SleepLongPolling = m.SleepLongPollling
+	interconnection.lifecycle = m
 
 	go m.storeInterconnectionInRedis(interconnection)
 
@@ -686,8 +1326,59 @@ func (m *Manager) storeInterconnectionInRedis(interconnection *Interconnection)
 }
 
 func (m *Manager) getContext(interconnection *Interconnection) {
-	interconnection.Context = fmt.Sprintf("%s:\n%s", Messages.Context, m.getContextByUserID(interconnection.UserID))
+	messages := interconnection.messages()
+	interconnection.Context = fmt.Sprintf("%s:\n%s", messages.Context, m.getContextByUserID(interconnection.UserID, messages))
 	logrus.Infof("Get context of userID : %s", interconnection.UserID)
+
+	// the case of a proactive chat is opened by the agent, the files and the transcript of the bot do not belong to it
+	if interconnection.proactive {
+		return
+	}
+	if ContextMediaAttachments > 0 {
+		m.attachContextMedia(interconnection)
+	}
+	if TranscriptFormat != "" {
+		m.attachTranscript(interconnection)
+	}
+}
+
+// attachContextMedia attaches to the case the recent files that the user sent to the bot
+func (m *Manager) attachContextMedia(interconnection *Interconnection) {
+	allContext := m.contextcache.RetrieveContextFromSet(m.client, interconnection.UserID)
+
+	sort.Slice(allContext, func(i, j int) bool { return allContext[i].Timestamp > allContext[j].Timestamp })
+	attachments := 0
+	for _, ctx := range allContext {
+		if attachments == ContextMediaAttachments {
+			return
+		}
+		if ctx.From != fromUser || ctx.URL == "" || ctx.Ttl.Before(time.Now()) {
+			continue
+		}
+
+		attachments++
+		fileName := contextFileName(ctx)
+		err := m.SalesforceService.InsertFileInCase(ctx.URL, fileName, ctx.MIMEType, interconnection.CaseID)
+		fileUploaded(interconnection, err)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				events.UserID: interconnection.UserID,
+				"caseId":      interconnection.CaseID,
+				"url":         ctx.URL,
+			}).WithError(err).Error("Could not attach the context file to the case")
+			continue
+		}
+		logrus.Infof("Context file %s attached to the case of userID : %s", fileName, interconnection.UserID)
+	}
+}
+
+// contextFileName returns the caption of the file as its name, otherwise the last part of the url
+func contextFileName(ctx cache.Context) string {
+	if ctx.Caption != "" {
+		re := regexp.MustCompile(`[^\w]`)
+		return strings.TrimSpace(re.ReplaceAllString(ctx.Caption, " "))
+	}
+	return path.Base(ctx.URL)
 }
 
 // SaveContext method will save context of integration message
@@ -765,6 +1456,9 @@ func (m *Manager) SaveContext(context context.Context, integration *models.Integ
 
 func (m *Manager) salesforceComunication(mainSpan tracer.Span, integration *models.IntegrationsRequest) bool {
 	interconnection, ok := m.validInterconnection(integration.From)
+	if ok {
+		interconnection.userMessageReceived(time.Now().UTC())
+	}
 	isInterconnectionActive := ok && interconnection.Status == Active
 	mainSpan.SetTag(events.ChatActive, isInterconnectionActive)
 	if isInterconnectionActive {
@@ -790,48 +1484,35 @@ func (m *Manager) sendMessageComunication(mainSpan tracer.Span, interconnection
 	}
 	switch integration.Type {
 	case constants.TextType:
-		if strings.Contains(constants.DevEnvironments, m.environment) {
-			for _, keyword := range m.keywordsRestart {
-				if strings.ToLower(integration.Text.Body) == keyword {
-					err := m.SalesforceService.EndChat(interconnection.AffinityToken, interconnection.SessionKey)
-					mainSpan.SetTag("finishChat", true)
-					if err != nil {
-						mainSpan.SetTag(ext.Error, err)
-						mainSpan.SetTag("finishChat", false)
-						logrus.WithFields(logFields).WithError(err).Error("EndChat error")
-						return
-					}
-					interconnection.updateStatusRedis(string(Closed))
-					interconnection.Status = Closed
-					interconnection.runnigLongPolling = false
-					return
-				}
-			}
+		if m.runEndUserCommand(mainSpan, interconnection, integration.Text.Body) {
+			return
 		}
 
 		interconnection.sendMessageToQueue(mainSpan,
 			integration.ID,
 			integration.Text.Body,
 			constants.SendMessageToSalesforce)
+		interconnection.publishMessage(ChatMessageInEvent, integration.ID, integration.Type, integration.Text.Body)
 
 	case constants.ImageType, constants.DocumentType, constants.AudioType:
-		fileMessageError := Messages.UploadFileError
-		fileMessageSuccess := Messages.UploadFileSuccess
+		messages := interconnection.messages()
+		fileMessageError := messages.UploadFileError
+		fileMessageSuccess := messages.UploadFileSuccess
 		uri := integration.Document.URL
 		mime := integration.Document.MIMEType
 		message := ""
 
 		if integration.Type == constants.ImageType {
-			fileMessageError = Messages.UploadImageError
-			fileMessageSuccess = Messages.UploadImageSuccess
+			fileMessageError = messages.UploadImageError
+			fileMessageSuccess = messages.UploadImageSuccess
 			uri = integration.Image.URL
 			mime = integration.Image.MIMEType
 			message = integration.Image.Caption
 		}
 
 		if integration.Type == constants.AudioType {
-			fileMessageError = Messages.UploadAudioError
-			fileMessageSuccess = Messages.UploadAudioSuccess
+			fileMessageError = messages.UploadAudioError
+			fileMessageSuccess = messages.UploadAudioSuccess
 			uri = integration.Audio.URL
 			mime = integration.Audio.MIMEType
 		}
@@ -843,21 +1524,24 @@ func (m *Manager) sendMessageComunication(mainSpan tracer.Span, interconnection
 			fileName,
 			mime,
 			interconnection.CaseID)
+		fileUploaded(interconnection, err)
 		if err != nil {
 			mainSpan.SetTag(ext.Error, err)
 			mainSpan.SetTag(events.SendFile, false)
 			logrus.WithFields(logFields).WithError(err).Error("InsertFileInCase error")
+			fileMessageError = fileErrorMessage(mainSpan, err, messages, fileMessageError)
 			interconnection.sendMessageToQueue(mainSpan,
 				integration.ID,
-				fileMessageError,
+				interconnection.renderMessage(mainSpan, fileMessageError, interconnection.templateVariables()),
 				constants.SendMessageToUser)
 
 			return
 		}
 		logrus.WithFields(logFields).Info("Send file to agent")
 		mainSpan.SetTag(events.SendFile, true)
+		interconnection.publishMessage(ChatMessageInEvent, integration.ID, integration.Type, message)
 
-		textMessage := fileMessageSuccess
+		textMessage := interconnection.renderMessage(mainSpan, fileMessageSuccess, interconnection.templateVariables())
 
 		if SendImageNameInMessage {
 			textMessage += fileName
@@ -878,6 +1562,96 @@ func (m *Manager) sendMessageComunication(mainSpan tracer.Span, interconnection
 	}
 }
 
+// runEndUserCommand ends the chat with the agent when the text is one of the end-user commands,
+// it returns true when the text was handled as a command
+func (m *Manager) runEndUserCommand(mainSpan tracer.Span, interconnection *Interconnection, text string) bool {
+	command := strings.ToLower(strings.TrimSpace(text))
+	states, ok := m.endUserCommands[command]
+	if !ok {
+		return false
+	}
+
+	logFields := logrus.Fields{
+		constants.TraceIdKey:  mainSpan.Context().TraceID(),
+		constants.SpanIdKey:   mainSpan.Context().SpanID(),
+		events.UserID:         interconnection.UserID,
+		events.EndUserCommand: command,
+	}
+	mainSpan.SetTag(events.EndUserCommand, command)
+	mainSpan.SetTag("finishChat", true)
+
+	// the note must be sent before ending the session, the agent doesn't receive messages after it
+	variables := interconnection.templateVariables()
+	variables.Command = command
+	if note := interconnection.renderMessage(mainSpan, interconnection.messages().EndUserCommandNote, variables, command); note != "" {
+		interconnection.sendMessageToSalesforce(NewSfMessage(mainSpan,
+			interconnection.AffinityToken,
+			interconnection.SessionKey,
+			note,
+			interconnection.UserID))
+	}
+
+	err := m.SalesforceService.EndChat(interconnection.AffinityToken, interconnection.SessionKey)
+	if err != nil {
+		mainSpan.SetTag(ext.Error, err)
+		mainSpan.SetTag("finishChat", false)
+		logrus.WithFields(logFields).WithError(err).Error("EndChat error")
+		return true
+	}
+
+	// the state of the command is sent below with the timeouts of the bot
+	if !m.finishChat(interconnection, "", userEndedReason) {
+		logrus.WithFields(logFields).Info("Chat already finished before the end-user command")
+		return true
+	}
+	logrus.WithFields(logFields).Info("Chat finished by end-user command")
+
+	if state := states[string(interconnection.Provider)]; state != "" {
+		go ChangeToState(interconnection.UserID, interconnection.BotSlug, state, m.BotrunnnerClient, BotrunnerTimeout, StudioNGTimeout, m.StudioNG, m.isStudioNGFlow)
+	}
+	return true
+}
+
+// newEndUserCommands returns the commands configured, in dev environments the restart keywords
+// are commands too, without state because the bot restarts itself with them
+func newEndUserCommands(commands envs.EndUserCommands, keywordsRestart []string, environment string) envs.EndUserCommands {
+	if !strings.Contains(constants.DevEnvironments, environment) || len(keywordsRestart) == 0 {
+		return commands
+	}
+
+	endUserCommands := envs.EndUserCommands{}
+	for _, keyword := range keywordsRestart {
+		endUserCommands[strings.ToLower(keyword)] = map[string]string{}
+	}
+	for command, states := range commands {
+		endUserCommands[command] = states
+	}
+	return endUserCommands
+}
+
+// splitMessage splits the text in the parts that the destination accepts, the destination can be
+// a provider or salesforce
+func splitMessage(text, destination string) []string {
+	return helpers.SplitMessage(text, MessageLimits[destination], SplitMessageMarkers)
+}
+
+// fileErrorMessage returns the message for the user when a file was rejected, otherwise the default error message
+func fileErrorMessage(mainSpan tracer.Span, err error, messages models.MessageTemplate, defaultMessage string) string {
+	switch {
+	case errors.Is(err, constants.ErrFileTooLarge):
+		mainSpan.SetTag(events.FileTooLarge, true)
+		if messages.UploadFileTooLarge != "" {
+			return messages.UploadFileTooLarge
+		}
+	case errors.Is(err, constants.ErrFileTypeNotAllowed):
+		mainSpan.SetTag(events.FileTypeNotAllowed, true)
+		if messages.UploadFileTypeNotAllowed != "" {
+			return messages.UploadFileTypeNotAllowed
+		}
+	}
+	return defaultMessage
+}
+
 func defineFileName(interconnection *Interconnection, integration *models.IntegrationsRequest) string {
 	caption := ""
 	url := ""
@@ -935,10 +1709,15 @@ func (m *Manager) EndChat(interconnection *Interconnection) {
 	logrus.Infof("Ending Interconnection : %s", interconnection.UserID)
 }
 
-func (m *Manager) getContextByUserID(userID string) string {
+func (m *Manager) getContextByUserID(userID string, messages models.MessageTemplate) string {
 	allContext := m.contextcache.RetrieveContextFromSet(m.client, userID)
 
 	sort.Slice(allContext, func(i, j int) bool { return allContext[j].Timestamp > allContext[i].Timestamp })
+	return renderContext(allContext, messages)
+}
+
+// renderContext returns the context as the agents see it, the context must be sorted
+func renderContext(allContext []cache.Context, messages models.MessageTemplate) string {
 	builder := strings.Builder{}
 	for _, ctx := range allContext {
 		if ctx.Ttl.Before(time.Now()) {
@@ -952,28 +1731,82 @@ func (m *Manager) getContextByUserID(userID string) string {
 			Format(constants.DateFormat)
 
 		ctx.Text = strings.TrimRight(ctx.Text, "\n")
+		if ctx.URL != "" {
+			ctx.Text = mediaContextText(ctx, messages)
+		}
 		if ctx.From == fromUser {
-			fmt.Fprintf(&builder, "%s [%s]:%s\n\n", Messages.ClientLabel, date, ctx.Text)
+			fmt.Fprintf(&builder, "%s [%s]:%s\n\n", messages.ClientLabel, date, ctx.Text)
 		} else {
-			fmt.Fprintf(&builder, "%s [%s]:%s\n\n", Messages.BotLabel, date, ctx.Text)
+			fmt.Fprintf(&builder, "%s [%s]:%s\n\n", messages.BotLabel, date, ctx.Text)
 		}
 	}
 
 	return builder.String()
 }
 
+// mediaContextText returns the text of a file of the context, e.g. "[Image: caption] <url>"
+func mediaContextText(ctx cache.Context, messages models.MessageTemplate) string {
+	label := messages.DocumentLabel
+	switch {
+	case strings.HasPrefix(ctx.MIMEType, "image/"):
+		label = messages.ImageLabel
+	case strings.HasPrefix(ctx.MIMEType, "audio/"):
+		label = messages.AudioLabel
+	}
+
+	if ctx.Caption != "" {
+		label = fmt.Sprintf("%s: %s", label, strings.TrimRight(ctx.Caption, "\n"))
+	}
+	text := fmt.Sprintf("[%s] %s", label, ctx.URL)
+	if ctx.Text != "" {
+		text = fmt.Sprintf("%s\n%s", text, ctx.Text)
+	}
+	return text
+}
+
 func (m *Manager) GetContextByUserID(userID string) []cache.Context {
 	allContext := m.contextcache.RetrieveContextFromSet(m.client, userID)
 
 	sort.Slice(allContext, func(i, j int) bool { return allContext[j].Timestamp > allContext[i].Timestamp })
 
-	for i, ctx := range allContext {
-		if ctx.Ttl.Before(time.Now()) {
-			allContext = append(allContext[:i], allContext[i+1:]...)
+	validContext := make([]cache.Context, 0, len(allContext))
+	for _, ctx := range allContext {
+		if !ctx.Ttl.Before(time.Now()) {
+			validContext = append(validContext, ctx)
 		}
 	}
 
-	return allContext
+	return validContext
+}
+
+// FilterContextByUserID returns the page of the context that matches the filter and the total of the matches
+func (m *Manager) FilterContextByUserID(userID string, filter ContextFilter) ([]cache.Context, int) {
+	filteredContext := []cache.Context{}
+	for _, ctx := range m.GetContextByUserID(userID) {
+		if filter.match(ctx) {
+			filteredContext = append(filteredContext, ctx)
+		}
+	}
+
+	total := len(filteredContext)
+	if filter.Offset >= total {
+		return []cache.Context{}, total
+	}
+	filteredContext = filteredContext[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(filteredContext) {
+		filteredContext = filteredContext[:filter.Limit]
+	}
+	return filteredContext, total
+}
+
+// RenderContextByUserID returns the context as the agents see it, with the messages of the chat of the user
+// when it exists
+func (m *Manager) RenderContextByUserID(userID string, allContext []cache.Context) string {
+	messages := Messages
+	if interconnection, ok := m.validInterconnection(userID); ok {
+		messages = interconnection.messages()
+	}
+	return renderContext(allContext, messages)
 }
 
 // Change button or owner according to the provider or by custom fields
@@ -1017,7 +1850,11 @@ func NewInterconectionCache(interconnection *Interconnection) cache.Interconnect
 		Email:         interconnection.Email,
 		PhoneNumber:   interconnection.PhoneNumber,
 		CaseID:        interconnection.CaseID,
+		CaseNumber:    interconnection.CaseNumber,
 		ExtraData:     interconnection.ExtraData,
+
+		LastUserMessageAt:    interconnection.LastUserMessageAt,
+		WindowTemplateSentAt: interconnection.windowTemplateSentAt,
 	}
 }
 
@@ -1098,38 +1935,25 @@ func (m *Manager) sendMessageComunicationFB(mainSpan tracer.Span, interconnectio
 	}
 	switch {
 	case message.Message.Text != "":
-		if strings.Contains(constants.DevEnvironments, m.environment) {
-			for _, keyword := range m.keywordsRestart {
-				if strings.ToLower(message.Message.Text) == keyword {
-					err := m.SalesforceService.EndChat(interconnection.AffinityToken, interconnection.SessionKey)
-					mainSpan.SetTag("finishChat", true)
-					if err != nil {
-						mainSpan.SetTag(ext.Error, err)
-						mainSpan.SetTag("finishChat", false)
-						logrus.WithFields(logFields).WithError(err).Error("End Chat for restart key error")
-						return
-					}
-					interconnection.updateStatusRedis(string(Closed))
-					interconnection.Status = Closed
-					interconnection.runnigLongPolling = false
-					return
-				}
-			}
+		if m.runEndUserCommand(mainSpan, interconnection, message.Message.Text) {
+			return
 		}
 		interconnection.sendMessageToQueue(mainSpan,
 			message.Sender.ID,
 			message.Message.Text,
 			constants.SendMessageToSalesforce)
+		interconnection.publishMessage(ChatMessageInEvent, message.Message.Mid, constants.TextType, message.Message.Text)
 
 	case message.Message.Attachments != nil:
 		for _, attachment := range message.Message.Attachments {
 			if attachment.Type == constants.ImageType || attachment.Type == constants.FileType {
-				fileMessageError := Messages.UploadFileError
-				fileMessageSuccess := Messages.UploadFileSuccess
+				messages := interconnection.messages()
+				fileMessageError := messages.UploadFileError
+				fileMessageSuccess := messages.UploadFileSuccess
 
 				if attachment.Type == constants.ImageType {
-					fileMessageError = Messages.UploadImageError
-					fileMessageSuccess = Messages.UploadImageSuccess
+					fileMessageError = messages.UploadImageError
+					fileMessageSuccess = messages.UploadImageSuccess
 				}
 
 				err := m.SalesforceService.InsertFileInCase(
@@ -1137,21 +1961,24 @@ func (m *Manager) sendMessageComunicationFB(mainSpan tracer.Span, interconnectio
 					interconnection.SessionID,
 					"",
 					interconnection.CaseID)
+				fileUploaded(interconnection, err)
 				if err != nil {
 					mainSpan.SetTag(ext.Error, err)
 					mainSpan.SetTag(events.SendFile, false)
 					logrus.WithFields(logFields).WithError(err).Error("InsertFileInCase error")
+					fileMessageError = fileErrorMessage(mainSpan, err, messages, fileMessageError)
 					interconnection.sendMessageToQueue(mainSpan,
 						message.Sender.ID,
-						fileMessageError,
+						interconnection.renderMessage(mainSpan, fileMessageError, interconnection.templateVariables()),
 						constants.SendMessageToUser)
 					return
 				}
 				logrus.WithFields(logFields).Info("FB Send File to agent")
 				mainSpan.SetTag(events.SendFile, true)
+				interconnection.publishMessage(ChatMessageInEvent, message.Message.Mid, attachment.Type, "")
 				interconnection.sendMessageToQueue(mainSpan,
 					message.Sender.ID,
-					fileMessageSuccess,
+					interconnection.renderMessage(mainSpan, fileMessageSuccess, interconnection.templateVariables()),
 					constants.SendMessageToSalesforce)
 			}
 		}
@@ -1287,11 +2114,14 @@ func (m *Manager) Process(ctx context.Context, msg []byte) error {
 	case constants.SendMessageToUser:
 		m.IntegrationChanRateLimiter.Wait(ctx)
 
-		go m.sendMessageToUser(NewIntegrationsMessage(span,
+		integrationsMessage := NewIntegrationsMessage(span,
 			message.ID,
 			message.Params.UserID,
 			message.Params.Text,
-			message.Params.Provider))
+			message.Params.Provider)
+		integrationsMessage.MediaURL = message.Params.MediaURL
+		integrationsMessage.MediaType = message.Params.MediaType
+		go m.sendMessageToUser(integrationsMessage)
 	}
 	return nil
 }
@@ -26,6 +26,7 @@ import (
 	"yalochat.com/salesforce-integration/base/clients/integrations"
 	"yalochat.com/salesforce-integration/base/constants"
 	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
 )
 
 const (
@@ -43,6 +44,7 @@ const (
 	recordTypeID        = "recordTypeID"
 	recordAccountTypeID = "recordAccountTypeID"
 	caseID              = "caseID"
+	caseNumber          = "00001001"
 	messageID           = "messageID"
 	ttlMessage          = time.Second * 3
 )
@@ -131,10 +133,18 @@ func TestCreateManager(t *testing.T) {
 		expected.finishInterconnection = actual.finishInterconnection
 		expected.contextcache = actual.contextcache
 		expected.interconnectionsCache = actual.interconnectionsCache
+		expected.chatRequestCache = actual.chatRequestCache
 		expected.isStudioNGFlow = true
 		expected.interconnectionMap = actual.interconnectionMap
 		expected.IntegrationChanRateLimiter = actual.IntegrationChanRateLimiter
 		expected.SalesforceChanRequestLimiter = actual.SalesforceChanRequestLimiter
+		expected.readiness = actual.readiness
+		expected.callbackClient = actual.callbackClient
+		expected.webhookCache = actual.webhookCache
+		expected.messageDeliveryCache = actual.messageDeliveryCache
+		expected.webhookSubscribers = actual.webhookSubscribers
+		expected.webhookClaimTimeout = webhookClaimMargin
+		expected.webhookWorkers = actual.webhookWorkers
 
 		actual.EndChat(interconnection)
 		assert.Equal(t, expected, actual)
@@ -182,6 +192,11 @@ func TestCreateManager(t *testing.T) {
 		expected.interconnectionMap = actual.interconnectionMap
 		expected.IntegrationChanRateLimiter = actual.IntegrationChanRateLimiter
 		expected.SalesforceChanRequestLimiter = actual.SalesforceChanRequestLimiter
+		expected.readiness = actual.readiness
+		expected.callbackClient = actual.callbackClient
+		expected.webhookSubscribers = actual.webhookSubscribers
+		expected.webhookClaimTimeout = webhookClaimMargin
+		expected.webhookWorkers = actual.webhookWorkers
 		assert.Equal(t, expected, actual)
 	})
 }
@@ -261,6 +276,7 @@ func TestManager_CreateChat(t *testing.T) {
 		}
 		salesforceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -277,6 +293,9 @@ func TestManager_CreateChat(t *testing.T) {
 			interconnection.ExtraData).
 			Return(caseID, nil).Once()
 
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).
+			Return(caseNumber, nil).Once()
+
 		salesforceMock.On("CreatChat",
 			mock.Anything,
 			interconnection.Name,
@@ -334,6 +353,7 @@ func TestManager_CreateChat(t *testing.T) {
 
 		err := manager.CreateChat(context.Background(), interconnection)
 		assert.NoError(t, err)
+		assert.Equal(t, caseNumber, interconnection.CaseNumber)
 		manager.EndChat(interconnection)
 	})
 
@@ -361,6 +381,7 @@ func TestManager_CreateChat(t *testing.T) {
 		}
 		salesforceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -377,6 +398,9 @@ func TestManager_CreateChat(t *testing.T) {
 			interconnection.ExtraData).
 			Return(caseID, nil).Once()
 
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).
+			Return(caseNumber, nil).Once()
+
 		salesforceMock.On("CreatChat",
 			mock.Anything,
 			interconnection.Name,
@@ -470,6 +494,7 @@ func TestManager_CreateChat(t *testing.T) {
 		}
 		salesforceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -486,6 +511,9 @@ func TestManager_CreateChat(t *testing.T) {
 			interconnection.ExtraData).
 			Return(caseID, nil).Once()
 
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).
+			Return("", errors.New("not found")).Once()
+
 		salesforceMock.On("CreatChat",
 			mock.Anything,
 			interconnection.Name,
@@ -566,6 +594,7 @@ func TestManager_CreateChat(t *testing.T) {
 
 		salesforceServiceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -626,6 +655,7 @@ func TestManager_CreateChat(t *testing.T) {
 
 		salesforceServiceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -686,6 +716,7 @@ func TestManager_CreateChat(t *testing.T) {
 
 		salesforceServiceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -758,6 +789,7 @@ func TestManager_CreateChat(t *testing.T) {
 
 		salesforceServiceMock.On("GetOrCreateContact",
 			mock.Anything,
+			"",
 			interconnection.Name,
 			interconnection.Email,
 			interconnection.PhoneNumber,
@@ -774,6 +806,9 @@ func TestManager_CreateChat(t *testing.T) {
 			interconnection.ExtraData).
 			Return(caseID, nil).Once()
 
+		salesforceServiceMock.On("GetCaseNumber", mock.Anything, caseID).
+			Return(caseNumber, nil).Once()
+
 		salesforceServiceMock.On("CreatChat",
 			mock.Anything,
 			interconnection.Name,
@@ -967,6 +1002,473 @@ func TestManager_CreateChat(t *testing.T) {
 
 }
 
+func TestManager_CreateProactiveChat(t *testing.T) {
+	interconectionLocal := cache.New()
+	Messages = models.MessageTemplate{ProactiveChatTemplate: "Un agente quiere continuar la conversación de tu caso {{.CaseNumber}}"}
+	ProactiveChatState = map[string]string{"whatsapp": "from-sf-proactive"}
+	SfcOrganizationID = organizationID
+	SfcDeploymentID = deploymentID
+	defer func() {
+		Messages = models.MessageTemplate{}
+		ProactiveChatState = nil
+	}()
+
+	newProactiveInterconnection := func() *Interconnection {
+		interconnection := NewInterconnection(&NewInterconnectionParams{
+			UserID:   userID,
+			BotSlug:  botSlug,
+			Name:     name,
+			Provider: provider,
+		})
+		interconnection.CaseID = caseID
+		return interconnection
+	}
+
+	t.Run("Should create the chat and notify the user", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		interconnection := newProactiveInterconnection()
+
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).Return(caseNumber, nil).Once()
+		salesforceMock.On("CreatChat", mock.Anything, name, organizationID, deploymentID, "agentButtonID", caseID, contactID).
+			Return(&chat.SessionResponse{AffinityToken: affinityToken, Key: sessionKey, Id: "sessionID"}, nil).Once()
+		salesforceMock.On("GetMessages", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(&chat.MessagesResponse{}, nil)
+
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound).Once()
+		interconnectionMock.On("StoreInterconnection", mock.Anything).Return(nil)
+
+		contextMock := new(mocks.IContextCache)
+		contextMock.On("RetrieveContextFromSet", client, userID).Return([]cache.Context{})
+
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", mock.Anything).Return(true, nil)
+
+		var queueMessage InterconnectionMessageQueue
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &queueMessage))
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			contextcache:          contextMock,
+			interconnectionMap:    interconectionLocal,
+			BotrunnnerClient:      botRunnerMock,
+			kafkaProducer:         producerMock,
+		}
+
+		err := manager.CreateProactiveChat(context.Background(), interconnection, contactID, "agentButtonID", "")
+		assert.NoError(t, err)
+		assert.True(t, interconnection.proactive)
+		assert.Equal(t, OnHold, interconnection.Status)
+		assert.Equal(t, "sessionID", interconnection.SessionID)
+		assert.Equal(t, caseNumber, interconnection.CaseNumber)
+		assert.Equal(t, constants.SendMessageToUser, queueMessage.EventType)
+		assert.Equal(t, "Un agente quiere continuar la conversación de tu caso 00001001", queueMessage.Params.Text)
+		manager.EndChat(interconnection)
+	})
+
+	t.Run("Should notify the user with the text of the agent", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		interconnection := newProactiveInterconnection()
+		interconnection.Provider = FacebookProvider
+
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).Return("", assert.AnError).Once()
+		salesforceMock.On("CreatChat", mock.Anything, name, organizationID, deploymentID, "agentButtonID", caseID, contactID).
+			Return(&chat.SessionResponse{AffinityToken: affinityToken, Key: sessionKey}, nil).Once()
+		salesforceMock.On("GetMessages", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(&chat.MessagesResponse{}, nil)
+
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, nil).Once()
+		interconnectionMock.On("StoreInterconnection", mock.Anything).Return(nil)
+
+		contextMock := new(mocks.IContextCache)
+		contextMock.On("RetrieveContextFromSet", client, userID).Return([]cache.Context{})
+
+		var queueMessage InterconnectionMessageQueue
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &queueMessage))
+		}).Return(nil).Once()
+
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			contextcache:          contextMock,
+			interconnectionMap:    interconectionLocal,
+			BotrunnnerClient:      botRunnerMock,
+			kafkaProducer:         producerMock,
+		}
+
+		err := manager.CreateProactiveChat(context.Background(), interconnection, contactID, "agentButtonID", "Necesitamos tu identificación")
+		assert.NoError(t, err)
+		assert.Equal(t, "Necesitamos tu identificación", queueMessage.Params.Text)
+		assert.Equal(t, FacebookProvider, queueMessage.Params.Provider)
+		botRunnerMock.AssertNotCalled(t, "SendTo", mock.Anything)
+		manager.EndChat(interconnection)
+	})
+
+	t.Run("Should return an error when the user has a chat", func(t *testing.T) {
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(&cache.Interconnection{UserID: userID, Client: client, Status: string(Active)}, nil).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			interconnectionMap:    interconectionLocal,
+		}
+
+		err := manager.CreateProactiveChat(context.Background(), newProactiveInterconnection(), contactID, "agentButtonID", "")
+		assert.Error(t, err)
+		salesforceMock.AssertNotCalled(t, "CreatChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return an error when the chat is not created in salesforce", func(t *testing.T) {
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, nil).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("GetCaseNumber", mock.Anything, caseID).Return(caseNumber, nil).Once()
+		salesforceMock.On("CreatChat", mock.Anything, name, organizationID, deploymentID, "agentButtonID", caseID, contactID).
+			Return(nil, assert.AnError).Once()
+		producerMock := new(mocks.Producer)
+
+		manager := &Manager{
+			client:                client,
+			SalesforceService:     salesforceMock,
+			interconnectionsCache: interconnectionMock,
+			interconnectionMap:    interconectionLocal,
+			kafkaProducer:         producerMock,
+		}
+
+		err := manager.CreateProactiveChat(context.Background(), newProactiveInterconnection(), contactID, "agentButtonID", "")
+		assert.Error(t, err)
+		_, ok := interconectionLocal.Get(fmt.Sprintf(constants.UserKey, userID))
+		assert.False(t, ok)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+}
+
+func TestManager_GetChatStatus(t *testing.T) {
+	timestamp := time.Now()
+
+	t.Run("Should get the status of the chat of this instance", func(t *testing.T) {
+		interconnections := cache.New()
+		interconnections.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{
+			UserID:            userID,
+			Client:            client,
+			Status:            Active,
+			Timestamp:         timestamp,
+			Provider:          provider,
+			BotSlug:           botSlug,
+			CaseID:            caseID,
+			CaseNumber:        "00001234",
+			runnigLongPolling: true,
+		}, ttlMessage)
+		interconnections.Wait()
+		manager := &Manager{interconnectionMap: interconnections}
+
+		status, err := manager.GetChatStatus(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, &ChatStatus{
+			UserID:          userID,
+			Client:          client,
+			Status:          Active,
+			Timestamp:       timestamp,
+			Provider:        provider,
+			BotSlug:         botSlug,
+			CaseID:          caseID,
+			CaseNumber:      "00001234",
+			OwnsLongPolling: true,
+		}, status)
+	})
+
+	t.Run("Should get the status of the chat from redis", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(&cache.Interconnection{
+				UserID:    userID,
+				Client:    client,
+				Status:    string(OnHold),
+				Timestamp: timestamp,
+				Provider:  provider,
+				BotSlug:   botSlug,
+				CaseID:    caseID,
+			}, nil).Once()
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+		}
+
+		status, err := manager.GetChatStatus(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, &ChatStatus{
+			UserID:    userID,
+			Client:    client,
+			Status:    OnHold,
+			Timestamp: timestamp,
+			Provider:  provider,
+			BotSlug:   botSlug,
+			CaseID:    caseID,
+		}, status)
+	})
+
+	t.Run("Should return an error when the chat does not exist", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound).Once()
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+		}
+
+		status, err := manager.GetChatStatus(userID)
+		assert.ErrorIs(t, err, constants.ErrInterconnectionNotFound)
+		assert.Nil(t, status)
+	})
+}
+
+func TestManager_ListChats(t *testing.T) {
+	now := time.Now()
+	interconnections := []cache.Interconnection{
+		{UserID: "1", Client: client, Status: string(Active), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-10 * time.Minute)},
+		{UserID: "2", Client: client, Status: string(OnHold), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-time.Minute)},
+		{UserID: "3", Client: client, Status: string(Closed), Provider: string(FacebookProvider), BotSlug: botSlug, Timestamp: now.Add(-time.Hour)},
+		{UserID: "4", Client: client, Status: string(Active), Provider: provider, BotSlug: "other-bot", Timestamp: now.Add(-2 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name   string
+		filter ChatFilter
+		users  []string
+		total  int
+		counts map[InterconnectionStatus]int
+	}{
+		{
+			name:   "Should list all the chats from the newest",
+			filter: ChatFilter{},
+			users:  []string{"2", "1", "3", "4"},
+			total:  4,
+			counts: map[InterconnectionStatus]int{Active: 2, OnHold: 1, Closed: 1},
+		},
+		{
+			name:   "Should filter by status and count all the status",
+			filter: ChatFilter{Status: Active, Ascending: true},
+			users:  []string{"4", "1"},
+			total:  2,
+			counts: map[InterconnectionStatus]int{Active: 2, OnHold: 1, Closed: 1},
+		},
+		{
+			name:   "Should filter by provider and bot slug",
+			filter: ChatFilter{Provider: WhatsappProvider, BotSlug: botSlug},
+			users:  []string{"2", "1"},
+			total:  2,
+			counts: map[InterconnectionStatus]int{Active: 1, OnHold: 1},
+		},
+		{
+			name:   "Should filter by age",
+			filter: ChatFilter{MinAge: 5 * time.Minute, MaxAge: 90 * time.Minute},
+			users:  []string{"1", "3"},
+			total:  2,
+			counts: map[InterconnectionStatus]int{Active: 1, Closed: 1},
+		},
+		{
+			name:   "Should paginate",
+			filter: ChatFilter{Page: 2, Size: 3},
+			users:  []string{"4"},
+			total:  4,
+			counts: map[InterconnectionStatus]int{Active: 2, OnHold: 1, Closed: 1},
+		},
+		{
+			name:   "Should return an empty page",
+			filter: ChatFilter{Page: 3, Size: 2},
+			users:  []string{},
+			total:  4,
+			counts: map[InterconnectionStatus]int{Active: 2, OnHold: 1, Closed: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interconnectionCacheMock := new(mocks.IInterconnectionCache)
+			interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(&interconnections).Once()
+			interconnectionsLocal := cache.New()
+			interconnectionsLocal.Set(fmt.Sprintf(constants.UserKey, "1"), &Interconnection{UserID: "1", runnigLongPolling: true}, ttlMessage)
+			interconnectionsLocal.Wait()
+			manager := &Manager{
+				client:                client,
+				interconnectionMap:    interconnectionsLocal,
+				interconnectionsCache: interconnectionCacheMock,
+			}
+
+			list, err := manager.ListChats(tc.filter)
+			assert.NoError(t, err)
+			users := []string{}
+			for _, chat := range list.Chats {
+				users = append(users, chat.UserID)
+				assert.Equal(t, chat.UserID == "1", chat.OwnsLongPolling)
+			}
+			assert.Equal(t, tc.users, users)
+			assert.Equal(t, tc.total, list.Total)
+			assert.Equal(t, tc.counts, list.Counts)
+		})
+	}
+
+	t.Run("Should return an error when redis fails", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(nil).Once()
+		manager := &Manager{
+			client:                client,
+			interconnectionsCache: interconnectionCacheMock,
+		}
+
+		list, err := manager.ListChats(ChatFilter{})
+		assert.Error(t, err)
+		assert.Nil(t, list)
+	})
+}
+
+func TestManager_CloseChats(t *testing.T) {
+	now := time.Now()
+	interconnections := []cache.Interconnection{
+		{UserID: "1", Client: client, Status: string(Active), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-10 * time.Hour), AffinityToken: "token1", SessionKey: "key1"},
+		{UserID: "2", Client: client, Status: string(OnHold), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-time.Hour), AffinityToken: "token2", SessionKey: "key2"},
+		{UserID: "3", Client: client, Status: string(Closed), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-20 * time.Hour)},
+		{UserID: "5", Client: client, Status: string(Active), Provider: provider, BotSlug: botSlug, Timestamp: now.Add(-2 * time.Hour), AffinityToken: "token5", SessionKey: "key5"},
+	}
+
+	t.Run("Should report the chats without closing them in a dry-run", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(&interconnections).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			SalesforceService:     salesforceMock,
+		}
+
+		report, err := manager.CloseChats(CloseChatsFilter{OlderThan: 5 * time.Hour, DryRun: true})
+		assert.NoError(t, err)
+		assert.Equal(t, &CloseChatsReport{
+			DryRun:  true,
+			Matched: 1,
+			Chats: []CloseChatResult{
+				{UserID: "1", Status: Active, Timestamp: interconnections[0].Timestamp, Provider: provider, BotSlug: botSlug},
+			},
+		}, report)
+		salesforceMock.AssertNotCalled(t, "EndChat", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should close the chats and send the users to the state", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(&interconnections).Once()
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: "1", Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(true, nil).Once()
+
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("EndChat", "token1", "key1").Return(nil).Once()
+		salesforceMock.On("EndChat", "token2", "key2").Return(assert.AnError).Once()
+
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		botRunnerMock.On("SendTo", map[string]interface{}{"botSlug": botSlug, "message": "", "state": "closed-by-agent", "userId": "1"}).
+			Return(true, nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			SalesforceService:     salesforceMock,
+			BotrunnnerClient:      botRunnerMock,
+		}
+
+		report, err := manager.CloseChats(CloseChatsFilter{
+			UserIDs:  []string{"1", "2", "3", "4"},
+			Statuses: []InterconnectionStatus{Active, OnHold},
+			BotState: "closed-by-agent",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &CloseChatsReport{
+			Matched: 2,
+			Closed:  1,
+			Failed:  3,
+			Chats: []CloseChatResult{
+				{UserID: "1", Status: Active, Timestamp: interconnections[0].Timestamp, Provider: provider, BotSlug: botSlug, Closed: true},
+				{UserID: "2", Status: OnHold, Timestamp: interconnections[1].Timestamp, Provider: provider, BotSlug: botSlug,
+					Error: "could not end chat in salesforce : assert.AnError general error for testing"},
+				{UserID: "3", Error: "this contact does not have an open interconnection that matches the filter"},
+				{UserID: "4", Error: "this contact does not have an open interconnection that matches the filter"},
+			},
+		}, report)
+
+		time.Sleep(10 * time.Millisecond)
+		salesforceMock.AssertExpectations(t)
+		interconnectionCacheMock.AssertExpectations(t)
+		botRunnerMock.AssertExpectations(t)
+	})
+
+	t.Run("Should not publish the end of the chats already finished by another pod", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(&interconnections).Once()
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: "5", Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(false, nil).Once()
+
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("EndChat", "token5", "key5").Return(nil).Once()
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		producerMock := new(mocks.Producer)
+
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			SalesforceService:     salesforceMock,
+			BotrunnnerClient:      botRunnerMock,
+			kafkaProducer:         producerMock,
+			lifecycleTopic:        "lifecycle",
+		}
+
+		report, err := manager.CloseChats(CloseChatsFilter{UserIDs: []string{"5"}, BotState: "closed-by-agent"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Closed)
+		assert.True(t, report.Chats[0].Closed)
+
+		time.Sleep(10 * time.Millisecond)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+		salesforceMock.AssertExpectations(t)
+		interconnectionCacheMock.AssertExpectations(t)
+		botRunnerMock.AssertNotCalled(t, "SendTo", mock.Anything)
+	})
+
+	t.Run("Should return an error when redis fails", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveAllInterconnections", client).Return(nil).Once()
+		manager := &Manager{
+			client:                client,
+			interconnectionsCache: interconnectionCacheMock,
+		}
+
+		report, err := manager.CloseChats(CloseChatsFilter{OlderThan: time.Hour})
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+}
+
 func TestManager_FinishChat(t *testing.T) {
 	interconectionLocal := cache.New()
 	botRunnerMock := new(mocks.BotRunnerInterface)
@@ -993,12 +1495,6 @@ func TestManager_FinishChat(t *testing.T) {
 		}
 		interconectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, ttlMessage)
 		interconectionLocal.Wait()
-		interconnectionCache := &cache.Interconnection{
-			UserID:     userID,
-			SessionID:  sessionID,
-			SessionKey: sessionID,
-			Status:     string(Active),
-		}
 
 		manager := &Manager{
 			interconnectionMap:    interconectionLocal,
@@ -1007,12 +1503,9 @@ func TestManager_FinishChat(t *testing.T) {
 			BotrunnnerClient:      botRunnerMock,
 		}
 
-		interconnectionCacheMock.On("RetrieveInterconnection",
-			cache.Interconnection{UserID: interconnection.UserID, Client: client}).
-			Return(interconnectionCache, nil).Once()
-		interconnectionCache.Status = string(Closed)
-		interconnectionCacheMock.On("StoreInterconnection", *interconnectionCache).
-			Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection",
+			cache.Interconnection{UserID: interconnection.UserID, Client: client}, string(Closed), string(Closed), string(Failed)).
+			Return(true, nil).Once()
 
 		salesforceMock.On("EndChat",
 			affinityToken, sessionKey).
@@ -1054,20 +1547,10 @@ func TestManager_FinishChat(t *testing.T) {
 
 		interconnectionCacheMock.On("RetrieveInterconnection",
 			cache.Interconnection{UserID: userID, Client: client}).
-			Return(interconnectionCache, nil).Times(2)
-
-		interconnectionCacheClose := &cache.Interconnection{
-			UserID:        userID,
-			BotSlug:       botSlug,
-			Provider:      provider,
-			SessionID:     sessionID,
-			SessionKey:    sessionKey,
-			AffinityToken: affinityToken,
-			Status:        string(Closed),
-			Client:        client,
-		}
-		interconnectionCacheMock.On("StoreInterconnection", *interconnectionCacheClose).
-			Return(nil).Once()
+			Return(interconnectionCache, nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection",
+			cache.Interconnection{UserID: userID, Client: client}, string(Closed), string(Closed), string(Failed)).
+			Return(true, nil).Once()
 
 		salesforceMock.On("EndChat",
 			affinityToken, sessionKey).
@@ -1153,14 +1636,6 @@ func TestManager_FinishChat(t *testing.T) {
 			interconnectionCache: interconnectionCacheMock}
 		interconectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, ttlMessage)
 		interconectionLocal.Wait()
-		interconnectionCache := &cache.Interconnection{
-			UserID:     userID,
-			Provider:   provider,
-			BotSlug:    botSlug,
-			SessionID:  sessionID,
-			SessionKey: sessionID,
-			Status:     string(Active),
-		}
 
 		manager := &Manager{
 			interconnectionMap:    interconectionLocal,
@@ -1169,12 +1644,9 @@ func TestManager_FinishChat(t *testing.T) {
 			interconnectionsCache: interconnectionCacheMock,
 		}
 
-		interconnectionCacheMock.On("RetrieveInterconnection",
-			cache.Interconnection{UserID: interconnection.UserID, Client: client}).
-			Return(interconnectionCache, nil).Once()
-		interconnectionCache.Status = string(Closed)
-		interconnectionCacheMock.On("StoreInterconnection", *interconnectionCache).
-			Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection",
+			cache.Interconnection{UserID: interconnection.UserID, Client: client}, string(Closed), string(Closed), string(Failed)).
+			Return(true, nil).Once()
 
 		salesforceMock.On("EndChat",
 			affinityToken, sessionKey).
@@ -1191,6 +1663,229 @@ func TestManager_FinishChat(t *testing.T) {
 
 }
 
+func TestManager_runEndUserCommand(t *testing.T) {
+	interconectionLocal := cache.New()
+	Messages = models.MessageTemplate{EndUserCommandNote: "El usuario salio con %s"}
+	defer func() { Messages = models.MessageTemplate{} }()
+	endUserCommands := envs.EndUserCommands{
+		"salir": {provider: "from-sf-exit"},
+		"menu":  {},
+	}
+
+	t.Run("Should not run a command", func(t *testing.T) {
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		manager := &Manager{
+			interconnectionMap: interconectionLocal,
+			SalesforceService:  salesforceMock,
+			endUserCommands:    endUserCommands,
+		}
+
+		isCommand := manager.runEndUserCommand(tracer.StartSpan("test"), &Interconnection{UserID: userID}, "quiero salir")
+
+		assert.False(t, isCommand)
+		salesforceMock.AssertNotCalled(t, "EndChat", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should finish chat and change the state of the bot", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		botRunnerMock := new(mocks.BotRunnerInterface)
+
+		interconnection := &Interconnection{
+			Client:               client,
+			UserID:               userID,
+			BotSlug:              botSlug,
+			Status:               Active,
+			Provider:             provider,
+			AffinityToken:        affinityToken,
+			SessionKey:           sessionKey,
+			runnigLongPolling:    true,
+			interconnectionCache: interconnectionCacheMock,
+			SalesforceService:    salesforceMock,
+		}
+		interconectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, ttlMessage)
+		interconectionLocal.Wait()
+
+		manager := &Manager{
+			interconnectionMap: interconectionLocal,
+			SalesforceService:  salesforceMock,
+			BotrunnnerClient:   botRunnerMock,
+			endUserCommands:    endUserCommands,
+		}
+
+		salesforceMock.On("SendMessage", mock.Anything, affinityToken, sessionKey,
+			chat.MessagePayload{Text: "El usuario salio con salir"}).
+			Return(true, nil).Once()
+		salesforceMock.On("EndChat", affinityToken, sessionKey).
+			Return(nil).Once()
+
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(true, nil).Once()
+
+		stateChanged := make(chan bool, 1)
+		botRunnerMock.On("SendTo", map[string]interface{}{"botSlug": botSlug, "message": "", "state": "from-sf-exit", "userId": userID}).
+			Run(func(args mock.Arguments) { stateChanged <- true }).
+			Return(true, nil).Once()
+
+		isCommand := manager.runEndUserCommand(tracer.StartSpan("test"), interconnection, " Salir ")
+
+		assert.True(t, isCommand)
+		assert.Equal(t, Closed, interconnection.Status)
+		assert.False(t, interconnection.runnigLongPolling)
+		_, ok := interconectionLocal.Get(fmt.Sprintf(constants.UserKey, userID))
+		assert.False(t, ok)
+		select {
+		case <-stateChanged:
+		case <-time.After(time.Second):
+			t.Fatal("the state of the bot was not changed")
+		}
+		salesforceMock.AssertExpectations(t)
+		interconnectionCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should finish chat without state", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		botRunnerMock := new(mocks.BotRunnerInterface)
+
+		interconnection := &Interconnection{
+			Client:               client,
+			UserID:               userID,
+			Status:               Active,
+			Provider:             provider,
+			AffinityToken:        affinityToken,
+			SessionKey:           sessionKey,
+			interconnectionCache: interconnectionCacheMock,
+			SalesforceService:    salesforceMock,
+		}
+
+		manager := &Manager{
+			interconnectionMap: interconectionLocal,
+			SalesforceService:  salesforceMock,
+			BotrunnnerClient:   botRunnerMock,
+			endUserCommands:    endUserCommands,
+		}
+
+		salesforceMock.On("SendMessage", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(true, nil).Once()
+		salesforceMock.On("EndChat", affinityToken, sessionKey).
+			Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection", mock.Anything, string(Closed), string(Closed), string(Failed)).
+			Return(true, nil).Once()
+
+		isCommand := manager.runEndUserCommand(tracer.StartSpan("test"), interconnection, "menu")
+
+		assert.True(t, isCommand)
+		assert.Equal(t, Closed, interconnection.Status)
+		botRunnerMock.AssertNotCalled(t, "SendTo", mock.Anything)
+	})
+
+	t.Run("Should not publish the end of a chat already finished", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		botRunnerMock := new(mocks.BotRunnerInterface)
+		producerMock := new(mocks.Producer)
+
+		interconnection := &Interconnection{
+			Client:               client,
+			UserID:               userID,
+			BotSlug:              botSlug,
+			Status:               Active,
+			Provider:             provider,
+			AffinityToken:        affinityToken,
+			SessionKey:           sessionKey,
+			runnigLongPolling:    true,
+			interconnectionCache: interconnectionCacheMock,
+			SalesforceService:    salesforceMock,
+		}
+
+		manager := &Manager{
+			interconnectionMap: interconectionLocal,
+			SalesforceService:  salesforceMock,
+			BotrunnnerClient:   botRunnerMock,
+			endUserCommands:    endUserCommands,
+			kafkaProducer:      producerMock,
+			lifecycleTopic:     "lifecycle",
+		}
+
+		salesforceMock.On("SendMessage", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(true, nil).Once()
+		salesforceMock.On("EndChat", affinityToken, sessionKey).
+			Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(false, nil).Once()
+
+		isCommand := manager.runEndUserCommand(tracer.StartSpan("test"), interconnection, "salir")
+
+		assert.True(t, isCommand)
+		assert.Equal(t, Closed, interconnection.Status)
+		time.Sleep(10 * time.Millisecond)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+		botRunnerMock.AssertNotCalled(t, "SendTo", mock.Anything)
+		interconnectionCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should not finish chat with error in EndChat", func(t *testing.T) {
+		defer interconectionLocal.Clear()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+
+		interconnection := &Interconnection{
+			Client:            client,
+			UserID:            userID,
+			Status:            Active,
+			Provider:          provider,
+			AffinityToken:     affinityToken,
+			SessionKey:        sessionKey,
+			runnigLongPolling: true,
+			SalesforceService: salesforceMock,
+		}
+		interconectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, ttlMessage)
+		interconectionLocal.Wait()
+
+		manager := &Manager{
+			interconnectionMap: interconectionLocal,
+			SalesforceService:  salesforceMock,
+			endUserCommands:    endUserCommands,
+		}
+
+		salesforceMock.On("SendMessage", mock.Anything, affinityToken, sessionKey, mock.Anything).
+			Return(true, nil).Once()
+		salesforceMock.On("EndChat", affinityToken, sessionKey).
+			Return(assert.AnError).Once()
+
+		isCommand := manager.runEndUserCommand(tracer.StartSpan("test"), interconnection, "salir")
+
+		assert.True(t, isCommand)
+		assert.Equal(t, Active, interconnection.Status)
+		assert.True(t, interconnection.runnigLongPolling)
+		_, ok := interconectionLocal.Get(fmt.Sprintf(constants.UserKey, userID))
+		assert.True(t, ok)
+	})
+}
+
+func Test_newEndUserCommands(t *testing.T) {
+	commands := envs.EndUserCommands{"salir": {provider: "from-sf-exit"}}
+
+	t.Run("Should add the restart keywords in dev environments", func(t *testing.T) {
+		actual := newEndUserCommands(commands, []string{"Restart", "salir"}, "dev")
+
+		assert.Equal(t, envs.EndUserCommands{
+			"restart": {},
+			"salir":   {provider: "from-sf-exit"},
+		}, actual)
+		assert.Len(t, commands, 1)
+	})
+
+	t.Run("Should not add the restart keywords in prod environments", func(t *testing.T) {
+		actual := newEndUserCommands(commands, []string{"restart"}, "prod")
+
+		assert.Equal(t, commands, actual)
+	})
+}
+
 func TestManager_SaveContext(t *testing.T) {
 	interconnectionLocal := cache.New()
 
@@ -1584,27 +2279,22 @@ func TestManager_SaveContext(t *testing.T) {
 		defer interconnectionLocal.Clear()
 		contextCache := new(mocks.IContextCache)
 		interconnectionCacheMock := new(mocks.IInterconnectionCache)
-		salesforceMock := new(mocks.SalesforceServiceInterface)
-		salesforceMock.On("EndChat",
-			affinityToken, sessionKey).
-			Return(nil).Once()
-
-		cacheMock := &cache.Interconnection{
-			UserID:     userID,
-			Client:     client,
-			SessionID:  sessionID,
-			SessionKey: sessionID,
-			Status:     string(Active),
-		}
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("EndChat",
+			affinityToken, sessionKey).
+			Return(nil).Once()
+
 		interconnectionCacheMock.On("RetrieveInterconnection",
 			cache.Interconnection{
 				UserID: userID,
 				Client: client,
 			}).
-			Return(cacheMock, nil).Once()
-		cacheMock.Status = string(Closed)
-		interconnectionCacheMock.On("StoreInterconnection", *cacheMock).
-			Return(nil).Once()
+			Return(&cache.Interconnection{UserID: userID, Client: client, Status: string(Active)}, nil).Once()
+		interconnectionCacheMock.On("StoreInterconnection", mock.MatchedBy(func(interconnection cache.Interconnection) bool {
+			return interconnection.Status == string(Active) && !interconnection.LastUserMessageAt.IsZero()
+		})).Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(true, nil).Once()
 
 		cacheMessage := new(mocks.IMessageCache)
 		cacheMessage.On("IsRepeatedMessage", messageID).Return(false).Once()
@@ -1614,7 +2304,7 @@ func TestManager_SaveContext(t *testing.T) {
 			client:                       client,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			interconnectionsCache:        interconnectionCacheMock,
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -1648,6 +2338,10 @@ func TestManager_SaveContext(t *testing.T) {
 		err := manager.SaveContext(context.Background(), integrations)
 
 		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			_, ok := interconnectionLocal.Get(fmt.Sprintf(constants.UserKey, userID))
+			return !ok
+		}, time.Second, 10*time.Millisecond)
 	})
 
 	t.Run("Should send message end chat error service", func(t *testing.T) {
@@ -1666,6 +2360,15 @@ func TestManager_SaveContext(t *testing.T) {
 			SessionKey: sessionID,
 			Status:     string(Active),
 		}
+		interconnectionCacheMock.On("RetrieveInterconnection",
+			cache.Interconnection{
+				UserID: userID,
+				Client: client,
+			}).
+			Return(&cache.Interconnection{UserID: userID, Client: client, Status: string(Active)}, nil).Once()
+		interconnectionCacheMock.On("StoreInterconnection", mock.MatchedBy(func(interconnection cache.Interconnection) bool {
+			return interconnection.Status == string(Active) && !interconnection.LastUserMessageAt.IsZero()
+		})).Return(nil).Once()
 		interconnectionCacheMock.On("RetrieveInterconnection",
 			cache.Interconnection{
 				UserID: userID,
@@ -1684,7 +2387,7 @@ func TestManager_SaveContext(t *testing.T) {
 			client:                       client,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			interconnectionsCache:        interconnectionCacheMock,
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -1755,7 +2458,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -1828,7 +2531,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -1892,7 +2595,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationsClient:           integrationsIMock,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -1954,7 +2657,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -2027,7 +2730,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -2092,7 +2795,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -2156,7 +2859,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationsClient:           integrationsIMock,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -2195,6 +2898,141 @@ func TestManager_SaveContext(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Should send file too large message to user", func(t *testing.T) {
+		Messages = models.MessageTemplate{UploadFileError: "Archivo no enviado", UploadFileTooLarge: "Archivo muy grande"}
+		defer interconnectionLocal.Clear()
+		contextCache := new(mocks.IContextCache)
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+
+		salesforceMock.On("InsertFileInCase",
+			"http://test.com", "caption", "document/pdf", "caseID").
+			Return(constants.ErrFileTooLarge).Once()
+
+		cacheMessage := new(mocks.IMessageCache)
+		cacheMessage.On("IsRepeatedMessage", messageID).Return(false).Once()
+
+		sentMessage := make(chan InterconnectionMessageQueue, 1)
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			message := InterconnectionMessageQueue{}
+			json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &message)
+			sentMessage <- message
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			contextcache:                 contextCache,
+			finishInterconnection:        make(chan *Interconnection),
+			SalesforceService:            salesforceMock,
+			cacheMessage:                 cacheMessage,
+			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
+			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
+			kafkaProducer:                producerMock,
+		}
+
+		interconnectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{
+			Status:        Active,
+			AffinityToken: affinityToken,
+			SessionKey:    sessionKey,
+			SessionID:     sessionID,
+			UserID:        userID,
+			CaseID:        caseID,
+			finishChannel: manager.finishInterconnection,
+			kafkaProducer: producerMock,
+		}, time.Second)
+		interconnectionLocal.Wait()
+
+		manager.interconnectionMap = interconnectionLocal
+
+		integrations := &models.IntegrationsRequest{
+			ID:        messageID,
+			Timestamp: "123456789",
+			Type:      constants.DocumentType,
+			From:      userID,
+			Document: models.Media{
+				URL:      "http://test.com",
+				MIMEType: "document/pdf",
+				Caption:  "caption",
+			},
+		}
+		err := manager.SaveContext(context.Background(), integrations)
+		assert.NoError(t, err)
+
+		select {
+		case message := <-sentMessage:
+			assert.Equal(t, constants.SendMessageToUser, message.EventType)
+			assert.Equal(t, Messages.UploadFileTooLarge, message.Params.Message.Text)
+		case <-time.After(time.Second):
+			t.Fatal("the file too large message was not sent")
+		}
+	})
+
+	t.Run("Should send file type not allowed message to user", func(t *testing.T) {
+		Messages = models.MessageTemplate{UploadImageError: "Imagen no enviada", UploadFileTypeNotAllowed: "Tipo de archivo no permitido"}
+		defer interconnectionLocal.Clear()
+		contextCache := new(mocks.IContextCache)
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+
+		salesforceMock.On("InsertFileInCase",
+			"http://test.com", mock.Anything, "image/png", "caseID").
+			Return(fmt.Errorf("%w: application/x-elf declared as image/png", constants.ErrFileTypeNotAllowed)).Once()
+
+		cacheMessage := new(mocks.IMessageCache)
+		cacheMessage.On("IsRepeatedMessage", messageID).Return(false).Once()
+
+		sentMessage := make(chan InterconnectionMessageQueue, 1)
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			message := InterconnectionMessageQueue{}
+			json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &message)
+			sentMessage <- message
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			contextcache:                 contextCache,
+			finishInterconnection:        make(chan *Interconnection),
+			SalesforceService:            salesforceMock,
+			cacheMessage:                 cacheMessage,
+			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
+			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
+			kafkaProducer:                producerMock,
+		}
+
+		interconnectionLocal.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{
+			Status:        Active,
+			AffinityToken: affinityToken,
+			SessionKey:    sessionKey,
+			SessionID:     sessionID,
+			UserID:        userID,
+			CaseID:        caseID,
+			finishChannel: manager.finishInterconnection,
+			kafkaProducer: producerMock,
+		}, time.Second)
+		interconnectionLocal.Wait()
+
+		manager.interconnectionMap = interconnectionLocal
+
+		integrations := &models.IntegrationsRequest{
+			ID:        messageID,
+			Timestamp: "123456789",
+			Type:      constants.ImageType,
+			From:      userID,
+			Image: models.Media{
+				URL:      "http://test.com",
+				MIMEType: "image/png",
+			},
+		}
+		err := manager.SaveContext(context.Background(), integrations)
+		assert.NoError(t, err)
+
+		select {
+		case message := <-sentMessage:
+			assert.Equal(t, constants.SendMessageToUser, message.EventType)
+			assert.Equal(t, Messages.UploadFileTypeNotAllowed, message.Params.Message.Text)
+		case <-time.After(time.Second):
+			t.Fatal("the file type not allowed message was not sent")
+		}
+	})
+
 	t.Run("Should send audio file to salesforce", func(t *testing.T) {
 		Messages = models.MessageTemplate{UploadAudioSuccess: "Audio was sent to SF, title: "}
 
@@ -2230,7 +3068,7 @@ func TestManager_SaveContext(t *testing.T) {
 			contextcache:                 contextCache,
 			finishInterconnection:        make(chan *Interconnection),
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
 			SalesforceChanRequestLimiter: rate.NewLimiter(rate.Limit(20), 21),
@@ -2269,6 +3107,34 @@ func TestManager_SaveContext(t *testing.T) {
 
 }
 
+func TestManager_getContext(t *testing.T) {
+	t.Run("Should only build the context of the proactive chats", func(t *testing.T) {
+		Messages = models.MessageTemplate{Context: "Contexto"}
+		TranscriptFormat = transcriptText
+		ContextMediaAttachments = 3
+		defer func() {
+			Messages = models.MessageTemplate{}
+			TranscriptFormat = ""
+			ContextMediaAttachments = 0
+		}()
+		contextCache := new(mocks.IContextCache)
+		contextCache.On("RetrieveContextFromSet", client, userID).Return([]cache.Context{}).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		manager := &Manager{
+			client:            client,
+			contextcache:      contextCache,
+			SalesforceService: salesforceMock,
+		}
+		interconnection := &Interconnection{UserID: userID, CaseID: caseID, proactive: true}
+
+		manager.getContext(interconnection)
+
+		assert.Equal(t, "Contexto:\n", interconnection.Context)
+		contextCache.AssertExpectations(t)
+		salesforceMock.AssertNotCalled(t, "InsertContentInCase", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 func TestManager_getContextByUserID(t *testing.T) {
 	t.Run("ContextByUserID", func(t *testing.T) {
 		salesforceMock := new(mocks.SalesforceServiceInterface)
@@ -2347,7 +3213,7 @@ second line
 			contextcache: contextCache,
 		}
 
-		ctxStr := manager.getContextByUserID(userID)
+		ctxStr := manager.getContextByUserID(userID, Messages)
 		expected := `Cliente [31-08-2021 05:00:00]:Hello
 
 Bot [31-08-2021 05:01:00]:Hello I'm a bot
@@ -2406,7 +3272,7 @@ second line
 			contextcache: contextCache,
 		}
 
-		ctxStr := manager.getContextByUserID(userID)
+		ctxStr := manager.getContextByUserID(userID, Messages)
 		expected := `Cliente [31-08-2021 07:00:00]:Hello
 
 Cliente [09-09-2021 12:45:37]:this a test second line
@@ -2414,6 +3280,124 @@ Cliente [09-09-2021 12:45:37]:this a test second line
 `
 		assert.Equal(t, expected, ctxStr)
 	})
+
+	t.Run("Should render the files of the context", func(t *testing.T) {
+		Messages = models.MessageTemplate{
+			BotLabel:      "Bot",
+			ClientLabel:   "Cliente",
+			ImageLabel:    "Imagen",
+			DocumentLabel: "Documento",
+		}
+		Timezone = "America/Mexico_City"
+		defer func() {
+			Messages = models.MessageTemplate{}
+			Timezone = ""
+		}()
+		contextCache := new(mocks.IContextCache)
+		ctx := []cache.Context{
+			{
+				UserID:    userID,
+				Client:    client,
+				Timestamp: 1630404000000,
+				Ttl:       time.Now().Add(2 * time.Minute),
+				URL:       "https://yalo.com/media/image.png",
+				MIMEType:  "image/png",
+				Caption:   "my ticket",
+				From:      fromUser,
+			},
+			{
+				UserID:    userID,
+				Client:    client,
+				Timestamp: 1630404060000,
+				Ttl:       time.Now().Add(2 * time.Minute),
+				URL:       "https://yalo.com/media/file.pdf",
+				MIMEType:  "application/pdf",
+				From:      fromBot,
+			},
+		}
+		contextCache.On("RetrieveContextFromSet", client, userID).Return(ctx)
+
+		manager := &Manager{
+			client:       client,
+			contextcache: contextCache,
+		}
+
+		ctxStr := manager.getContextByUserID(userID, Messages)
+		expected := `Cliente [31-08-2021 05:00:00]:[Imagen: my ticket] https://yalo.com/media/image.png
+
+Bot [31-08-2021 05:01:00]:[Documento] https://yalo.com/media/file.pdf
+
+`
+		assert.Equal(t, expected, ctxStr)
+	})
+}
+
+func TestManager_attachContextMedia(t *testing.T) {
+	ContextMediaAttachments = 2
+	defer func() {
+		ContextMediaAttachments = 0
+	}()
+	ctx := []cache.Context{
+		{
+			Timestamp: 1630404000000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			URL:       "https://yalo.com/media/old",
+			MIMEType:  "image/png",
+			From:      fromUser,
+		},
+		{
+			Timestamp: 1630404060000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			URL:       "https://yalo.com/media/920518159314377",
+			MIMEType:  "image/png",
+			From:      fromUser,
+		},
+		{
+			Timestamp: 1630404120000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			URL:       "https://yalo.com/media/bot",
+			MIMEType:  "image/png",
+			From:      fromBot,
+		},
+		{
+			Timestamp: 1630404180000,
+			Ttl:       time.Now().Add(2 * time.Minute * -1),
+			URL:       "https://yalo.com/media/expired",
+			MIMEType:  "image/png",
+			From:      fromUser,
+		},
+		{
+			Timestamp: 1630404240000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			URL:       "https://yalo.com/media/ticket",
+			MIMEType:  "application/pdf",
+			Caption:   "my ticket.pdf",
+			From:      fromUser,
+		},
+		{
+			Timestamp: 1630404300000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			Text:      "Hello",
+			From:      fromUser,
+		},
+	}
+	contextCache := new(mocks.IContextCache)
+	contextCache.On("RetrieveContextFromSet", client, userID).Return(ctx)
+
+	salesforceMock := new(mocks.SalesforceServiceInterface)
+	salesforceMock.On("InsertFileInCase", "https://yalo.com/media/ticket", "my ticket pdf", "application/pdf", caseID).
+		Return(nil).Once()
+	salesforceMock.On("InsertFileInCase", "https://yalo.com/media/920518159314377", "920518159314377", "image/png", caseID).
+		Return(assert.AnError).Once()
+
+	manager := &Manager{
+		client:            client,
+		contextcache:      contextCache,
+		SalesforceService: salesforceMock,
+	}
+
+	manager.attachContextMedia(&Interconnection{UserID: userID, CaseID: caseID})
+	salesforceMock.AssertExpectations(t)
 }
 
 func TestManager_GetContextByUserID(t *testing.T) {
@@ -2531,6 +3515,75 @@ second line
 	})
 }
 
+func TestManager_FilterContextByUserID(t *testing.T) {
+	ttl := time.Now().Add(2 * time.Minute)
+	ctx := []cache.Context{
+		{UserID: userID, Client: client, Ttl: ttl, Timestamp: 1630404000000, Text: "Hello", From: fromUser},
+		{UserID: userID, Client: client, Ttl: ttl, Timestamp: 1630404060000, Text: "Hello I'm a bot", From: fromBot},
+		{UserID: userID, Client: client, Ttl: ttl, Timestamp: 1630404120000, Text: "I need help", From: fromUser},
+		{UserID: userID, Client: client, Ttl: ttl, Timestamp: 1630404240000, Text: "ok.", From: fromBot},
+	}
+
+	testCases := []struct {
+		name     string
+		filter   ContextFilter
+		expected []cache.Context
+		total    int
+	}{
+		{name: "Should return all the context without filter", filter: ContextFilter{}, expected: ctx, total: 4},
+		{name: "Should filter by sender", filter: ContextFilter{Sender: fromUser}, expected: []cache.Context{ctx[0], ctx[2]}, total: 2},
+		{name: "Should filter by timestamps", filter: ContextFilter{From: 1630404060000, To: 1630404120000}, expected: ctx[1:3], total: 2},
+		{name: "Should paginate", filter: ContextFilter{Limit: 2, Offset: 1}, expected: ctx[1:3], total: 4},
+		{name: "Should return an empty page", filter: ContextFilter{Sender: fromBot, Offset: 2}, expected: []cache.Context{}, total: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			contextCache := new(mocks.IContextCache)
+			contextCache.On("RetrieveContextFromSet", client, userID).Return(append([]cache.Context{}, ctx...)).Once()
+			manager := &Manager{
+				client:       client,
+				contextcache: contextCache,
+			}
+
+			filteredContext, total := manager.FilterContextByUserID(userID, tc.filter)
+			assert.Equal(t, tc.expected, filteredContext)
+			assert.Equal(t, tc.total, total)
+		})
+	}
+}
+
+func TestManager_RenderContextByUserID(t *testing.T) {
+	Timezone = "UTC"
+	Messages = models.MessageTemplate{ClientLabel: "Cliente", BotLabel: "Bot"}
+	defer func() {
+		Messages = models.MessageTemplate{}
+		MessagesByBot = nil
+	}()
+	ctx := []cache.Context{
+		{UserID: userID, Ttl: time.Now().Add(time.Minute), Timestamp: 1630404000000, Text: "Hello", From: fromUser},
+		{UserID: userID, Ttl: time.Now().Add(time.Minute), Timestamp: 1630404060000, Text: "Hello I'm a bot", From: fromBot},
+	}
+
+	t.Run("Should render with the default messages", func(t *testing.T) {
+		manager := &Manager{interconnectionMap: cache.New()}
+
+		expected := "Cliente [31-08-2021 10:00:00]:Hello\n\nBot [31-08-2021 10:01:00]:Hello I'm a bot\n\n"
+		assert.Equal(t, expected, manager.RenderContextByUserID(userID, ctx))
+	})
+
+	t.Run("Should render with the messages of the bot of the chat", func(t *testing.T) {
+		MessagesByBot = models.MessageTemplates{"bot-slug": {ClientLabel: "Client"}}
+		interconnections := cache.New()
+		interconnections.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{UserID: userID, BotSlug: "bot-slug"}, ttlMessage)
+		interconnections.Wait()
+		manager := &Manager{interconnectionMap: interconnections}
+
+		expected := "Client [31-08-2021 10:00:00]:Hello\n\nBot [31-08-2021 10:01:00]:Hello I'm a bot\n\n"
+		assert.Equal(t, expected, manager.RenderContextByUserID(userID, ctx))
+	})
+}
+
 func TestManager_SaveContextFB(t *testing.T) {
 	interconnectionLocal := cache.New()
 	t.Run("Should save context text from user", func(t *testing.T) {
@@ -2744,7 +3797,7 @@ func TestManager_SaveContextFB(t *testing.T) {
 			client:                       client,
 			contextcache:                 contextCache,
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			finishInterconnection:        make(chan *Interconnection),
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -2822,7 +3875,7 @@ func TestManager_SaveContextFB(t *testing.T) {
 		manager := &Manager{
 			contextcache:                 contextCache,
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			finishInterconnection:        make(chan *Interconnection),
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -2910,7 +3963,7 @@ func TestManager_SaveContextFB(t *testing.T) {
 		manager := &Manager{
 			contextcache:                 contextCache,
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			finishInterconnection:        make(chan *Interconnection),
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -2989,23 +4042,9 @@ func TestManager_SaveContextFB(t *testing.T) {
 			affinityToken, sessionKey, mock.Anything).
 			Return(false, nil).Once()
 
-		cacheMock := &cache.Interconnection{
-			UserID:     userID,
-			Client:     client,
-			SessionID:  sessionID,
-			SessionKey: sessionID,
-			Status:     string(Active),
-		}
 		interconnectionCacheMock := new(mocks.IInterconnectionCache)
-		interconnectionCacheMock.On("RetrieveInterconnection",
-			cache.Interconnection{
-				UserID: userID,
-				Client: client,
-			}).
-			Return(cacheMock, nil).Once()
-		cacheMock.Status = string(Closed)
-		interconnectionCacheMock.On("StoreInterconnection", *cacheMock).
-			Return(nil).Once()
+		interconnectionCacheMock.On("FinishInterconnection", cache.Interconnection{UserID: userID, Client: client},
+			string(Closed), string(Closed), string(Failed)).Return(true, nil).Once()
 
 		cacheMessage := new(mocks.IMessageCache)
 		cacheMessage.On("IsRepeatedMessage", messageID).Return(false).Once()
@@ -3014,7 +4053,7 @@ func TestManager_SaveContextFB(t *testing.T) {
 			client:                       client,
 			contextcache:                 contextCache,
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			finishInterconnection:        make(chan *Interconnection),
 			cacheMessage:                 cacheMessage,
 			IntegrationChanRateLimiter:   rate.NewLimiter(rate.Limit(20), 21),
@@ -3073,6 +4112,10 @@ func TestManager_SaveContextFB(t *testing.T) {
 		err := manager.SaveContextFB(context.Background(), integrations)
 
 		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			_, ok := interconnectionLocal.Get(fmt.Sprintf(constants.UserKey, userID))
+			return !ok
+		}, time.Second, 10*time.Millisecond)
 	})
 
 	t.Run("Should interaction image from user error", func(t *testing.T) {
@@ -3109,7 +4152,7 @@ func TestManager_SaveContextFB(t *testing.T) {
 		manager := &Manager{
 			contextcache:                 contextCache,
 			SalesforceService:            salesforceMock,
-			keywordsRestart:              []string{"restart", "test"},
+			endUserCommands:              envs.EndUserCommands{"restart": {}, "test": {}},
 			finishInterconnection:        make(chan *Interconnection),
 			IntegrationsClient:           integrationsIMock,
 			cacheMessage:                 cacheMessage,
@@ -3544,6 +4587,27 @@ func TestManager_sendMessageToSalesforce(t *testing.T) {
 		}
 	})
 
+	t.Run("Should send a long message in parts", func(t *testing.T) {
+		MessageLimits = map[string]int{salesforceDestination: 16}
+		SplitMessageMarkers = true
+		defer func() {
+			MessageLimits = nil
+			SplitMessageMarkers = false
+		}()
+
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("SendMessage", mock.Anything, message.AffinityToken, message.SessionKey, chat.MessagePayload{Text: "(1/2) Hola test"}).Return(true, nil).Once()
+		salesforceServiceMock.On("SendMessage", mock.Anything, message.AffinityToken, message.SessionKey, chat.MessagePayload{Text: "(2/2) de mensaje"}).Return(true, nil).Once()
+		manager := Manager{
+			SalesforceService: salesforceServiceMock,
+		}
+
+		message := *message
+		message.Text = "Hola test de mensaje"
+		manager.sendMessageToSalesforce(&message)
+		salesforceServiceMock.AssertExpectations(t)
+	})
+
 }
 
 func TestManager_sendMessageToUser(t *testing.T) {
@@ -3616,7 +4680,79 @@ func TestManager_sendMessageToUser(t *testing.T) {
 		}
 	})
 
+	t.Run("Should send a long message in parts whats", func(t *testing.T) {
+		MessageLimits = map[string]int{string(WhatsappProvider): 5}
+		defer func() {
+			MessageLimits = nil
+		}()
+
+		var sentMessages []integrations.SendTextPayload
+		integrationsClient := new(mocks.IntegrationInterface)
+		integrationsClient.On("SendMessage", mock.Anything, string(message.Provider)).Run(func(args mock.Arguments) {
+			sentMessages = append(sentMessages, args.Get(0).(integrations.SendTextPayload))
+		}).Return(&integrations.SendMessageResponse{}, nil).Twice()
+		manager := Manager{
+			IntegrationsClient: integrationsClient,
+		}
+
+		message := *message
+		message.ID = messageID
+		manager.sendMessageToUser(&message)
+
+		assert.Len(t, sentMessages, 2)
+		assert.Equal(t, messageID, sentMessages[0].Id)
+		assert.Equal(t, "Hola", sentMessages[0].Text.Body)
+		assert.Equal(t, messageID+"-1", sentMessages[1].Id)
+		assert.Equal(t, "test", sentMessages[1].Text.Body)
+	})
+
+	t.Run("Should send a media with its caption whats", func(t *testing.T) {
+		integrationsClient := new(mocks.IntegrationInterface)
+		integrationsClient.On("SendMessage", integrations.SendImagePayload{
+			ID:     messageID,
+			Type:   ImageMedia,
+			UserID: userID,
+			Image:  integrations.Media{Url: "https://example.com/image.png", Caption: "Hola test"},
+		}, string(WhatsappProvider)).Return(&integrations.SendMessageResponse{}, nil).Once()
+		manager := Manager{
+			IntegrationsClient: integrationsClient,
+		}
+
+		message := *message
+		message.ID = messageID
+		message.MediaURL = "https://example.com/image.png"
+		message.MediaType = ImageMedia
+		manager.sendMessageToUser(&message)
+
+		integrationsClient.AssertExpectations(t)
+	})
+
 	message.Provider = FacebookProvider
+	t.Run("Should send a media and then its text fb", func(t *testing.T) {
+		var sentMessages []integrations.SendTextPayloadFB
+		integrationsClient := new(mocks.IntegrationInterface)
+		integrationsClient.On("SendMessage", mock.Anything, string(FacebookProvider)).Run(func(args mock.Arguments) {
+			sentMessages = append(sentMessages, args.Get(0).(integrations.SendTextPayloadFB))
+		}).Return(&integrations.SendMessageResponse{}, nil).Twice()
+		manager := Manager{
+			IntegrationsClient: integrationsClient,
+		}
+
+		message := *message
+		message.MediaURL = "https://example.com/file.pdf"
+		message.MediaType = DocumentMedia
+		manager.sendMessageToUser(&message)
+
+		assert.Len(t, sentMessages, 2)
+		assert.Equal(t, &integrations.Attachment{
+			Type:    "file",
+			Payload: integrations.AttachmentPayload{Url: "https://example.com/file.pdf"},
+		}, sentMessages[0].Message.Attachment)
+		assert.Empty(t, sentMessages[0].Message.Text)
+		assert.Nil(t, sentMessages[1].Message.Attachment)
+		assert.Equal(t, "Hola test", sentMessages[1].Message.Text)
+	})
+
 	t.Run("Should sent message fb", func(t *testing.T) {
 		expectedLog := "Send message to UserID"
 		integrationsClient := new(mocks.IntegrationInterface)
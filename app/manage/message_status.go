@@ -0,0 +1,127 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/integrations"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/metrics"
+	"yalochat.com/salesforce-integration/base/models"
+)
+
+// storeMessageDeliveries saves the IDs that Integrations returned for the message sent to the user with the session
+// of its chat, so the status updates of the message can be matched
+func (m *Manager) storeMessageDeliveries(message *Message, response *integrations.SendMessageResponse) {
+	if m.messageDeliveryCache == nil || response == nil {
+		return
+	}
+
+	sessionID := ""
+	if m.interconnectionMap != nil {
+		if interconnection, ok := m.validInterconnection(message.UserID); ok {
+			sessionID = interconnection.SessionID
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, sent := range response.Messages {
+		if sent.Id == "" {
+			continue
+		}
+		delivery := cache.MessageDelivery{
+			ID:        sent.Id,
+			MessageID: message.ID,
+			Client:    m.client,
+			UserID:    message.UserID,
+			SessionID: sessionID,
+			Provider:  string(message.Provider),
+			Status:    cache.MessageAccepted,
+			SentAt:    now,
+			UpdatedAt: now,
+		}
+		if err := m.messageDeliveryCache.StoreMessageDelivery(delivery, m.messageDeliveryTTL); err != nil {
+			logrus.WithFields(logrus.Fields{
+				events.UserID: message.UserID,
+				"messageId":   message.ID,
+			}).WithError(err).Error("Could not store the message delivery")
+		}
+	}
+}
+
+// UpdateMessageStatus saves the status of the message sent to the user, the statuses that arrive after a later
+// status and the statuses of unknown messages are skipped. The agent is told when the message could not be delivered
+func (m *Manager) UpdateMessageStatus(ctx context.Context, integration *models.IntegrationsRequest) error {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "manager.UpdateMessageStatus")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, integration.From)
+	span.SetTag("messageId", integration.ID)
+	span.SetTag("status", integration.Status)
+	defer span.Finish()
+
+	logFields := logrus.Fields{
+		constants.TraceIdKey: span.Context().TraceID(),
+		constants.SpanIdKey:  span.Context().SpanID(),
+		events.UserID:        integration.From,
+		"messageId":          integration.ID,
+		"status":             integration.Status,
+	}
+	if m.messageDeliveryCache == nil {
+		logrus.WithFields(logFields).Error("Could not update the status of the message without redis")
+		return nil
+	}
+
+	delivery, err := m.messageDeliveryCache.RetrieveMessageDelivery(m.client, integration.ID)
+	if errors.Is(err, constants.ErrMessageDeliveryNotFound) {
+		logrus.WithFields(logFields).Info("Status of a message that was not sent by the integration")
+		return nil
+	}
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		return err
+	}
+
+	status := cache.MessageDeliveryStatus(integration.Status)
+	if !status.Follows(delivery.Status) {
+		logrus.WithFields(logFields).Infof("Status skipped, the message is %s", delivery.Status)
+		return nil
+	}
+
+	delivery.Status = status
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := m.messageDeliveryCache.StoreMessageDelivery(*delivery, m.messageDeliveryTTL); err != nil {
+		span.SetTag(ext.Error, err)
+		return err
+	}
+	metrics.MessageStatuses.WithLabelValues(delivery.Provider, string(status)).Inc()
+	logrus.WithFields(logFields).Info("Status of the message updated")
+
+	if status == cache.MessageFailed {
+		m.reportUndeliveredMessage(span, delivery)
+	}
+	return nil
+}
+
+// reportUndeliveredMessage tells the agent of the chat where the message was sent that it was not delivered,
+// nothing is sent when the chat already ended
+func (m *Manager) reportUndeliveredMessage(span tracer.Span, delivery *cache.MessageDelivery) {
+	interconnection, ok := m.validInterconnection(delivery.UserID)
+	if !ok || interconnection.Status != Active ||
+		(delivery.SessionID != "" && delivery.SessionID != interconnection.SessionID) {
+		return
+	}
+
+	text := interconnection.renderMessage(span, interconnection.messages().MessageNotDelivered, interconnection.templateVariables())
+	if text == "" {
+		return
+	}
+	interconnection.sendMessageToQueue(span, helpers.RandomString(36), text, constants.SendMessageToSalesforce)
+}
@@ -0,0 +1,217 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/integrations"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/metrics"
+	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+func TestManager_storeMessageDeliveries(t *testing.T) {
+	message := &Message{ID: "messageID", UserID: userID, Provider: FacebookProvider}
+
+	t.Run("Should store the IDs returned by Integrations", func(t *testing.T) {
+		interconnectionMap := cache.New()
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{UserID: userID, SessionID: sessionID}, 0)
+		interconnectionMap.Wait()
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		for _, id := range []string{"wamid.1", "wamid.2"} {
+			id := id
+			deliveryCacheMock.On("StoreMessageDelivery", mock.MatchedBy(func(delivery cache.MessageDelivery) bool {
+				return delivery.ID == id && delivery.MessageID == message.ID && delivery.Client == client &&
+					delivery.UserID == userID && delivery.SessionID == sessionID && delivery.Provider == string(FacebookProvider) &&
+					delivery.Status == cache.MessageAccepted
+			}), time.Hour).Return(nil).Once()
+		}
+
+		manager := &Manager{
+			client:               client,
+			interconnectionMap:   interconnectionMap,
+			messageDeliveryCache: deliveryCacheMock,
+			messageDeliveryTTL:   time.Hour,
+		}
+
+		manager.storeMessageDeliveries(message, &integrations.SendMessageResponse{
+			Messages: []integrations.MessageId{{Id: "wamid.1"}, {Id: ""}, {Id: "wamid.2"}},
+		})
+
+		deliveryCacheMock.AssertExpectations(t)
+		deliveryCacheMock.AssertNumberOfCalls(t, "StoreMessageDelivery", 2)
+	})
+
+	t.Run("Should not store without response", func(t *testing.T) {
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock}
+
+		manager.storeMessageDeliveries(message, nil)
+
+		deliveryCacheMock.AssertNotCalled(t, "StoreMessageDelivery", mock.Anything, mock.Anything)
+	})
+}
+
+func TestManager_UpdateMessageStatus(t *testing.T) {
+	Messages = models.MessageTemplate{MessageNotDelivered: "El mensaje no fue entregado"}
+	defer func() {
+		Messages = models.MessageTemplate{}
+	}()
+	newDelivery := func(status cache.MessageDeliveryStatus) *cache.MessageDelivery {
+		return &cache.MessageDelivery{
+			ID:        "wamid.1",
+			MessageID: "messageID",
+			Client:    client,
+			UserID:    userID,
+			SessionID: sessionID,
+			Status:    status,
+		}
+	}
+	newRequest := func(status string) *models.IntegrationsRequest {
+		return &models.IntegrationsRequest{
+			ID:     "wamid.1",
+			Type:   constants.StatusType,
+			From:   userID,
+			Status: status,
+		}
+	}
+
+	t.Run("Should update the status that follows the current one", func(t *testing.T) {
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(newDelivery(cache.MessageSent), nil).Once()
+		deliveryCacheMock.On("StoreMessageDelivery", mock.MatchedBy(func(delivery cache.MessageDelivery) bool {
+			return delivery.ID == "wamid.1" && delivery.Status == cache.MessageDelivered
+		}), time.Hour).Return(nil).Once()
+
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock, messageDeliveryTTL: time.Hour}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("delivered"))
+
+		assert.NoError(t, err)
+		deliveryCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should count the status with the provider of the message", func(t *testing.T) {
+		delivery := newDelivery(cache.MessageSent)
+		delivery.Provider = string(FacebookProvider)
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").Return(delivery, nil).Once()
+		deliveryCacheMock.On("StoreMessageDelivery", mock.Anything, mock.Anything).Return(nil).Once()
+		count := func() float64 {
+			return testutil.ToFloat64(metrics.MessageStatuses.WithLabelValues(string(FacebookProvider), "read"))
+		}
+		before := count()
+
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("read"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, before+1, count())
+	})
+
+	t.Run("Should skip the status older than the current one", func(t *testing.T) {
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(newDelivery(cache.MessageRead), nil).Once()
+
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("delivered"))
+
+		assert.NoError(t, err)
+		deliveryCacheMock.AssertNotCalled(t, "StoreMessageDelivery", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should skip the status of an unknown message", func(t *testing.T) {
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(nil, constants.ErrMessageDeliveryNotFound).Once()
+
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("read"))
+
+		assert.NoError(t, err)
+		deliveryCacheMock.AssertNotCalled(t, "StoreMessageDelivery", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return the error of the cache", func(t *testing.T) {
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(nil, assert.AnError).Once()
+
+		manager := &Manager{client: client, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("read"))
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("Should tell the agent that the message was not delivered", func(t *testing.T) {
+		var sentMessages []InterconnectionMessageQueue
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			message := InterconnectionMessageQueue{}
+			json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &message)
+			sentMessages = append(sentMessages, message)
+		}).Return(nil).Once()
+		interconnectionMap := cache.New()
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{
+			UserID:        userID,
+			SessionID:     sessionID,
+			Status:        Active,
+			kafkaProducer: producerMock,
+		}, 0)
+		interconnectionMap.Wait()
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(newDelivery(cache.MessageSent), nil).Once()
+		deliveryCacheMock.On("StoreMessageDelivery", mock.MatchedBy(func(delivery cache.MessageDelivery) bool {
+			return delivery.Status == cache.MessageFailed
+		}), mock.Anything).Return(nil).Once()
+
+		manager := &Manager{client: client, interconnectionMap: interconnectionMap, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("failed"))
+
+		assert.NoError(t, err)
+		assert.Len(t, sentMessages, 1)
+		assert.Equal(t, "El mensaje no fue entregado", sentMessages[0].Params.Message.Text)
+		assert.Equal(t, constants.SendMessageToSalesforce, sentMessages[0].EventType)
+		producerMock.AssertExpectations(t)
+	})
+
+	t.Run("Shouldn't tell the agent of another chat that the message was not delivered", func(t *testing.T) {
+		producerMock := new(mocks.Producer)
+		interconnectionMap := cache.New()
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), &Interconnection{
+			UserID:        userID,
+			SessionID:     "otherSessionID",
+			Status:        Active,
+			kafkaProducer: producerMock,
+		}, 0)
+		interconnectionMap.Wait()
+		deliveryCacheMock := new(mocks.IMessageDeliveryCache)
+		deliveryCacheMock.On("RetrieveMessageDelivery", client, "wamid.1").
+			Return(newDelivery(cache.MessageAccepted), nil).Once()
+		deliveryCacheMock.On("StoreMessageDelivery", mock.Anything, mock.Anything).Return(nil).Once()
+
+		manager := &Manager{client: client, interconnectionMap: interconnectionMap, messageDeliveryCache: deliveryCacheMock}
+
+		err := manager.UpdateMessageStatus(context.Background(), newRequest("failed"))
+
+		assert.NoError(t, err)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+}
@@ -0,0 +1,86 @@
+package manage
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/metrics"
+)
+
+// Reasons of the chats that could not be created
+const (
+	sessionExistsReason = "session_exists"
+	contactReason       = "contact"
+	blockedReason       = "blocked"
+	caseReason          = "case"
+	chatReason          = "chat"
+)
+
+// Results of the file uploads besides success and failure
+const (
+	fileTooLargeResult       = "too_large"
+	fileTypeNotAllowedResult = "type_not_allowed"
+)
+
+// interconnectionsCollector collects the number of interconnections of the client in redis on each scrape
+type interconnectionsCollector struct {
+	manager *Manager
+}
+
+// MetricsCollector returns the collector of the interconnections of the manager
+func (m *Manager) MetricsCollector() prometheus.Collector {
+	return &interconnectionsCollector{manager: m}
+}
+
+func (c *interconnectionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.Interconnections
+}
+
+func (c *interconnectionsCollector) Collect(ch chan<- prometheus.Metric) {
+	interconnections := c.manager.interconnectionsCache.RetrieveAllInterconnections(c.manager.client)
+	if interconnections == nil {
+		return
+	}
+
+	counts := map[[3]string]int{}
+	for _, interconnection := range *interconnections {
+		counts[[3]string{interconnection.Provider, interconnection.BotSlug, interconnection.Status}]++
+	}
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(metrics.Interconnections, prometheus.GaugeValue, float64(count),
+			labels[0], labels[1], labels[2])
+	}
+}
+
+// chatFailed counts the chat that could not be created by the step that failed
+func chatFailed(interconnection *Interconnection, reason string) {
+	metrics.ChatsFailed.WithLabelValues(string(interconnection.Provider), interconnection.BotSlug, reason).Inc()
+}
+
+// messageLabels returns the labels of the message, the provider and the bot come from its interconnection
+func (m *Manager) messageLabels(direction string, message *Message) []string {
+	provider, botSlug := string(message.Provider), ""
+	if m.interconnectionMap == nil {
+		return []string{direction, provider, botSlug}
+	}
+	if interconnection, ok := m.validInterconnection(message.UserID); ok {
+		botSlug = interconnection.BotSlug
+		if provider == "" {
+			provider = string(interconnection.Provider)
+		}
+	}
+	return []string{direction, provider, botSlug}
+}
+
+// fileUploaded counts the file of the user uploaded to the case by result
+func fileUploaded(interconnection *Interconnection, err error) {
+	result := metrics.Result(err)
+	switch {
+	case errors.Is(err, constants.ErrFileTooLarge):
+		result = fileTooLargeResult
+	case errors.Is(err, constants.ErrFileTypeNotAllowed):
+		result = fileTypeNotAllowedResult
+	}
+	metrics.FileUploads.WithLabelValues(string(interconnection.Provider), interconnection.BotSlug, result).Inc()
+}
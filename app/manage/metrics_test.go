@@ -0,0 +1,81 @@
+package manage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/metrics"
+)
+
+func TestManager_MetricsCollector(t *testing.T) {
+	t.Run("Should collect the interconnections by status", func(t *testing.T) {
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveAllInterconnections", client).Return(&[]cache.Interconnection{
+			{UserID: "1", Provider: provider, BotSlug: botSlug, Status: string(Active)},
+			{UserID: "2", Provider: provider, BotSlug: botSlug, Status: string(Active)},
+			{UserID: "3", Provider: provider, BotSlug: botSlug, Status: string(OnHold)},
+		}).Once()
+		manager := &Manager{client: client, interconnectionsCache: interconnectionMock}
+
+		expected := fmt.Sprintf(`
+# HELP salesforce_integration_interconnections Interconnections stored in redis, by status.
+# TYPE salesforce_integration_interconnections gauge
+salesforce_integration_interconnections{bot_slug="%[1]s",provider="%[2]s",status="ACTIVE"} 2
+salesforce_integration_interconnections{bot_slug="%[1]s",provider="%[2]s",status="ON_HOLD"} 1
+`, botSlug, provider)
+		err := testutil.CollectAndCompare(manager.MetricsCollector(), strings.NewReader(expected))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should not collect the interconnections when redis fails", func(t *testing.T) {
+		interconnectionMock := new(mocks.IInterconnectionCache)
+		interconnectionMock.On("RetrieveAllInterconnections", client).Return(nil).Once()
+		manager := &Manager{client: client, interconnectionsCache: interconnectionMock}
+
+		assert.Equal(t, 0, testutil.CollectAndCount(manager.MetricsCollector()))
+	})
+}
+
+func TestManager_messageLabels(t *testing.T) {
+	manager := &Manager{interconnectionMap: cache.New()}
+	manager.interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID),
+		&Interconnection{UserID: userID, BotSlug: botSlug, Provider: WhatsappProvider}, 0)
+	manager.interconnectionMap.Wait()
+
+	t.Run("Should take the provider and the bot of the interconnection", func(t *testing.T) {
+		labels := manager.messageLabels(metrics.ToSalesforce, &Message{UserID: userID})
+
+		assert.Equal(t, []string{metrics.ToSalesforce, string(WhatsappProvider), botSlug}, labels)
+	})
+
+	t.Run("Should keep the provider of the message without interconnection", func(t *testing.T) {
+		labels := manager.messageLabels(metrics.ToUser, &Message{UserID: "unknown", Provider: FacebookProvider})
+
+		assert.Equal(t, []string{metrics.ToUser, string(FacebookProvider), ""}, labels)
+	})
+}
+
+func TestFileUploaded(t *testing.T) {
+	interconnection := &Interconnection{Provider: FacebookProvider, BotSlug: "metrics-bot"}
+	count := func(result string) float64 {
+		return testutil.ToFloat64(metrics.FileUploads.WithLabelValues(string(FacebookProvider), "metrics-bot", result))
+	}
+
+	fileUploaded(interconnection, nil)
+	fileUploaded(interconnection, constants.ErrFileTooLarge)
+	fileUploaded(interconnection, constants.ErrFileTypeNotAllowed)
+	fileUploaded(interconnection, errors.New("not insert file"))
+
+	assert.Equal(t, float64(1), count(metrics.Success))
+	assert.Equal(t, float64(1), count(fileTooLargeResult))
+	assert.Equal(t, float64(1), count(fileTypeNotAllowedResult))
+	assert.Equal(t, float64(1), count(metrics.Failure))
+}
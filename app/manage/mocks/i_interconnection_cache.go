@@ -47,6 +47,34 @@ func (_m *IInterconnectionCache) DeleteInterconnection(_a0 cache.Interconnection
 	return r0, r1
 }
 
+// FinishInterconnection provides a mock function with given fields: interconnection, status, finishedStatuses
+func (_m *IInterconnectionCache) FinishInterconnection(interconnection cache.Interconnection, status string, finishedStatuses ...string) (bool, error) {
+	_va := make([]interface{}, len(finishedStatuses))
+	for _i := range finishedStatuses {
+		_va[_i] = finishedStatuses[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, interconnection, status)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(cache.Interconnection, string, ...string) bool); ok {
+		r0 = rf(interconnection, status, finishedStatuses...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cache.Interconnection, string, ...string) error); ok {
+		r1 = rf(interconnection, status, finishedStatuses...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RetrieveAllInterconnections provides a mock function with given fields: client
 func (_m *IInterconnectionCache) RetrieveAllInterconnections(client string) *[]cache.Interconnection {
 	ret := _m.Called(client)
@@ -12,6 +12,20 @@ type IntegrationInterface struct {
 	mock.Mock
 }
 
+// Ping provides a mock function with given fields:
+func (_m *IntegrationInterface) Ping() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SendMessage provides a mock function with given fields: messagePayload, provider
 func (_m *IntegrationInterface) SendMessage(messagePayload interface{}, provider string) (*integrations.SendMessageResponse, error) {
 	ret := _m.Called(messagePayload, provider)
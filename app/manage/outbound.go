@@ -0,0 +1,184 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+// Media types that can be sent to the user
+const (
+	ImageMedia    = "image"
+	VideoMedia    = "video"
+	AudioMedia    = "audio"
+	DocumentMedia = "document"
+)
+
+// OutboundMessage is a message sent to the user outside a chat, e.g. from a Salesforce Flow.
+// The user is found by UserID or, in whatsapp, by PhoneNumber, and the case by CaseID or the last chat of the user
+type OutboundMessage struct {
+	UserID      string
+	PhoneNumber string
+	Provider    Provider
+	BotSlug     string
+	CaseID      string
+	Text        string
+	MediaURL    string
+	MediaType   string
+}
+
+// OutboundMessageResult is the message queued to be sent to the user
+type OutboundMessageResult struct {
+	ID       string   `json:"id"`
+	UserID   string   `json:"userId"`
+	Provider Provider `json:"provider"`
+	CaseID   string   `json:"caseId"`
+}
+
+// SendMessageToUser logs the message in the case and queues it to be sent to the user
+func (m *Manager) SendMessageToUser(ctx context.Context, message OutboundMessage) (*OutboundMessageResult, error) {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "manager.SendMessageToUser")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.Provider, message.Provider)
+	span.SetTag("botSlug", message.BotSlug)
+	defer span.Finish()
+
+	userID, err := outboundUserID(message)
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		return nil, err
+	}
+	message.UserID = userID
+	span.SetTag(events.UserID, userID)
+
+	if m.kafkaProducer == nil {
+		err := fmt.Errorf("could not send the message to the user %s, kafka is not configured", userID)
+		span.SetTag(ext.Error, err)
+		return nil, err
+	}
+
+	if message.CaseID == "" {
+		status, err := m.GetChatStatus(userID)
+		if err != nil && !errors.Is(err, constants.ErrInterconnectionNotFound) {
+			span.SetTag(ext.Error, err)
+			return nil, err
+		}
+		if status == nil || status.CaseID == "" {
+			err := fmt.Errorf("%w for the user %s", constants.ErrCaseNotFound, userID)
+			span.SetTag(ext.Error, err)
+			return nil, err
+		}
+		message.CaseID = status.CaseID
+	}
+	span.SetTag("caseId", message.CaseID)
+
+	messages := MessagesByBot.Get(Messages, message.BotSlug, "")
+	if err := m.SalesforceService.InsertCommentInCase(ctx, message.CaseID, outboundMessageComment(message, messages)); err != nil {
+		span.SetTag(ext.Error, err)
+		return nil, fmt.Errorf("could not log the message in the case %s: %w", message.CaseID, err)
+	}
+
+	result := &OutboundMessageResult{
+		ID:       helpers.RandomString(36),
+		UserID:   userID,
+		Provider: message.Provider,
+		CaseID:   message.CaseID,
+	}
+
+	queueMessage := InterconnectionMessageQueue{
+		ID:        result.ID,
+		EventType: constants.SendMessageToUser,
+		Params: MessageQueue{
+			Client: m.client,
+			Message: Message{
+				Text:      message.Text,
+				MediaURL:  message.MediaURL,
+				MediaType: message.MediaType,
+				UserID:    userID,
+				Provider:  message.Provider,
+			},
+		},
+		TraceID: strconv.FormatUint(span.Context().TraceID(), 10),
+	}
+	messageBin, _ := json.Marshal(queueMessage)
+	span.SetTag(events.MessageKafka, queueMessage)
+
+	err = m.kafkaProducer.SendMessage(kafka.KafkaMessageParams{
+		Topic: m.KafkaTopic,
+		Msg:   messageBin,
+		Key:   constants.DefaultKey,
+	})
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		return nil, fmt.Errorf("could not send the message to kafka: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		events.UserID: userID,
+		"caseId":      message.CaseID,
+		"messageId":   result.ID,
+	}).Info("Message to the user queued")
+	return result, nil
+}
+
+// outboundUserID returns the userID of the message, in whatsapp the userID is the phone number without symbols
+func outboundUserID(message OutboundMessage) (string, error) {
+	if message.Provider != WhatsappProvider && message.Provider != FacebookProvider {
+		return "", fmt.Errorf("%w: provider %s is not supported", constants.ErrInvalidMessage, message.Provider)
+	}
+
+	if message.UserID != "" {
+		return message.UserID, nil
+	}
+
+	if message.Provider != WhatsappProvider {
+		return "", fmt.Errorf("%w: the userID is required for %s", constants.ErrInvalidMessage, message.Provider)
+	}
+
+	userID := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, message.PhoneNumber)
+	if userID == "" {
+		return "", fmt.Errorf("%w: the userID or the phoneNumber is required", constants.ErrInvalidMessage)
+	}
+	return userID, nil
+}
+
+// outboundMessageComment returns the message as the agents see the context, e.g. "Bot [31-08-2021 05:00:00]:Hola"
+func outboundMessageComment(message OutboundMessage, messages models.MessageTemplate) string {
+	text := message.Text
+	if message.MediaURL != "" {
+		label := messages.DocumentLabel
+		switch message.MediaType {
+		case ImageMedia:
+			label = messages.ImageLabel
+		case AudioMedia:
+			label = messages.AudioLabel
+		}
+
+		text = fmt.Sprintf("[%s] %s", label, message.MediaURL)
+		if message.Text != "" {
+			text = fmt.Sprintf("%s\n%s", text, message.Text)
+		}
+	}
+
+	loc, _ := time.LoadLocation(Timezone)
+	return fmt.Sprintf("%s [%s]:%s", messages.BotLabel, time.Now().In(loc).Format(constants.DateFormat), text)
+}
@@ -0,0 +1,222 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+func TestManager_SendMessageToUser(t *testing.T) {
+	Messages = models.MessageTemplate{BotLabel: "Bot", ImageLabel: "Imagen"}
+	defer func() {
+		Messages = models.MessageTemplate{}
+	}()
+
+	t.Run("Should log the message in the case and send it to kafka", func(t *testing.T) {
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("InsertCommentInCase", mock.Anything, caseID, mock.MatchedBy(func(comment string) bool {
+			return strings.HasPrefix(comment, "Bot [") && strings.HasSuffix(comment, "]:Your case was resolved")
+		})).Return(nil).Once()
+
+		var queueMessage InterconnectionMessageQueue
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			params := args.Get(0).(kafka.KafkaMessageParams)
+			assert.Equal(t, "topic", params.Topic)
+			assert.NoError(t, json.Unmarshal(params.Msg, &queueMessage))
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			client:            client,
+			SalesforceService: salesforceServiceMock,
+			kafkaProducer:     producerMock,
+			KafkaTopic:        "topic",
+		}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			UserID:   userID,
+			Provider: WhatsappProvider,
+			BotSlug:  botSlug,
+			CaseID:   caseID,
+			Text:     "Your case was resolved",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, userID, result.UserID)
+		assert.Equal(t, caseID, result.CaseID)
+		assert.Equal(t, result.ID, queueMessage.ID)
+		assert.Equal(t, constants.SendMessageToUser, queueMessage.EventType)
+		assert.Equal(t, client, queueMessage.Params.Client)
+		assert.Equal(t, userID, queueMessage.Params.UserID)
+		assert.Equal(t, "Your case was resolved", queueMessage.Params.Text)
+		assert.Equal(t, WhatsappProvider, queueMessage.Params.Provider)
+		salesforceServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("Should use the phone number and the case of the last chat in whatsapp", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: "5217331175599", Client: client}).
+			Return(&cache.Interconnection{UserID: "5217331175599", Client: client, Status: string(Closed), CaseID: caseID}, nil).Once()
+
+		mediaComment := "[Imagen] https://example.com/image.png\nResolved"
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("InsertCommentInCase", mock.Anything, caseID, mock.MatchedBy(func(comment string) bool {
+			return strings.HasSuffix(comment, mediaComment)
+		})).Return(nil).Once()
+
+		var queueMessage InterconnectionMessageQueue
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &queueMessage))
+		}).Return(nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			SalesforceService:     salesforceServiceMock,
+			kafkaProducer:         producerMock,
+		}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			PhoneNumber: "+52 1 733 117 5599",
+			Provider:    WhatsappProvider,
+			BotSlug:     botSlug,
+			Text:        "Resolved",
+			MediaURL:    "https://example.com/image.png",
+			MediaType:   ImageMedia,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "5217331175599", result.UserID)
+		assert.Equal(t, caseID, result.CaseID)
+		assert.Equal(t, "https://example.com/image.png", queueMessage.Params.MediaURL)
+		assert.Equal(t, ImageMedia, queueMessage.Params.MediaType)
+		salesforceServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("Should return an error when there is no case", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(nil, constants.ErrInterconnectionNotFound).Once()
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		producerMock := new(mocks.Producer)
+
+		manager := &Manager{
+			client:                client,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			SalesforceService:     salesforceServiceMock,
+			kafkaProducer:         producerMock,
+		}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			UserID:   userID,
+			Provider: FacebookProvider,
+			BotSlug:  botSlug,
+			Text:     "Hola",
+		})
+
+		assert.ErrorIs(t, err, constants.ErrCaseNotFound)
+		assert.Nil(t, result)
+		salesforceServiceMock.AssertNotCalled(t, "InsertCommentInCase", mock.Anything, mock.Anything, mock.Anything)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+
+	t.Run("Should not send the message when it is not logged in the case", func(t *testing.T) {
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("InsertCommentInCase", mock.Anything, caseID, mock.Anything).Return(assert.AnError).Once()
+		producerMock := new(mocks.Producer)
+
+		manager := &Manager{
+			SalesforceService: salesforceServiceMock,
+			kafkaProducer:     producerMock,
+		}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			UserID:   userID,
+			Provider: WhatsappProvider,
+			BotSlug:  botSlug,
+			CaseID:   caseID,
+			Text:     "Hola",
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, result)
+		producerMock.AssertNotCalled(t, "SendMessage", mock.Anything)
+	})
+
+	t.Run("Should return an error when kafka fails", func(t *testing.T) {
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("InsertCommentInCase", mock.Anything, caseID, mock.Anything).Return(nil).Once()
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Return(assert.AnError).Once()
+
+		manager := &Manager{
+			SalesforceService: salesforceServiceMock,
+			kafkaProducer:     producerMock,
+		}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			UserID:   userID,
+			Provider: WhatsappProvider,
+			BotSlug:  botSlug,
+			CaseID:   caseID,
+			Text:     "Hola",
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, result)
+	})
+
+	t.Run("Should return an error when kafka is not configured", func(t *testing.T) {
+		manager := &Manager{}
+
+		result, err := manager.SendMessageToUser(context.Background(), OutboundMessage{
+			UserID:   userID,
+			Provider: WhatsappProvider,
+			BotSlug:  botSlug,
+			CaseID:   caseID,
+			Text:     "Hola",
+		})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func Test_outboundUserID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  OutboundMessage
+		expected string
+		err      bool
+	}{
+		{name: "userID", message: OutboundMessage{UserID: userID, Provider: FacebookProvider}, expected: userID},
+		{name: "phone number in whatsapp", message: OutboundMessage{PhoneNumber: "+52 (733) 117-5599", Provider: WhatsappProvider}, expected: "527331175599"},
+		{name: "phone number in facebook", message: OutboundMessage{PhoneNumber: "5217331175599", Provider: FacebookProvider}, err: true},
+		{name: "without user", message: OutboundMessage{Provider: WhatsappProvider}, err: true},
+		{name: "unknown provider", message: OutboundMessage{UserID: userID, Provider: "telegram"}, err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			userID, err := outboundUserID(testCase.message)
+			if testCase.err {
+				assert.ErrorIs(t, err, constants.ErrInvalidMessage)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, userID)
+		})
+	}
+}
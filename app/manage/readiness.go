@@ -0,0 +1,76 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/health"
+	"yalochat.com/salesforce-integration/base/subscribers"
+)
+
+// Names of the dependencies checked by the readiness probe
+const (
+	RedisComponent        = "redis"
+	KafkaComponent        = "kafka"
+	SalesforceComponent   = "salesforce"
+	IntegrationsComponent = "integrations"
+)
+
+// CheckReadiness checks the dependencies of the app, the results are cached during the ReadinessCacheTTL
+func (m *Manager) CheckReadiness(ctx context.Context) health.Report {
+	if m.readiness == nil {
+		return health.Report{Status: health.Up, Components: map[string]health.ComponentReport{}}
+	}
+	return m.readiness.Check(ctx)
+}
+
+// readinessComponents returns the dependencies of the manager, all are critical except the nonCritical ones
+func (m *Manager) readinessComponents(redisCache *cache.RedisCache, timeout time.Duration, nonCritical []string) []health.Component {
+	components := []health.Component{
+		{
+			Name: RedisComponent,
+			Check: func(ctx context.Context) error {
+				if redisCache == nil {
+					return errors.New("redis is not configured")
+				}
+				return redisCache.Ping()
+			},
+		},
+		{
+			Name: KafkaComponent,
+			Check: func(ctx context.Context) error {
+				if m.kafkaProducer == nil {
+					return errors.New("kafka is not configured")
+				}
+				if pinger, ok := m.kafkaProducer.(subscribers.Pinger); ok {
+					return pinger.Ping(timeout)
+				}
+				return nil
+			},
+		},
+		{
+			Name: SalesforceComponent,
+			Check: func(ctx context.Context) error {
+				return m.SalesforceService.CheckToken(ctx)
+			},
+		},
+		{
+			Name: IntegrationsComponent,
+			Check: func(ctx context.Context) error {
+				return m.IntegrationsClient.Ping()
+			},
+		},
+	}
+
+	for i := range components {
+		components[i].Critical = true
+		for _, name := range nonCritical {
+			if components[i].Name == name {
+				components[i].Critical = false
+			}
+		}
+	}
+	return components
+}
@@ -0,0 +1,59 @@
+package manage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/health"
+)
+
+func TestManager_CheckReadiness(t *testing.T) {
+	t.Run("Should be up without the readiness checker", func(t *testing.T) {
+		manager := &Manager{}
+
+		report := manager.CheckReadiness(context.Background())
+
+		assert.Equal(t, health.Up, report.Status)
+	})
+
+	t.Run("Should be down when redis and kafka are not configured", func(t *testing.T) {
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("CheckToken", mock.Anything).Return(nil).Once()
+		integrationsMock := new(mocks.IntegrationInterface)
+		integrationsMock.On("Ping").Return(nil).Once()
+
+		manager := &Manager{SalesforceService: salesforceServiceMock, IntegrationsClient: integrationsMock}
+		manager.readiness = health.NewChecker(time.Second, 0, manager.readinessComponents(nil, time.Second, nil)...)
+
+		report := manager.CheckReadiness(context.Background())
+
+		assert.Equal(t, health.Down, report.Status)
+		assert.Equal(t, "redis is not configured", report.Components[RedisComponent].Error)
+		assert.Equal(t, "kafka is not configured", report.Components[KafkaComponent].Error)
+		assert.Equal(t, health.Up, report.Components[SalesforceComponent].Status)
+		assert.Equal(t, health.Up, report.Components[IntegrationsComponent].Status)
+	})
+
+	t.Run("Should be degraded when a non-critical component is down", func(t *testing.T) {
+		salesforceServiceMock := new(mocks.SalesforceServiceInterface)
+		salesforceServiceMock.On("CheckToken", mock.Anything).Return(errors.New("invalid token")).Once()
+		integrationsMock := new(mocks.IntegrationInterface)
+		integrationsMock.On("Ping").Return(nil).Once()
+
+		manager := &Manager{SalesforceService: salesforceServiceMock, IntegrationsClient: integrationsMock}
+		manager.readiness = health.NewChecker(time.Second, 0, manager.readinessComponents(nil, time.Second,
+			[]string{RedisComponent, KafkaComponent, SalesforceComponent})...)
+
+		report := manager.CheckReadiness(context.Background())
+
+		assert.Equal(t, health.Degraded, report.Status)
+		assert.False(t, report.Components[SalesforceComponent].Critical)
+		assert.Equal(t, "invalid token", report.Components[SalesforceComponent].Error)
+		assert.True(t, report.Components[IntegrationsComponent].Critical)
+	})
+}
@@ -0,0 +1,154 @@
+package manage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/models"
+)
+
+const (
+	transcriptText         = "txt"
+	transcriptHTML         = "html"
+	defaultTranscriptTitle = "Bot transcript"
+)
+
+var transcriptHTMLTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Name}}{{if .CaseNumber}} - {{.CaseNumber}}{{end}}</p>
+<table>
+{{- range .Entries}}
+<tr><td>{{.Label}}</td><td>{{.Date}}</td><td style="white-space: pre-wrap">{{.Text}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// transcript is the conversation of the user with the bot before the chat with the agent
+type transcript struct {
+	Title      string
+	Name       string
+	CaseNumber string
+	Entries    []transcriptEntry
+}
+
+type transcriptEntry struct {
+	Label string
+	Date  string
+	Text  string
+}
+
+// attachTranscript attaches the conversation of the user with the bot to the case as a file
+func (m *Manager) attachTranscript(interconnection *Interconnection) {
+	logFields := logrus.Fields{
+		events.UserID: interconnection.UserID,
+		"caseId":      interconnection.CaseID,
+	}
+
+	allContext := m.GetContextByUserID(interconnection.UserID)
+	if len(allContext) == 0 {
+		logrus.WithFields(logFields).Info("There is no context for the transcript")
+		return
+	}
+
+	messages := interconnection.messages()
+	title := messages.TranscriptTitle
+	if title == "" {
+		title = defaultTranscriptTitle
+	}
+
+	content, err := buildTranscript(transcript{
+		Title:      title,
+		Name:       interconnection.Name,
+		CaseNumber: interconnection.CaseNumber,
+		Entries:    transcriptEntries(allContext, messages),
+	}, TranscriptFormat)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not build the transcript")
+		return
+	}
+
+	caseReference := interconnection.CaseNumber
+	if caseReference == "" {
+		caseReference = interconnection.CaseID
+	}
+	fileName := fmt.Sprintf("transcript-%s.%s", caseReference, transcriptExtension(TranscriptFormat))
+
+	err = m.SalesforceService.InsertContentInCase(context.Background(), content, title, fileName, interconnection.CaseID)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not attach the transcript to the case")
+		return
+	}
+	logrus.WithFields(logFields).Info("Transcript attached to the case")
+}
+
+// transcriptEntries returns the entries of the context with the labels and dates that the agents see
+func transcriptEntries(allContext []cache.Context, messages models.MessageTemplate) []transcriptEntry {
+	loc, _ := time.LoadLocation(Timezone)
+	entries := make([]transcriptEntry, 0, len(allContext))
+	for _, ctx := range allContext {
+		text := strings.TrimRight(ctx.Text, "\n")
+		if ctx.URL != "" {
+			text = mediaContextText(ctx, messages)
+		}
+
+		label := messages.BotLabel
+		if ctx.From == fromUser {
+			label = messages.ClientLabel
+		}
+
+		entries = append(entries, transcriptEntry{
+			Label: label,
+			Date: time.Unix(0, ctx.Timestamp*int64(time.Millisecond)).
+				In(loc).
+				Format(constants.DateFormat),
+			Text: text,
+		})
+	}
+	return entries
+}
+
+// buildTranscript returns the transcript as a html document or as plain text
+func buildTranscript(conversation transcript, format string) ([]byte, error) {
+	var buffer bytes.Buffer
+	if transcriptExtension(format) == transcriptHTML {
+		err := transcriptHTMLTemplate.Execute(&buffer, conversation)
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	fmt.Fprintf(&buffer, "%s\n", conversation.Title)
+	if conversation.CaseNumber != "" {
+		fmt.Fprintf(&buffer, "%s - %s\n\n", conversation.Name, conversation.CaseNumber)
+	} else {
+		fmt.Fprintf(&buffer, "%s\n\n", conversation.Name)
+	}
+	for _, entry := range conversation.Entries {
+		fmt.Fprintf(&buffer, "%s [%s]:%s\n\n", entry.Label, entry.Date, entry.Text)
+	}
+	return buffer.Bytes(), nil
+}
+
+func transcriptExtension(format string) string {
+	if strings.EqualFold(format, transcriptHTML) {
+		return transcriptHTML
+	}
+	return transcriptText
+}
@@ -0,0 +1,114 @@
+package manage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/models"
+)
+
+func TestManager_attachTranscript(t *testing.T) {
+	Messages = models.MessageTemplate{
+		BotLabel:        "Bot",
+		ClientLabel:     "Cliente",
+		TranscriptTitle: "Conversacion con el bot",
+	}
+	Timezone = "America/Mexico_City"
+	defer func() {
+		Messages = models.MessageTemplate{}
+		Timezone = ""
+		TranscriptFormat = ""
+	}()
+	ctx := []cache.Context{
+		{
+			UserID:    userID,
+			Client:    client,
+			Timestamp: 1630404000000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			Text:      "Hola",
+			From:      fromUser,
+		},
+		{
+			UserID:    userID,
+			Client:    client,
+			Timestamp: 1630404060000,
+			Ttl:       time.Now().Add(2 * time.Minute),
+			Text:      "<b>Hola</b> soy un bot\n",
+			From:      fromBot,
+		},
+	}
+	interconnection := &Interconnection{UserID: userID, Name: name, CaseID: caseID, CaseNumber: "00001001"}
+
+	t.Run("Should attach the transcript as text", func(t *testing.T) {
+		TranscriptFormat = transcriptText
+		contextCache := new(mocks.IContextCache)
+		contextCache.On("RetrieveContextFromSet", client, userID).Return(ctx).Once()
+
+		expected := `Conversacion con el bot
+username - 00001001
+
+Cliente [31-08-2021 05:00:00]:Hola
+
+Bot [31-08-2021 05:01:00]:<b>Hola</b> soy un bot
+
+`
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("InsertContentInCase", mock.Anything, []byte(expected), "Conversacion con el bot", "transcript-00001001.txt", caseID).
+			Return(nil).Once()
+
+		manager := &Manager{
+			client:            client,
+			contextcache:      contextCache,
+			SalesforceService: salesforceMock,
+		}
+
+		manager.attachTranscript(interconnection)
+		salesforceMock.AssertExpectations(t)
+	})
+
+	t.Run("Should attach the transcript as html", func(t *testing.T) {
+		TranscriptFormat = transcriptHTML
+		contextCache := new(mocks.IContextCache)
+		contextCache.On("RetrieveContextFromSet", client, userID).Return(ctx).Once()
+
+		var content []byte
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+		salesforceMock.On("InsertContentInCase", mock.Anything, mock.Anything, "Conversacion con el bot", "transcript-00001001.html", caseID).
+			Run(func(args mock.Arguments) {
+				content = args.Get(1).([]byte)
+			}).Return(nil).Once()
+
+		manager := &Manager{
+			client:            client,
+			contextcache:      contextCache,
+			SalesforceService: salesforceMock,
+		}
+
+		manager.attachTranscript(interconnection)
+		salesforceMock.AssertExpectations(t)
+		assert.Contains(t, string(content), "<h1>Conversacion con el bot</h1>")
+		assert.Contains(t, string(content), "<p>username - 00001001</p>")
+		assert.Contains(t, string(content), "<td>Cliente</td><td>31-08-2021 05:00:00</td>")
+		assert.Contains(t, string(content), "&lt;b&gt;Hola&lt;/b&gt; soy un bot")
+	})
+
+	t.Run("Shouldn't attach the transcript without context", func(t *testing.T) {
+		TranscriptFormat = transcriptText
+		contextCache := new(mocks.IContextCache)
+		contextCache.On("RetrieveContextFromSet", client, userID).Return([]cache.Context{}).Once()
+		salesforceMock := new(mocks.SalesforceServiceInterface)
+
+		manager := &Manager{
+			client:            client,
+			contextcache:      contextCache,
+			SalesforceService: salesforceMock,
+		}
+
+		manager.attachTranscript(interconnection)
+		salesforceMock.AssertNotCalled(t, "InsertContentInCase", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
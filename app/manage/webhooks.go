@@ -0,0 +1,273 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/app/config/envs"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/callback"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/metrics"
+)
+
+var (
+	// webhookOutboxInterval is the time between the checks of the outbox
+	webhookOutboxInterval = time.Second
+	// webhookBatchSize is the maximum number of webhooks claimed from the outbox at once
+	webhookBatchSize int64 = 50
+	// webhookClaimMargin is added to the timeout of the subscribers to hide the claimed webhooks from the other
+	// instances while they are delivered
+	webhookClaimMargin = 30 * time.Second
+)
+
+// webhookSubscriber is a system that receives the lifecycle events of the chats
+type webhookSubscriber struct {
+	url    string
+	events map[LifecycleEventType]bool
+	client callback.CallbackInterface
+}
+
+// newWebhookSubscribers returns the subscribers with their clients, the retries are made by the outbox
+func newWebhookSubscribers(subscribers envs.WebhookSubscribers, secret string, timeout time.Duration) map[string]webhookSubscriber {
+	webhookSubscribers := make(map[string]webhookSubscriber, len(subscribers))
+	for name, subscriber := range subscribers {
+		subscriberSecret := secret
+		if subscriber.Secret != "" {
+			subscriberSecret = subscriber.Secret
+		}
+
+		eventTypes := make(map[LifecycleEventType]bool, len(subscriber.Events))
+		for _, eventType := range subscriber.Events {
+			eventTypes[LifecycleEventType(eventType)] = true
+		}
+
+		webhookSubscribers[name] = webhookSubscriber{
+			url:    subscriber.URL,
+			events: eventTypes,
+			client: callback.NewCallbackClient(subscriberSecret, timeout, 0),
+		}
+	}
+	return webhookSubscribers
+}
+
+// webhookConcurrency returns the number of webhooks delivered at the same time, at least one
+func webhookConcurrency(concurrency int) int {
+	if concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
+
+// subscribed returns true when the subscriber receives the events of the type
+func (s webhookSubscriber) subscribed(eventType LifecycleEventType) bool {
+	return len(s.events) == 0 || s.events[eventType]
+}
+
+// enqueueWebhooks saves in the outbox a delivery of the event for each subscriber of its type
+func (m *Manager) enqueueWebhooks(event *LifecycleEvent) {
+	if len(m.webhookSubscribers) == 0 {
+		return
+	}
+
+	logFields := logrus.Fields{
+		events.UserID: event.UserID,
+		"eventId":     event.ID,
+		"eventType":   event.Type,
+	}
+	if m.webhookCache == nil {
+		logrus.WithFields(logFields).Error("Could not enqueue the webhooks without redis")
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not encode the lifecycle event")
+		return
+	}
+
+	for name, subscriber := range m.webhookSubscribers {
+		if !subscriber.subscribed(event.Type) {
+			continue
+		}
+
+		delivery := cache.WebhookDelivery{
+			ID:            helpers.RandomString(36),
+			Client:        m.client,
+			Subscriber:    name,
+			URL:           subscriber.url,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			Payload:       payload,
+			Status:        cache.WebhookPending,
+			CreatedAt:     event.Timestamp,
+			NextAttemptAt: event.Timestamp,
+		}
+		if err := m.webhookCache.EnqueueWebhook(delivery); err != nil {
+			logrus.WithFields(logFields).WithField("subscriber", name).WithError(err).Error("Could not enqueue the webhook")
+		}
+	}
+}
+
+// runWebhookOutbox delivers the webhooks of the outbox when they are due, the instances of the client share the outbox
+func (m *Manager) runWebhookOutbox() {
+	ticker := time.NewTicker(webhookOutboxInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.deliverWebhooks(context.Background())
+	}
+}
+
+// deliverWebhooks delivers concurrently the webhooks that are due, it claims only the webhooks that the free workers
+// can deliver so a slow subscriber does not hold the webhooks of the others
+func (m *Manager) deliverWebhooks(ctx context.Context) {
+	for {
+		free := int64(cap(m.webhookWorkers) - len(m.webhookWorkers))
+		if free > webhookBatchSize {
+			free = webhookBatchSize
+		}
+		if free <= 0 {
+			return
+		}
+
+		deliveries, err := m.webhookCache.ClaimWebhooks(m.client, time.Now(), m.webhookClaimTimeout, free)
+		if err != nil {
+			logrus.WithError(err).Error("Could not claim the webhooks of the outbox")
+		}
+		for _, delivery := range deliveries {
+			m.webhookWorkers <- struct{}{}
+			go func(delivery cache.WebhookDelivery) {
+				defer func() { <-m.webhookWorkers }()
+				m.deliverWebhook(ctx, delivery)
+			}(delivery)
+		}
+		if int64(len(deliveries)) < free {
+			return
+		}
+	}
+}
+
+// webhookBackoff returns the delay before the next attempt of a webhook, it doubles on each attempt up to the
+// maximum delay
+func (m *Manager) webhookBackoff(attempts int) time.Duration {
+	delay := m.webhookRetryDelay << (attempts - 1)
+	if m.webhookMaxRetryDelay > 0 && (delay <= 0 || delay > m.webhookMaxRetryDelay) {
+		return m.webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// deliverWebhook sends the webhook to its subscriber, the failed webhooks return to the outbox with an exponential
+// delay until the attempts run out and the others are removed from it. Each attempt is saved in the delivery log
+func (m *Manager) deliverWebhook(ctx context.Context, delivery cache.WebhookDelivery) {
+	logFields := logrus.Fields{
+		"deliveryId": delivery.ID,
+		"eventId":    delivery.EventID,
+		"subscriber": delivery.Subscriber,
+	}
+
+	delivery.Attempts++
+	delivery.StatusCode = 0
+	delivery.Error = ""
+	err := errors.New("the subscriber is not configured")
+	subscriber, ok := m.webhookSubscribers[delivery.Subscriber]
+	if ok {
+		err = subscriber.client.Send(ctx, delivery.URL, delivery.Payload)
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = cache.WebhookDelivered
+	case !ok || delivery.Attempts >= m.webhookMaxAttempts:
+		delivery.Status = cache.WebhookFailed
+	default:
+		delivery.Status = cache.WebhookPending
+		delivery.NextAttemptAt = time.Now().Add(m.webhookBackoff(delivery.Attempts))
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		var statusError *callback.StatusError
+		if errors.As(err, &statusError) {
+			delivery.StatusCode = statusError.StatusCode
+		}
+		logrus.WithFields(logFields).WithError(err).Warnf("Could not deliver the webhook on the attempt %d", delivery.Attempts)
+	}
+	metrics.WebhookDeliveries.WithLabelValues(delivery.Subscriber, string(delivery.Status)).Inc()
+
+	if delivery.Status == cache.WebhookPending {
+		if err := m.webhookCache.EnqueueWebhook(delivery); err != nil {
+			logrus.WithFields(logFields).WithError(err).Error("Could not return the webhook to the outbox")
+		}
+	} else if err := m.webhookCache.CompleteWebhook(delivery.Client, delivery.ID); err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not remove the webhook from the outbox")
+	}
+	if err := m.webhookCache.LogWebhookDelivery(delivery, m.webhookLogSize); err != nil {
+		logrus.WithFields(logFields).WithError(err).Error("Could not log the webhook delivery")
+	}
+}
+
+// WebhookDeliveryFilter filters and paginates the delivery log, the zero values don't filter
+type WebhookDeliveryFilter struct {
+	Status     cache.WebhookDeliveryStatus
+	EventType  LifecycleEventType
+	Subscriber string
+	Page       int64
+	Size       int64
+}
+
+func (f WebhookDeliveryFilter) match(delivery cache.WebhookDelivery) bool {
+	return (f.Status == "" || delivery.Status == f.Status) &&
+		(f.EventType == "" || LifecycleEventType(delivery.EventType) == f.EventType) &&
+		(f.Subscriber == "" || delivery.Subscriber == f.Subscriber)
+}
+
+// WebhookDeliveryList is a page of the delivery log, the newest attempts first
+type WebhookDeliveryList struct {
+	Deliveries []cache.WebhookDelivery `json:"deliveries"`
+	Total      int                     `json:"total"`
+	Page       int64                   `json:"page"`
+	Size       int64                   `json:"size"`
+}
+
+// ListWebhookDeliveries returns the attempts of the delivery log of the client that match the filter
+func (m *Manager) ListWebhookDeliveries(filter WebhookDeliveryFilter) (*WebhookDeliveryList, error) {
+	if m.webhookCache == nil {
+		return nil, errors.New("the webhook deliveries are not stored without redis")
+	}
+
+	deliveries, err := m.webhookCache.RetrieveWebhookDeliveries(m.client)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &WebhookDeliveryList{
+		Deliveries: []cache.WebhookDelivery{},
+		Page:       filter.Page,
+		Size:       filter.Size,
+	}
+	for _, delivery := range deliveries {
+		if filter.match(delivery) {
+			list.Deliveries = append(list.Deliveries, delivery)
+		}
+	}
+
+	list.Total = len(list.Deliveries)
+	if filter.Page > 0 && filter.Size > 0 {
+		start := (filter.Page - 1) * filter.Size
+		if start >= int64(list.Total) {
+			list.Deliveries = []cache.WebhookDelivery{}
+			return list, nil
+		}
+		end := start + filter.Size
+		if end > int64(list.Total) {
+			end = int64(list.Total)
+		}
+		list.Deliveries = list.Deliveries[start:end]
+	}
+	return list, nil
+}
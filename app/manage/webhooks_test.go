@@ -0,0 +1,284 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/callback"
+)
+
+func TestManager_enqueueWebhooks(t *testing.T) {
+	event := &LifecycleEvent{
+		ID:        "eventID",
+		Type:      ChatQueuedEvent,
+		Client:    client,
+		UserID:    userID,
+		Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Should enqueue a delivery for each subscriber of the event", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("EnqueueWebhook", mock.MatchedBy(func(delivery cache.WebhookDelivery) bool {
+			var payload LifecycleEvent
+			_ = json.Unmarshal(delivery.Payload, &payload)
+			return delivery.Subscriber == "crm" && delivery.URL == "https://crm.yalochat.com/webhooks" &&
+				delivery.Client == client && delivery.EventID == event.ID && delivery.Status == cache.WebhookPending &&
+				delivery.NextAttemptAt.Equal(event.Timestamp) && payload.ID == event.ID
+		})).Return(nil).Once()
+		webhookCacheMock.On("EnqueueWebhook", mock.MatchedBy(func(delivery cache.WebhookDelivery) bool {
+			return delivery.Subscriber == "analytics"
+		})).Return(nil).Once()
+
+		manager := &Manager{
+			client:       client,
+			webhookCache: webhookCacheMock,
+			webhookSubscribers: map[string]webhookSubscriber{
+				"crm":       {url: "https://crm.yalochat.com/webhooks", events: map[LifecycleEventType]bool{ChatQueuedEvent: true}},
+				"analytics": {url: "https://analytics.yalochat.com/webhooks"},
+				"audit":     {url: "https://audit.yalochat.com/webhooks", events: map[LifecycleEventType]bool{ChatEndedEvent: true}},
+			},
+		}
+
+		manager.enqueueWebhooks(event)
+
+		webhookCacheMock.AssertExpectations(t)
+		webhookCacheMock.AssertNumberOfCalls(t, "EnqueueWebhook", 2)
+	})
+
+	t.Run("Should not enqueue without subscribers", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		manager := &Manager{client: client, webhookCache: webhookCacheMock}
+
+		manager.enqueueWebhooks(event)
+
+		webhookCacheMock.AssertNotCalled(t, "EnqueueWebhook", mock.Anything)
+	})
+}
+
+func TestManager_deliverWebhook(t *testing.T) {
+	delivery := cache.WebhookDelivery{
+		ID:         "deliveryID",
+		Client:     client,
+		Subscriber: "crm",
+		URL:        "https://crm.yalochat.com/webhooks",
+		EventID:    "eventID",
+		EventType:  string(ChatEndedEvent),
+		Payload:    json.RawMessage(`{"id":"eventID"}`),
+		Status:     cache.WebhookPending,
+	}
+	newManager := func(webhookCacheMock *mocks.IWebhookCache, callbackMock *mocks.CallbackInterface) *Manager {
+		return &Manager{
+			client:             client,
+			webhookCache:       webhookCacheMock,
+			webhookSubscribers: map[string]webhookSubscriber{"crm": {url: delivery.URL, client: callbackMock}},
+			webhookMaxAttempts: 3,
+			webhookRetryDelay:  time.Minute,
+			webhookLogSize:     100,
+		}
+	}
+
+	t.Run("Should log the delivered webhook", func(t *testing.T) {
+		callbackMock := new(mocks.CallbackInterface)
+		callbackMock.On("Send", mock.Anything, delivery.URL, delivery.Payload).Return(nil).Once()
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("CompleteWebhook", client, delivery.ID).Return(nil).Once()
+		webhookCacheMock.On("LogWebhookDelivery", mock.MatchedBy(func(logged cache.WebhookDelivery) bool {
+			return logged.Status == cache.WebhookDelivered && logged.Attempts == 1 && logged.Error == ""
+		}), int64(100)).Return(nil).Once()
+
+		newManager(webhookCacheMock, callbackMock).deliverWebhook(context.Background(), delivery)
+
+		callbackMock.AssertExpectations(t)
+		webhookCacheMock.AssertExpectations(t)
+		webhookCacheMock.AssertNotCalled(t, "EnqueueWebhook", mock.Anything)
+	})
+
+	t.Run("Should return the failed webhook to the outbox with a delay", func(t *testing.T) {
+		failed := delivery
+		failed.Attempts = 1
+		callbackMock := new(mocks.CallbackInterface)
+		callbackMock.On("Send", mock.Anything, delivery.URL, delivery.Payload).
+			Return(&callback.StatusError{StatusCode: 500}).Once()
+		isRetried := func(retried cache.WebhookDelivery) bool {
+			return retried.Status == cache.WebhookPending && retried.Attempts == 2 && retried.StatusCode == 500 &&
+				retried.Error == "callback responded with status 500" &&
+				retried.NextAttemptAt.After(time.Now().Add(time.Minute+30*time.Second))
+		}
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("EnqueueWebhook", mock.MatchedBy(isRetried)).Return(nil).Once()
+		webhookCacheMock.On("LogWebhookDelivery", mock.MatchedBy(isRetried), int64(100)).Return(nil).Once()
+
+		newManager(webhookCacheMock, callbackMock).deliverWebhook(context.Background(), failed)
+
+		callbackMock.AssertExpectations(t)
+		webhookCacheMock.AssertExpectations(t)
+		webhookCacheMock.AssertNotCalled(t, "CompleteWebhook", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should cap the delay of the retries", func(t *testing.T) {
+		manager := newManager(nil, nil)
+		manager.webhookMaxRetryDelay = 10 * time.Minute
+
+		assert.Equal(t, time.Minute, manager.webhookBackoff(1))
+		assert.Equal(t, 8*time.Minute, manager.webhookBackoff(4))
+		assert.Equal(t, 10*time.Minute, manager.webhookBackoff(5))
+		assert.Equal(t, 10*time.Minute, manager.webhookBackoff(80))
+	})
+
+	t.Run("Should fail the webhook on the last attempt", func(t *testing.T) {
+		lastAttempt := delivery
+		lastAttempt.Attempts = 2
+		callbackMock := new(mocks.CallbackInterface)
+		callbackMock.On("Send", mock.Anything, delivery.URL, delivery.Payload).Return(errors.New("timeout")).Once()
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("CompleteWebhook", client, delivery.ID).Return(nil).Once()
+		webhookCacheMock.On("LogWebhookDelivery", mock.MatchedBy(func(logged cache.WebhookDelivery) bool {
+			return logged.Status == cache.WebhookFailed && logged.Attempts == 3 && logged.Error == "timeout"
+		}), int64(100)).Return(nil).Once()
+
+		newManager(webhookCacheMock, callbackMock).deliverWebhook(context.Background(), lastAttempt)
+
+		webhookCacheMock.AssertExpectations(t)
+		webhookCacheMock.AssertNotCalled(t, "EnqueueWebhook", mock.Anything)
+	})
+
+	t.Run("Should fail the webhook of a subscriber that is not configured", func(t *testing.T) {
+		unknown := delivery
+		unknown.Subscriber = "removed"
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("CompleteWebhook", client, delivery.ID).Return(nil).Once()
+		webhookCacheMock.On("LogWebhookDelivery", mock.MatchedBy(func(logged cache.WebhookDelivery) bool {
+			return logged.Status == cache.WebhookFailed && logged.Error == "the subscriber is not configured"
+		}), int64(100)).Return(nil).Once()
+
+		newManager(webhookCacheMock, new(mocks.CallbackInterface)).deliverWebhook(context.Background(), unknown)
+
+		webhookCacheMock.AssertExpectations(t)
+		webhookCacheMock.AssertNotCalled(t, "EnqueueWebhook", mock.Anything)
+	})
+}
+
+func TestManager_deliverWebhooks(t *testing.T) {
+	t.Run("Should deliver the webhooks concurrently up to the free workers", func(t *testing.T) {
+		slow := make(chan struct{})
+		callbackMock := new(mocks.CallbackInterface)
+		callbackMock.On("Send", mock.Anything, "https://slow.yalochat.com/webhooks", mock.Anything).
+			Run(func(mock.Arguments) { <-slow }).Return(nil)
+		callbackMock.On("Send", mock.Anything, "https://crm.yalochat.com/webhooks", mock.Anything).Return(nil)
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("ClaimWebhooks", client, mock.Anything, 40*time.Second, int64(2)).
+			Return([]cache.WebhookDelivery{
+				{ID: "1", Client: client, Subscriber: "slow", URL: "https://slow.yalochat.com/webhooks"},
+				{ID: "2", Client: client, Subscriber: "crm", URL: "https://crm.yalochat.com/webhooks"},
+			}, nil).Once()
+		webhookCacheMock.On("ClaimWebhooks", client, mock.Anything, 40*time.Second, int64(1)).
+			Return([]cache.WebhookDelivery{}, nil).Once()
+		delivered := make(chan string, 2)
+		webhookCacheMock.On("CompleteWebhook", client, mock.Anything).Run(func(args mock.Arguments) {
+			delivered <- args.String(1)
+		}).Return(nil)
+		webhookCacheMock.On("LogWebhookDelivery", mock.Anything, int64(100)).Return(nil)
+
+		manager := &Manager{
+			client:       client,
+			webhookCache: webhookCacheMock,
+			webhookSubscribers: map[string]webhookSubscriber{
+				"slow": {url: "https://slow.yalochat.com/webhooks", client: callbackMock},
+				"crm":  {url: "https://crm.yalochat.com/webhooks", client: callbackMock},
+			},
+			webhookMaxAttempts:  3,
+			webhookLogSize:      100,
+			webhookClaimTimeout: 40 * time.Second,
+			webhookWorkers:      make(chan struct{}, 2),
+		}
+
+		manager.deliverWebhooks(context.Background())
+		assert.Equal(t, "2", <-delivered, "the slow subscriber does not hold the others")
+		assert.Eventually(t, func() bool { return len(manager.webhookWorkers) == 1 }, time.Second, time.Millisecond)
+
+		manager.deliverWebhooks(context.Background())
+		close(slow)
+		assert.Equal(t, "1", <-delivered)
+		webhookCacheMock.AssertExpectations(t)
+	})
+
+	t.Run("Should not claim without free workers", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		manager := &Manager{client: client, webhookCache: webhookCacheMock, webhookWorkers: make(chan struct{}, 1)}
+		manager.webhookWorkers <- struct{}{}
+
+		manager.deliverWebhooks(context.Background())
+
+		webhookCacheMock.AssertNotCalled(t, "ClaimWebhooks", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestManager_ListWebhookDeliveries(t *testing.T) {
+	deliveries := []cache.WebhookDelivery{
+		{ID: "1", Subscriber: "crm", EventType: string(ChatEndedEvent), Status: cache.WebhookFailed},
+		{ID: "2", Subscriber: "crm", EventType: string(ChatEndedEvent), Status: cache.WebhookPending},
+		{ID: "3", Subscriber: "analytics", EventType: string(ChatCreatedEvent), Status: cache.WebhookDelivered},
+		{ID: "4", Subscriber: "crm", EventType: string(ChatCreatedEvent), Status: cache.WebhookDelivered},
+	}
+
+	t.Run("Should filter and paginate the delivery log", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("RetrieveWebhookDeliveries", client).Return(deliveries, nil).Once()
+		manager := &Manager{client: client, webhookCache: webhookCacheMock}
+
+		list, err := manager.ListWebhookDeliveries(WebhookDeliveryFilter{Subscriber: "crm", Page: 2, Size: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &WebhookDeliveryList{
+			Deliveries: []cache.WebhookDelivery{deliveries[3]},
+			Total:      3,
+			Page:       2,
+			Size:       2,
+		}, list)
+	})
+
+	t.Run("Should filter by status and event type", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("RetrieveWebhookDeliveries", client).Return(deliveries, nil).Once()
+		manager := &Manager{client: client, webhookCache: webhookCacheMock}
+
+		list, err := manager.ListWebhookDeliveries(WebhookDeliveryFilter{
+			Status:    cache.WebhookDelivered,
+			EventType: ChatCreatedEvent,
+			Page:      1,
+			Size:      10,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []cache.WebhookDelivery{deliveries[2], deliveries[3]}, list.Deliveries)
+		assert.Equal(t, 2, list.Total)
+	})
+
+	t.Run("Should return an empty page after the last one", func(t *testing.T) {
+		webhookCacheMock := new(mocks.IWebhookCache)
+		webhookCacheMock.On("RetrieveWebhookDeliveries", client).Return(deliveries, nil).Once()
+		manager := &Manager{client: client, webhookCache: webhookCacheMock}
+
+		list, err := manager.ListWebhookDeliveries(WebhookDeliveryFilter{Page: 3, Size: 2})
+
+		assert.NoError(t, err)
+		assert.Empty(t, list.Deliveries)
+		assert.Equal(t, 4, list.Total)
+	})
+
+	t.Run("Should return an error without redis", func(t *testing.T) {
+		manager := &Manager{client: client}
+
+		list, err := manager.ListWebhookDeliveries(WebhookDeliveryFilter{Page: 1, Size: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, list)
+	})
+}
@@ -0,0 +1,168 @@
+package manage
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/integrations"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/events"
+	"yalochat.com/salesforce-integration/base/helpers"
+)
+
+// whatsappWindow is the time after the last message of the user in which whatsapp accepts free-form messages,
+// after it only the approved templates are delivered
+const whatsappWindow = 24 * time.Hour
+
+// userMessageReceived saves the time of the last message of the user in the chat and in redis, it opens the
+// whatsapp window again
+func (in *Interconnection) userMessageReceived(timestamp time.Time) {
+	in.LastUserMessageAt = timestamp
+	if in.interconnectionCache != nil {
+		in.updateLastUserMessageRedis(timestamp)
+	}
+}
+
+// windowExpired returns true when the last message of the user is older than the whatsapp window, the window of
+// the chats where the user has not written is closed
+func (in *Interconnection) windowExpired(now time.Time) bool {
+	return now.Sub(in.LastUserMessageAt) > whatsappWindow
+}
+
+// windowTemplatePending returns true when the window template was not sent since the last message of the user
+func (in *Interconnection) windowTemplatePending() bool {
+	return in.windowTemplateSentAt.IsZero() || in.windowTemplateSentAt.Before(in.LastUserMessageAt)
+}
+
+// windowInterconnection returns the open chat of the user, the chats of the other instances are read from redis
+func (m *Manager) windowInterconnection(userID string) (*Interconnection, bool) {
+	if m.interconnectionMap != nil {
+		if interconnection, ok := m.validInterconnection(userID); ok {
+			return interconnection, true
+		}
+	}
+	if m.interconnectionsCache == nil {
+		return nil, false
+	}
+
+	stored, err := m.interconnectionsCache.RetrieveInterconnection(cache.Interconnection{UserID: userID, Client: m.client})
+	if err != nil {
+		return nil, false
+	}
+	interconnection := convertInterconnectionCacheToInterconnection(*stored)
+	if interconnection.Status != Active && interconnection.Status != OnHold {
+		return nil, false
+	}
+	interconnection.interconnectionCache = m.interconnectionsCache
+	interconnection.kafkaProducer = m.kafkaProducer
+	interconnection.KafkaTopic = m.KafkaTopic
+	return interconnection, true
+}
+
+// lastUserMessageAt returns the time of the newest message of the user in the context, it's zero when the
+// context does not have messages of the user
+func (m *Manager) lastUserMessageAt(userID string) time.Time {
+	if m.contextcache == nil {
+		return time.Time{}
+	}
+
+	allContext := m.GetContextByUserID(userID)
+	for i := len(allContext) - 1; i >= 0; i-- {
+		if allContext[i].From == fromUser {
+			return time.UnixMilli(allContext[i].Timestamp).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// windowClosed returns true when the message can't be sent because the whatsapp window of the user is closed.
+// The first message after the window closed sends the window template to invite the user to continue the chat, and
+// the agent is told that the message was not delivered
+func (m *Manager) windowClosed(message *Message) bool {
+	if message.Provider != WhatsappProvider || m.windowTemplate.Name == "" {
+		return false
+	}
+	interconnection, ok := m.windowInterconnection(message.UserID)
+	if !ok || !interconnection.windowExpired(time.Now()) {
+		return false
+	}
+
+	// datadog tracing
+	spanContext := events.GetSpanContextFromSpan(message.MainSpan)
+	span := tracer.StartSpan("windowClosed", tracer.ChildOf(spanContext))
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.UserID, message.UserID)
+	span.SetTag("lastUserMessageAt", interconnection.LastUserMessageAt)
+	defer span.Finish()
+
+	logrus.WithFields(logrus.Fields{
+		constants.TraceIdKey: span.Context().TraceID(),
+		constants.SpanIdKey:  span.Context().SpanID(),
+		events.UserID:        message.UserID,
+		"messageId":          message.ID,
+	}).Warn("The whatsapp window of the user is closed, the message is not sent")
+
+	agentMessage := interconnection.messages().MessageNotDelivered
+	if interconnection.windowTemplatePending() && m.sendWindowTemplate(span, interconnection, message) {
+		agentMessage = interconnection.messages().WindowClosed
+	}
+
+	if text := interconnection.renderMessage(span, agentMessage, interconnection.templateVariables()); text != "" {
+		interconnection.sendMessageToQueue(span, helpers.RandomString(36), text, constants.SendMessageToSalesforce)
+	}
+	return true
+}
+
+// sendWindowTemplate sends the window template to the user of the message, the time it was sent is saved so the
+// template is sent once until the user writes again
+func (m *Manager) sendWindowTemplate(span tracer.Span, interconnection *Interconnection, message *Message) bool {
+	template := *message
+	template.ID = helpers.RandomString(36)
+	template.Text = ""
+	template.MediaURL = ""
+	if !m.sendPayloadToUser(&template, m.windowTemplatePayload(span, interconnection, template.ID)) {
+		return false
+	}
+
+	interconnection.windowTemplateSentAt = time.Now().UTC()
+	if interconnection.interconnectionCache != nil {
+		interconnection.updateWindowTemplateRedis(interconnection.windowTemplateSentAt)
+	}
+	logrus.WithFields(logrus.Fields{
+		constants.TraceIdKey: span.Context().TraceID(),
+		constants.SpanIdKey:  span.Context().SpanID(),
+		events.UserID:        interconnection.UserID,
+	}).Info("Window template sent to the user")
+	return true
+}
+
+// windowTemplatePayload returns the payload of integrations of the window template with its params rendered
+func (m *Manager) windowTemplatePayload(span tracer.Span, interconnection *Interconnection, id string) integrations.SendTemplatePayload {
+	template := integrations.TemplateMessage{
+		Namespace: m.windowTemplate.Namespace,
+		Name:      m.windowTemplate.Name,
+		Language:  integrations.TemplateLanguage{Policy: "deterministic", Code: m.windowTemplate.Language},
+	}
+
+	if len(m.windowTemplate.Params) > 0 {
+		variables := interconnection.templateVariables()
+		parameters := make([]integrations.TemplateParameter, 0, len(m.windowTemplate.Params))
+		for _, param := range m.windowTemplate.Params {
+			parameters = append(parameters, integrations.TemplateParameter{
+				Type: constants.TextType,
+				Text: interconnection.renderMessage(span, param, variables),
+			})
+		}
+		template.Components = []integrations.TemplateComponent{{Type: "body", Parameters: parameters}}
+	}
+
+	return integrations.SendTemplatePayload{
+		Id:       id,
+		Type:     constants.TemplateType,
+		UserID:   interconnection.UserID,
+		Template: template,
+	}
+}
@@ -0,0 +1,275 @@
+package manage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"yalochat.com/salesforce-integration/app/manage/mocks"
+	"yalochat.com/salesforce-integration/base/cache"
+	"yalochat.com/salesforce-integration/base/clients/integrations"
+	"yalochat.com/salesforce-integration/base/constants"
+	"yalochat.com/salesforce-integration/base/models"
+	"yalochat.com/salesforce-integration/base/subscribers/kafka"
+)
+
+func TestInterconnection_windowExpired(t *testing.T) {
+	now := time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Should expire 24 hours after the last message of the user", func(t *testing.T) {
+		interconnection := &Interconnection{LastUserMessageAt: now.Add(-25 * time.Hour)}
+
+		assert.True(t, interconnection.windowExpired(now))
+	})
+
+	t.Run("Shouldn't expire before 24 hours", func(t *testing.T) {
+		interconnection := &Interconnection{LastUserMessageAt: now.Add(-23 * time.Hour)}
+
+		assert.False(t, interconnection.windowExpired(now))
+	})
+
+	t.Run("Should expire without messages of the user", func(t *testing.T) {
+		interconnection := &Interconnection{}
+
+		assert.True(t, interconnection.windowExpired(now))
+	})
+}
+
+func TestConvertInterconnectionCacheToInterconnection_window(t *testing.T) {
+	now := time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Should restore the window of the chat", func(t *testing.T) {
+		interconnection := convertInterconnectionCacheToInterconnection(cache.Interconnection{
+			UserID:               userID,
+			Timestamp:            now.Add(-48 * time.Hour),
+			LastUserMessageAt:    now.Add(-time.Hour),
+			WindowTemplateSentAt: now.Add(-time.Minute),
+		})
+
+		assert.Equal(t, now.Add(-time.Hour), interconnection.LastUserMessageAt)
+		assert.Equal(t, now.Add(-time.Minute), interconnection.windowTemplateSentAt)
+		assert.False(t, interconnection.windowTemplatePending())
+	})
+
+	t.Run("Should take the creation of the chats stored before the window was tracked", func(t *testing.T) {
+		interconnection := convertInterconnectionCacheToInterconnection(cache.Interconnection{
+			UserID:    userID,
+			Timestamp: now.Add(-48 * time.Hour),
+		})
+
+		assert.Equal(t, now.Add(-48*time.Hour), interconnection.LastUserMessageAt)
+	})
+}
+
+func TestManager_windowClosed(t *testing.T) {
+	Messages = models.MessageTemplate{
+		MessageNotDelivered: "El mensaje no fue entregado",
+		WindowClosed:        "Se envió la plantilla a {{.Name}}",
+	}
+	defer func() {
+		Messages = models.MessageTemplate{}
+	}()
+	span, _ := tracer.SpanFromContext(context.Background())
+	message := &Message{
+		ID:       "messageID",
+		MainSpan: span,
+		Text:     "Hola",
+		UserID:   userID,
+		Provider: WhatsappProvider,
+	}
+	windowTemplate := models.WindowTemplate{
+		Name:      "continue_chat",
+		Namespace: "namespace",
+		Language:  "es_MX",
+		Params:    []string{"{{.Name}}", "{{.CaseNumber}}"},
+	}
+	newManager := func(interconnection *Interconnection, integrationsMock *mocks.IntegrationInterface) *Manager {
+		interconnectionMap := cache.New()
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, 0)
+		interconnectionMap.Wait()
+		return &Manager{
+			client:             client,
+			IntegrationsClient: integrationsMock,
+			interconnectionMap: interconnectionMap,
+			windowTemplate:     windowTemplate,
+		}
+	}
+	newProducer := func(sentMessages *[]InterconnectionMessageQueue) *mocks.Producer {
+		producerMock := new(mocks.Producer)
+		producerMock.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+			message := InterconnectionMessageQueue{}
+			json.Unmarshal(args.Get(0).(kafka.KafkaMessageParams).Msg, &message)
+			*sentMessages = append(*sentMessages, message)
+		}).Return(nil)
+		return producerMock
+	}
+
+	t.Run("Should send the window template and tell the agent", func(t *testing.T) {
+		var sentMessages []InterconnectionMessageQueue
+		interconnection := &Interconnection{
+			UserID:            userID,
+			Name:              "Juan",
+			CaseNumber:        "00001001",
+			Status:            Active,
+			LastUserMessageAt: time.Now().Add(-25 * time.Hour),
+			kafkaProducer:     newProducer(&sentMessages),
+		}
+		integrationsMock := new(mocks.IntegrationInterface)
+		integrationsMock.On("SendMessage", mock.MatchedBy(func(payload integrations.SendTemplatePayload) bool {
+			return payload.Type == constants.TemplateType && payload.UserID == userID &&
+				payload.Template.Name == "continue_chat" && payload.Template.Namespace == "namespace" &&
+				payload.Template.Language.Code == "es_MX" &&
+				assert.ObjectsAreEqual([]integrations.TemplateComponent{{
+					Type: "body",
+					Parameters: []integrations.TemplateParameter{
+						{Type: constants.TextType, Text: "Juan"},
+						{Type: constants.TextType, Text: "00001001"},
+					},
+				}}, payload.Template.Components)
+		}), string(WhatsappProvider)).Return(&integrations.SendMessageResponse{}, nil).Once()
+
+		manager := newManager(interconnection, integrationsMock)
+
+		assert.True(t, manager.windowClosed(message))
+		integrationsMock.AssertExpectations(t)
+		assert.False(t, interconnection.windowTemplateSentAt.IsZero())
+		assert.Len(t, sentMessages, 1)
+		assert.Equal(t, "Se envió la plantilla a Juan", sentMessages[0].Params.Message.Text)
+		assert.Equal(t, constants.SendMessageToSalesforce, sentMessages[0].EventType)
+	})
+
+	t.Run("Should send the window template once until the user writes", func(t *testing.T) {
+		var sentMessages []InterconnectionMessageQueue
+		lastUserMessageAt := time.Now().Add(-25 * time.Hour)
+		interconnection := &Interconnection{
+			UserID:               userID,
+			Status:               Active,
+			LastUserMessageAt:    lastUserMessageAt,
+			windowTemplateSentAt: lastUserMessageAt.Add(time.Hour),
+			kafkaProducer:        newProducer(&sentMessages),
+		}
+		integrationsMock := new(mocks.IntegrationInterface)
+
+		manager := newManager(interconnection, integrationsMock)
+
+		assert.True(t, manager.windowClosed(message))
+		integrationsMock.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+		assert.Len(t, sentMessages, 1)
+		assert.Equal(t, "El mensaje no fue entregado", sentMessages[0].Params.Message.Text)
+	})
+
+	t.Run("Should tell the agent when the window template was not sent", func(t *testing.T) {
+		var sentMessages []InterconnectionMessageQueue
+		interconnection := &Interconnection{
+			UserID:            userID,
+			Status:            Active,
+			LastUserMessageAt: time.Now().Add(-25 * time.Hour),
+			kafkaProducer:     newProducer(&sentMessages),
+		}
+		integrationsMock := new(mocks.IntegrationInterface)
+		integrationsMock.On("SendMessage", mock.Anything, string(WhatsappProvider)).Return(nil, assert.AnError).Once()
+
+		manager := newManager(interconnection, integrationsMock)
+
+		assert.True(t, manager.windowClosed(message))
+		assert.True(t, interconnection.windowTemplateSentAt.IsZero())
+		assert.Len(t, sentMessages, 1)
+		assert.Equal(t, "El mensaje no fue entregado", sentMessages[0].Params.Message.Text)
+	})
+
+	t.Run("Should send the window template to the chat of another instance", func(t *testing.T) {
+		var sentMessages []InterconnectionMessageQueue
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		stored := cache.Interconnection{
+			UserID:            userID,
+			Client:            client,
+			Status:            string(Active),
+			Provider:          string(WhatsappProvider),
+			LastUserMessageAt: time.Now().Add(-25 * time.Hour),
+		}
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(func(cache.Interconnection) *cache.Interconnection {
+				copied := stored
+				return &copied
+			}, nil)
+		interconnectionCacheMock.On("StoreInterconnection", mock.MatchedBy(func(interconnection cache.Interconnection) bool {
+			return !interconnection.WindowTemplateSentAt.IsZero()
+		})).Return(nil).Once()
+		integrationsMock := new(mocks.IntegrationInterface)
+		integrationsMock.On("SendMessage", mock.AnythingOfType("integrations.SendTemplatePayload"), string(WhatsappProvider)).
+			Return(&integrations.SendMessageResponse{}, nil).Once()
+
+		manager := &Manager{
+			client:                client,
+			IntegrationsClient:    integrationsMock,
+			interconnectionMap:    cache.New(),
+			interconnectionsCache: interconnectionCacheMock,
+			kafkaProducer:         newProducer(&sentMessages),
+			windowTemplate:        windowTemplate,
+		}
+
+		assert.True(t, manager.windowClosed(message))
+		integrationsMock.AssertExpectations(t)
+		interconnectionCacheMock.AssertExpectations(t)
+		assert.Len(t, sentMessages, 1)
+		assert.Equal(t, "Se envió la plantilla a ", sentMessages[0].Params.Message.Text)
+	})
+
+	t.Run("Shouldn't close the window when the user wrote in the last 24 hours", func(t *testing.T) {
+		interconnection := &Interconnection{UserID: userID, Status: Active, LastUserMessageAt: time.Now().Add(-time.Hour)}
+		integrationsMock := new(mocks.IntegrationInterface)
+
+		manager := newManager(interconnection, integrationsMock)
+
+		assert.False(t, manager.windowClosed(message))
+	})
+
+	t.Run("Shouldn't close the window without window template", func(t *testing.T) {
+		interconnection := &Interconnection{UserID: userID, Status: Active, LastUserMessageAt: time.Now().Add(-25 * time.Hour)}
+		integrationsMock := new(mocks.IntegrationInterface)
+
+		manager := newManager(interconnection, integrationsMock)
+		manager.windowTemplate = models.WindowTemplate{}
+
+		assert.False(t, manager.windowClosed(message))
+	})
+
+	t.Run("Shouldn't close the window of facebook", func(t *testing.T) {
+		interconnection := &Interconnection{UserID: userID, Status: Active, LastUserMessageAt: time.Now().Add(-25 * time.Hour)}
+		integrationsMock := new(mocks.IntegrationInterface)
+		facebookMessage := *message
+		facebookMessage.Provider = FacebookProvider
+
+		manager := newManager(interconnection, integrationsMock)
+
+		assert.False(t, manager.windowClosed(&facebookMessage))
+	})
+}
+
+func TestManager_salesforceComunication_lastUserMessage(t *testing.T) {
+	t.Run("Should save the time of the last message of the user", func(t *testing.T) {
+		interconnectionCacheMock := new(mocks.IInterconnectionCache)
+		interconnectionCacheMock.On("RetrieveInterconnection", cache.Interconnection{UserID: userID, Client: client}).
+			Return(&cache.Interconnection{UserID: userID, Client: client, Status: string(OnHold)}, nil).Once()
+		interconnectionCacheMock.On("StoreInterconnection", mock.MatchedBy(func(interconnection cache.Interconnection) bool {
+			return interconnection.Status == string(OnHold) && !interconnection.LastUserMessageAt.IsZero()
+		})).Return(nil).Once()
+		interconnection := &Interconnection{UserID: userID, Client: client, Status: OnHold, interconnectionCache: interconnectionCacheMock}
+		interconnectionMap := cache.New()
+		interconnectionMap.Set(fmt.Sprintf(constants.UserKey, userID), interconnection, 0)
+		interconnectionMap.Wait()
+		manager := &Manager{client: client, interconnectionMap: interconnectionMap}
+		span, _ := tracer.SpanFromContext(context.Background())
+
+		sent := manager.salesforceComunication(span, &models.IntegrationsRequest{ID: messageID, From: userID, Type: constants.TextType})
+
+		assert.False(t, sent)
+		assert.WithinDuration(t, time.Now(), interconnection.LastUserMessageAt, time.Second)
+		interconnectionCacheMock.AssertExpectations(t)
+	})
+}
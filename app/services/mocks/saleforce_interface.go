@@ -6,6 +6,8 @@ import (
 	ddtrace "gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	helpers "yalochat.com/salesforce-integration/base/helpers"
 
+	io "io"
+
 	mock "github.com/stretchr/testify/mock"
 
 	models "yalochat.com/salesforce-integration/base/models"
@@ -18,6 +20,22 @@ type SaleforceInterface struct {
 	mock.Mock
 }
 
+// CheckToken provides a mock function with given fields: mainSpan
+func (_m *SaleforceInterface) CheckToken(mainSpan ddtrace.Span) *helpers.ErrorResponse {
+	ret := _m.Called(mainSpan)
+
+	var r0 *helpers.ErrorResponse
+	if rf, ok := ret.Get(0).(func(ddtrace.Span) *helpers.ErrorResponse); ok {
+		r0 = rf(mainSpan)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*helpers.ErrorResponse)
+		}
+	}
+
+	return r0
+}
+
 // Composite provides a mock function with given fields: mainSpan, compositeRequest
 func (_m *SaleforceInterface) Composite(mainSpan ddtrace.Span, compositeRequest salesforce.CompositeRequest) (salesforce.CompositeResponses, *helpers.ErrorResponse) {
 	ret := _m.Called(mainSpan, compositeRequest)
@@ -112,6 +130,29 @@ func (_m *SaleforceInterface) CreateCase(mainSpan ddtrace.Span, payload interfac
 	return r0, r1
 }
 
+// CreateCaseComment provides a mock function with given fields: mainSpan, payload
+func (_m *SaleforceInterface) CreateCaseComment(mainSpan ddtrace.Span, payload salesforce.CaseCommentRequest) (string, *helpers.ErrorResponse) {
+	ret := _m.Called(mainSpan, payload)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(ddtrace.Span, salesforce.CaseCommentRequest) string); ok {
+		r0 = rf(mainSpan, payload)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 *helpers.ErrorResponse
+	if rf, ok := ret.Get(1).(func(ddtrace.Span, salesforce.CaseCommentRequest) *helpers.ErrorResponse); ok {
+		r1 = rf(mainSpan, payload)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*helpers.ErrorResponse)
+		}
+	}
+
+	return r0, r1
+}
+
 // CreateContact provides a mock function with given fields: mainSpan, payload
 func (_m *SaleforceInterface) CreateContact(mainSpan ddtrace.Span, payload interface{}) (string, *helpers.ErrorResponse) {
 	ret := _m.Called(mainSpan, payload)
@@ -156,6 +197,29 @@ func (_m *SaleforceInterface) CreateContentVersion(_a0 salesforce.ContentVersion
 	return r0, r1
 }
 
+// CreateContentVersionStream provides a mock function with given fields: mainSpan, metadata, content
+func (_m *SaleforceInterface) CreateContentVersionStream(mainSpan ddtrace.Span, metadata salesforce.ContentVersionMetadata, content io.Reader) (string, *helpers.ErrorResponse) {
+	ret := _m.Called(mainSpan, metadata, content)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(ddtrace.Span, salesforce.ContentVersionMetadata, io.Reader) string); ok {
+		r0 = rf(mainSpan, metadata, content)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 *helpers.ErrorResponse
+	if rf, ok := ret.Get(1).(func(ddtrace.Span, salesforce.ContentVersionMetadata, io.Reader) *helpers.ErrorResponse); ok {
+		r1 = rf(mainSpan, metadata, content)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*helpers.ErrorResponse)
+		}
+	}
+
+	return r0, r1
+}
+
 // GetContentVersionURL provides a mock function with given fields:
 func (_m *SaleforceInterface) GetContentVersionURL() string {
 	ret := _m.Called()
@@ -1,9 +1,11 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"yalochat.com/salesforce-integration/base/clients/salesforce"
 	"yalochat.com/salesforce-integration/base/constants"
 	"yalochat.com/salesforce-integration/base/helpers"
+	"yalochat.com/salesforce-integration/base/metrics"
 	"yalochat.com/salesforce-integration/base/models"
 )
 
@@ -26,6 +29,10 @@ const (
 	visibility                 = "allUsers"
 	queryContentDocumentIDByID = `SELECT+ContentDocumentID+FROM+ContentVersion+WHERE+id+=+'@{newContentVersion.id}'`
 	linkReferenceID            = "@{newQuery.records[0].ContentDocumentId}"
+	queryContentDocumentID     = `SELECT+ContentDocumentID+FROM+ContentVersion+WHERE+id+=+'%s'`
+	queryCaseNumber            = `SELECT+CaseNumber+FROM+Case+WHERE+Id+=+'%s'`
+	// compositeMaxFileSize files up to this size are sent encoded in a composite request, bigger files are streamed
+	compositeMaxFileSize = 5 * 1024 * 1024
 )
 
 type SalesforceService struct {
@@ -41,18 +48,23 @@ type SalesforceService struct {
 	AccountRecordTypeId            string
 	DefaultBirthDateAccount        string
 	RecordTypeID                   string
-	FirstNameContact               string
+	MaxFileSize                    int64
+	FileTypePolicy                 helpers.FileTypePolicy
 }
 
 type SalesforceServiceInterface interface {
 	CreatChat(context context.Context, contactName, organizationID, deploymentID, buttonID, caseID, contactID string) (*chat.SessionResponse, error)
-	GetOrCreateContact(context context.Context, name, email, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error)
+	GetOrCreateContact(context context.Context, firstName, name, email, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error)
 	SendMessage(tracer.Span, string, string, chat.MessagePayload) (bool, error)
 	GetMessages(mainSpan tracer.Span, affinityToken, sessionKey string, ack int) (*chat.MessagesResponse, *helpers.ErrorResponse)
 	CreatCase(context context.Context, contactID, description, subject, origin, ownerID string, extraData map[string]interface{}) (string, error)
+	GetCaseNumber(context context.Context, caseID string) (string, error)
+	InsertCommentInCase(context context.Context, caseID, comment string) error
 	InsertFileInCase(uri, title, mimeType, caseID string) error
+	InsertContentInCase(context context.Context, content []byte, title, fileName, caseID string) error
 	EndChat(affinityToken, sessionKey string) error
 	RefreshToken()
+	CheckToken(context context.Context) error
 	SearchContactComposite(email, phoneNumber string, sfcCustomFieldsToSearchContact map[string]string, extraData map[string]interface{}) (*models.SfcContact, *helpers.ErrorResponse)
 	ReconnectSession(sessionKey, offset string) (*chat.MessagesResponse, error)
 }
@@ -63,8 +75,7 @@ func NewSalesforceService(
 	salesforceClient salesforce.SalesforceClient,
 	tokenPayload login.TokenPayload,
 	customFieldsCase map[string]string,
-	recordTypeID,
-	firsNameContact string,
+	recordTypeID string,
 	customFieldsContact map[string]string,
 	customFieldsToSearchContact map[string]string,
 	customFieldsAccount map[string]string,
@@ -80,7 +91,6 @@ func NewSalesforceService(
 		SfcCustomFieldsAccount:         customFieldsAccount,
 		RecordTypeID:                   recordTypeID,
 		DefaultBirthDateAccount:        time.Now().Format(constants.DateFormatDateTime),
-		FirstNameContact:               firsNameContact,
 	}
 	salesforceService.RefreshToken()
 	return salesforceService
@@ -194,7 +204,9 @@ func (s *SalesforceService) CreatChat(ctx context.Context, contactName, organiza
 	return session, nil
 }
 
-func (s *SalesforceService) GetOrCreateContact(ctx context.Context, name, email, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error) {
+// GetOrCreateContact returns the contact of the email, phone number or custom fields, the contact is created with the
+// first name when it is not found
+func (s *SalesforceService) GetOrCreateContact(ctx context.Context, firstName, name, email, phoneNumber string, extraData map[string]interface{}) (*models.SfcContact, error) {
 	// datadog tracing
 	span, _ := tracer.StartSpanFromContext(ctx, "salesforceService.GetOrCreateContact")
 	span.SetTag(ext.AnalyticsEvent, true)
@@ -216,7 +228,7 @@ func (s *SalesforceService) GetOrCreateContact(ctx context.Context, name, email,
 	}
 
 	contact = &models.SfcContact{
-		FirstName:   s.FirstNameContact,
+		FirstName:   firstName,
 		LastName:    name,
 		Email:       email,
 		MobilePhone: phoneNumber,
@@ -238,7 +250,6 @@ func (s *SalesforceService) GetOrCreateContact(ctx context.Context, name, email,
 			}
 		}
 
-		firstName := s.FirstNameContact
 		accountRequest := salesforce.AccountRequest{
 			FirstName:         &firstName,
 			LastName:          &name,
@@ -274,6 +285,7 @@ func (s *SalesforceService) GetOrCreateContact(ctx context.Context, name, email,
 
 		contact.ID = account.PersonContactId
 		contact.AccountID = account.ID
+		contact.Created = true
 		span.SetTag("createAccount", true)
 		return contact, nil
 	}
@@ -308,6 +320,7 @@ func (s *SalesforceService) GetOrCreateContact(ctx context.Context, name, email,
 		return nil, errors.New(helpers.ErrorMessage("not found or create contact", err.Error))
 	}
 	contact.ID = contactID
+	contact.Created = true
 	return contact, nil
 }
 
@@ -382,8 +395,58 @@ func (s *SalesforceService) CreatCase(ctx context.Context, contactID, descriptio
 	return caseID, nil
 }
 
+// GetCaseNumber retrieves the CaseNumber of the case, the number that the user can use to reference it
+func (s *SalesforceService) GetCaseNumber(ctx context.Context, caseID string) (string, error) {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "salesforceService.GetCaseNumber")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag("caseID", caseID)
+	defer span.Finish()
+
+	response, errorResponse := s.SfcClient.Search(fmt.Sprintf(queryCaseNumber, caseID))
+	if errorResponse != nil {
+		if errorResponse.StatusCode == http.StatusUnauthorized {
+			s.RefreshToken()
+		}
+		span.SetTag(ext.Error, errorResponse.Error)
+		return "", errorResponse.Error
+	}
+
+	if len(response.Records) < 1 || response.Records[0].CaseNumber == "" {
+		err := fmt.Errorf("%s : %s", constants.RequestError, helpers.EmptyResponse)
+		span.SetTag(ext.Error, err)
+		return "", err
+	}
+
+	span.SetTag("caseNumber", response.Records[0].CaseNumber)
+	return response.Records[0].CaseNumber, nil
+}
+
+// InsertCommentInCase adds a private comment to the case
+func (s *SalesforceService) InsertCommentInCase(ctx context.Context, caseID, comment string) error {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "salesforceService.InsertCommentInCase")
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag("caseID", caseID)
+	defer span.Finish()
+
+	_, errorResponse := s.SfcClient.CreateCaseComment(span, salesforce.CaseCommentRequest{
+		ParentID:    caseID,
+		CommentBody: comment,
+	})
+	if errorResponse != nil {
+		if errorResponse.StatusCode == http.StatusUnauthorized {
+			s.RefreshToken()
+		}
+		span.SetTag(ext.Error, errorResponse.Error)
+		return errorResponse.Error
+	}
+	return nil
+}
+
 func (s *SalesforceService) RefreshToken() {
 	token, err := s.SfcLoginClient.GetToken(s.TokenPayload)
+	metrics.TokenRefreshes.WithLabelValues(metrics.Result(err)).Inc()
 	if err != nil {
 		logrus.Errorf("Could not get access token from salesforce Server : %s", err.Error())
 		return
@@ -394,6 +457,24 @@ func (s *SalesforceService) RefreshToken() {
 	logrus.Info("Refresh token successful")
 }
 
+// CheckToken validates the access token, a new token is requested when it is not valid
+func (s *SalesforceService) CheckToken(ctx context.Context) error {
+	// datadog tracing
+	span, _ := tracer.StartSpanFromContext(ctx, "salesforceService.CheckToken")
+	span.SetTag(ext.AnalyticsEvent, true)
+	defer span.Finish()
+
+	errorResponse := s.SfcClient.CheckToken(span)
+	if errorResponse != nil {
+		if errorResponse.StatusCode == http.StatusUnauthorized || errorResponse.StatusCode == http.StatusForbidden {
+			s.RefreshToken()
+		}
+		span.SetTag(ext.Error, errorResponse.Error)
+		return errorResponse.Error
+	}
+	return nil
+}
+
 func (s *SalesforceService) InsertFileInCase(uri, title, mimeType, caseID string) error {
 	span := tracer.StartSpan("InsertFileInCase")
 	span.SetTag("caseId", caseID)
@@ -405,6 +486,7 @@ func (s *SalesforceService) InsertFileInCase(uri, title, mimeType, caseID string
 		span.SetTag(ext.Error, err)
 		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		err := fmt.Errorf("file not found")
@@ -412,24 +494,63 @@ func (s *SalesforceService) InsertFileInCase(uri, title, mimeType, caseID string
 		return err
 	}
 
-	var body []byte
+	span.SetTag("fileSize", resp.ContentLength)
+	if s.MaxFileSize > 0 && resp.ContentLength > s.MaxFileSize {
+		span.SetTag(ext.Error, constants.ErrFileTooLarge)
+		return constants.ErrFileTooLarge
+	}
+
+	limitedReader := helpers.NewLimitedReader(resp.Body, s.MaxFileSize)
+	sniffedMIME, content, err := helpers.GetMIMEAndReader(limitedReader)
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		return err
+	}
+
+	span.SetTag("fileType", sniffedMIME.String())
+	if err := s.FileTypePolicy.Validate(sniffedMIME, mimeType, title); err != nil {
+		span.SetTag(ext.Error, err)
+		return err
+	}
+
 	if mimeType == "" {
-		contentType, content, err := helpers.GetContentAndTypeByReader(resp.Body)
-		if err != nil {
-			span.SetTag(ext.Error, err)
-			return err
+		mimeType = sniffedMIME.String()
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(content, compositeMaxFileSize+1))
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		if limitedReader.Exceeded() {
+			return constants.ErrFileTooLarge
 		}
+		return err
+	}
 
-		mimeType = contentType
-		body = helpers.StreamToByte(content)
+	if len(body) > compositeMaxFileSize {
+		span.SetTag("stream", true)
+		err = s.insertFileInCaseStream(span, io.MultiReader(bytes.NewReader(body), content), limitedReader, title, mimeType, caseID)
 	} else {
-		body, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			span.SetTag(ext.Error, err)
-			return err
-		}
+		err = s.insertContentInCase(span, body, title, helpers.GetExportFilename(title, mimeType), caseID)
+	}
+	if err == nil {
+		metrics.FileUploadSize.Observe(float64(limitedReader.BytesRead()))
 	}
+	return err
+}
 
+// InsertContentInCase uploads the content as a file with the name fileName and links it to the case
+func (s *SalesforceService) InsertContentInCase(ctx context.Context, content []byte, title, fileName, caseID string) error {
+	span, _ := tracer.StartSpanFromContext(ctx, "salesforceService.InsertContentInCase")
+	span.SetTag("caseId", caseID)
+	span.SetTag("title", title)
+	span.SetTag("fileSize", len(content))
+	defer span.Finish()
+
+	return s.insertContentInCase(span, content, title, fileName, caseID)
+}
+
+// insertContentInCase creates the content version, queries its document and links it to the case in a composite request
+func (s *SalesforceService) insertContentInCase(span tracer.Span, content []byte, title, pathOnClient, caseID string) error {
 	request := salesforce.CompositeRequest{
 		AllOrNone:          true,
 		CollateSubrequests: false,
@@ -440,8 +561,8 @@ func (s *SalesforceService) InsertFileInCase(uri, title, mimeType, caseID string
 				Body: salesforce.ContentVersionPayload{
 					Title:           title,
 					ContentLocation: contentLocation,
-					PathOnClient:    helpers.GetExportFilename(title, mimeType),
-					VersionData:     string(helpers.Encode(body)),
+					PathOnClient:    pathOnClient,
+					VersionData:     string(helpers.Encode(content)),
 				},
 				ReferenceId: "newContentVersion",
 			},
@@ -473,6 +594,55 @@ func (s *SalesforceService) InsertFileInCase(uri, title, mimeType, caseID string
 	return nil
 }
 
+// insertFileInCaseStream uploads the file as a multipart content version and then links the document to the case
+func (s *SalesforceService) insertFileInCaseStream(span tracer.Span, content io.Reader, limitedReader *helpers.LimitedReader, title, mimeType, caseID string) error {
+	contentVersionID, errResponse := s.SfcClient.CreateContentVersionStream(span, salesforce.ContentVersionMetadata{
+		Title:           title,
+		ContentLocation: contentLocation,
+		PathOnClient:    helpers.GetExportFilename(title, mimeType),
+	}, content)
+	if limitedReader.Exceeded() {
+		span.SetTag(ext.Error, constants.ErrFileTooLarge)
+		return constants.ErrFileTooLarge
+	}
+
+	if errResponse != nil {
+		span.SetTag(ext.Error, errResponse.Error)
+		return errors.New(helpers.ErrorMessage("not insert file", errResponse.Error))
+	}
+
+	request := salesforce.CompositeRequest{
+		AllOrNone:          true,
+		CollateSubrequests: false,
+		CompositeRequest: []salesforce.Composite{
+			{
+				Method:      http.MethodGet,
+				URL:         s.SfcClient.GetSearchURL(fmt.Sprintf(queryContentDocumentID, contentVersionID)),
+				ReferenceId: "newQuery",
+			},
+			{
+				Method: http.MethodPost,
+				URL:    s.SfcClient.GetDocumentLinkURL(),
+				Body: salesforce.LinkDocumentPayload{
+					ContentDocumentID: linkReferenceID,
+					LinkedEntityID:    caseID,
+					ShareType:         shareType,
+					Visibility:        visibility,
+				},
+				ReferenceId: "newContentDocumentLink",
+			},
+		},
+	}
+
+	_, errResponse = s.SfcClient.Composite(span, request)
+	if errResponse != nil {
+		span.SetTag(ext.Error, errResponse.Error)
+		return errors.New(helpers.ErrorMessage("not link file", errResponse.Error))
+	}
+
+	return nil
+}
+
 func (s *SalesforceService) EndChat(affinityToken, sessionKey string) error {
 	return s.SfcChatClient.ChatEnd(affinityToken, sessionKey)
 }
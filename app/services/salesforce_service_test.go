@@ -3,7 +3,12 @@ package services
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -18,6 +23,7 @@ import (
 	"yalochat.com/salesforce-integration/base/clients/login"
 	"yalochat.com/salesforce-integration/base/clients/proxy"
 	"yalochat.com/salesforce-integration/base/clients/salesforce"
+	"yalochat.com/salesforce-integration/base/constants"
 	"yalochat.com/salesforce-integration/base/helpers"
 	"yalochat.com/salesforce-integration/base/models"
 )
@@ -34,6 +40,7 @@ const (
 	title             = "title"
 	versionData       = "iVBORw0KGgoAAAANSUhEUgAAACAAAAAgCAMAAABEpIrGAAAABGdBTUEAALGPC/xhBQAAAAFzUkdCAK7OHOkAAAAgY0hSTQAAeiYAAICEAAD6AAAAgOgAAHUwAADqYAAAOpgAABdwnLpRPAAAAHJQTFRFAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA////mlzVjAAAACR0Uk5TACtqaUMF7v7twmjrYAHI72QCbfNvBOo9RPFGBk5MS0jyPmPJVqU8vQAAAAFiS0dEJcMByQ8AAAAJcEhZcwABOvYAATr2ATqxVzoAAAC2SURBVDjLvdPJEoIwDAbg0BUKyI4ouPf9n1Fo0XG0yYnxP/T0zSRpG4ANEjHuw4QMAm5fUQIHejliAiTMcE6VSLMcbZJbvSusKisc2Jo1hJiBgZYQM+DQEcIBSgjlLmgR/T4E5DC4+WdxOJKPMk4nCf/IOJ3pQpdr03afzX/n1lsv1vF/Ut1jL/wFhkTpBQpWAQYFXrAaB04UD40DyLN0+YhhIAXjhiU4EOq9BxSwdIl1FyPYIE+RZBNEN1CCzQAAACV0RVh0ZGF0ZTpjcmVhdGUAMjAyMC0wNS0wN1QwOTowNzo0MCswMTowMJZQazMAAAAldEVYdGRhdGU6bW9kaWZ5ADIwMjAtMDUtMDdUMDk6MDc6NDArMDE6MDDnDdOPAAAARnRFWHRzb2Z0d2FyZQBJbWFnZU1hZ2ljayA2LjcuOC05IDIwMTktMDItMDEgUTE2IGh0dHA6Ly93d3cuaW1hZ2VtYWdpY2sub3JnQXviyAAAABh0RVh0VGh1bWI6OkRvY3VtZW50OjpQYWdlcwAxp/+7LwAAABh0RVh0VGh1bWI6OkltYWdlOjpoZWlnaHQANTEywNBQUQAAABd0RVh0VGh1bWI6OkltYWdlOjpXaWR0aAA1MTIcfAPcAAAAGXRFWHRUaHVtYjo6TWltZXR5cGUAaW1hZ2UvcG5nP7JWTgAAABd0RVh0VGh1bWI6Ok1UaW1lADE1ODg4Mzg4NjDthjAYAAAAEnRFWHRUaHVtYjo6U2l6ZQA1LjNLQkLfeornAAAASXRFWHRUaHVtYjo6VVJJAGZpbGU6Ly8uL3VwbG9hZHMvNTYvNDJhTzRoSC8yMzQ4L3NpemVfbWF4aW1pemVfaWNvbl8xNDI5NjgucG5nCRVuAQAAAABJRU5ErkJggg=="
 	recordTypeID      = "recordTypeID"
+	pngSignature      = "\x89PNG\r\n\x1a\n"
 )
 
 var (
@@ -118,7 +125,7 @@ func TestSalesforceService_CreatChat(t *testing.T) {
 		}
 		mockSfcChatInterface.On("CreateChat", mock.Anything, sessionExpected.AffinityToken, sessionExpected.Key, request).Return(false, nil).Once()
 
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcChatClient = mockSfcChatInterface
 
 		session, err := salesforceService.CreatChat(context.Background(), contactName, organizationID, deploymentID, buttonID, "caseId", "contactId")
@@ -138,7 +145,7 @@ func TestSalesforceService_CreatChat(t *testing.T) {
 		}
 		mockSfcChatInterface.On("CreateSession", mock.Anything).Return(sessionExpected, assert.AnError).Once()
 
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, nil, nil, make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, nil, nil, make(map[string]string))
 		salesforceService.SfcChatClient = mockSfcChatInterface
 
 		session, err := salesforceService.CreatChat(context.Background(), contactName, organizationID, deploymentID, buttonID, "caseId", "contactId")
@@ -161,7 +168,7 @@ func TestSalesforceService_CreatChat(t *testing.T) {
 		request := chat.ChatRequest{OrganizationId: "organizationID", DeploymentId: "deploymentID", ButtonId: buttonID, SessionId: "ec550263-354e-477c-b773-7747ebce3f5e", UserAgent: "Yalo Bot", Language: "es-MX", ScreenResolution: "1900x1080", VisitorName: "contactName", PrechatDetails: []chat.PreChatDetailsObject{{Label: "CaseId", Value: "caseId", DisplayToAgent: true, TranscriptFields: []string{"CaseId"}}, {Label: "ContactId", Value: "contactId", DisplayToAgent: true, TranscriptFields: []string{"ContactId"}}}, PrechatEntities: []chat.PrechatEntitiesObject{{EntityName: "Case", LinkToEntityName: "Case", LinkToEntityField: "Id", SaveToTranscript: "Case", ShowOnCreate: true, EntityFieldsMaps: []chat.EntityField{{FieldName: "Id", Label: "CaseId", DoFind: true, IsExactMatch: true, DoCreate: false}}}, {EntityName: "Contact", LinkToEntityName: "Contact", LinkToEntityField: "Id", SaveToTranscript: "Contact", ShowOnCreate: true, EntityFieldsMaps: []chat.EntityField{{FieldName: "Id", Label: "ContactId", DoFind: true, IsExactMatch: true, DoCreate: false}}}}, ReceiveQueueUpdates: true, IsPost: true}
 		mockSfcChatInterface.On("CreateChat", mock.Anything, sessionExpected.AffinityToken, sessionExpected.Key, request).Return(false, assert.AnError).Once()
 
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcChatClient = mockSfcChatInterface
 
 		session, err := salesforceService.CreatChat(context.Background(), contactName, organizationID, deploymentID, buttonID, "caseId", "contactId")
@@ -179,7 +186,7 @@ func TestSalesforceService_EndChat(t *testing.T) {
 
 	t.Run("End Chat Succesfull", func(t *testing.T) {
 		mock := new(mocks.SfcChatInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcChatClient = mock
 
 		mock.On("ChatEnd", affinityToken, sessionKey).Return(nil)
@@ -201,12 +208,12 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Get Contact by email Succesfull", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, email, phoneNumber, map[string]string{}, map[string]interface{}{}).Return(contactExpected, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -214,12 +221,12 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Get Contact by phone Succesfull", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, email, phoneNumber, map[string]string{}, map[string]interface{}{}).Return(contactExpected, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -227,12 +234,12 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Get Contact by custom field", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), map[string]string{"customerId": "Customer__ID__SF"}, make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), map[string]string{"customerId": "Customer__ID__SF"}, make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, email, phoneNumber, map[string]string{"customerId": "Customer__ID__SF"}, map[string]interface{}{"customerId": "1234"}).Return(contactExpected, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{"customerId": "1234"})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{"customerId": "1234"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -240,16 +247,17 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create Contact Succesfull with custom fields", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, map[string]string{"document": "SF_Document"}, make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, map[string]string{"document": "SF_Document"}, make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, email, phoneNumber, map[string]string{}, map[string]interface{}{"document": "123456"}).Return(nil, errorResponse).Once()
 
+		contactExpected.Created = true
 		payload := map[string]interface{}{"FirstName": contactExpected.FirstName, "LastName": contactExpected.LastName, "MobilePhone": phoneNumber, "Email": email, "SF_Document": "123456"}
 		mockSalesforceService.On("CreateContact", mock.Anything, payload).Return(contactExpected.ID, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{"document": "123456"})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{"document": "123456"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -257,7 +265,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create Contact Succesfull without custom fields", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
@@ -266,7 +274,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 		payload := map[string]interface{}{"FirstName": contactExpected.FirstName, "LastName": contactExpected.LastName, "MobilePhone": phoneNumber, "Email": email}
 		mockSalesforceService.On("CreateContact", mock.Anything, payload).Return(contactExpected.ID, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -274,7 +282,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create contact and account using the accountRecordTypeID from enviroment variable", func(t *testing.T) {
 		mockSalesforce := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.AccountRecordTypeId = "recordTypeID"
 		salesforceService.DefaultBirthDateAccount = "2023-01-12T17:11:22"
 		salesforceService.SfcClient = mockSalesforce
@@ -300,7 +308,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 			"RecordTypeID":      &salesforceService.AccountRecordTypeId,
 		}).Return(accountFound, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{"anyExtraData": "anyValue"})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{"anyExtraData": "anyValue"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -308,7 +316,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create contact and account getting accountRecordTypeID from extraData", func(t *testing.T) {
 		mockSalesforce := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.AccountRecordTypeId = "defaultAccountRecordTypeID"
 		salesforceService.DefaultBirthDateAccount = "2023-01-12T17:11:22"
 		salesforceService.SfcClient = mockSalesforce
@@ -337,7 +345,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 			"RecordTypeID":      &recordTypeID,
 		}).Return(accountCreated, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{"AccountRecordTypeId": "extraDataAccountRecordTypeID"})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{"AccountRecordTypeId": "extraDataAccountRecordTypeID"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -345,7 +353,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create contact and account with custom fields on the account request", func(t *testing.T) {
 		mockSalesforce := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), map[string]string{"companyDocument": "Company__Document__ID__SF"})
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), map[string]string{"companyDocument": "Company__Document__ID__SF"})
 		salesforceService.AccountRecordTypeId = "defaultAccountRecordTypeID"
 		salesforceService.DefaultBirthDateAccount = "2023-01-12T17:11:22"
 		salesforceService.SfcClient = mockSalesforce
@@ -376,7 +384,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 			"Company__Document__ID__SF": &companyDocument,
 		}).Return(accountCreated, nil).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{"AccountRecordTypeId": "extraDataAccountRecordTypeID", "companyDocument": "0987654321"})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{"AccountRecordTypeId": "extraDataAccountRecordTypeID", "companyDocument": "0987654321"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, contactExpected, contact)
@@ -384,7 +392,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Create Contact with account Error service", func(t *testing.T) {
 		mockSalesforce := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.AccountRecordTypeId = "recordTypeID"
 		salesforceService.SfcClient = mockSalesforce
 
@@ -397,7 +405,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 			Error:      assert.AnError,
 		}).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{})
 
 		assert.Error(t, err)
 		assert.Empty(t, contact)
@@ -405,7 +413,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Should not create contact the token is expired", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
@@ -418,7 +426,7 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 			Error:      assert.AnError,
 		}).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, email, phoneNumber, map[string]interface{}{})
 
 		assert.Error(t, err)
 		assert.Empty(t, contact)
@@ -426,13 +434,13 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Should return error invalid payload - name is empty", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, email, phoneNumber, map[string]string{}, map[string]interface{}{}).Return(nil, errorResponse).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), "", email, phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, "", email, phoneNumber, map[string]interface{}{})
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, `Invalid payload received : Key: 'ContactRequest.LastName' Error:Field validation for 'LastName' failed on the 'required' tag`)
@@ -441,13 +449,13 @@ func TestSalesforceService_GetOrCreateContact(t *testing.T) {
 
 	t.Run("Should return error invalid payload - email is empty", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
 		mockSalesforceService.On("SearchContactComposite", mock.Anything, "", phoneNumber, map[string]string{}, map[string]interface{}{}).Return(nil, errorResponse).Once()
 
-		contact, err := salesforceService.GetOrCreateContact(context.Background(), contactName, "", phoneNumber, map[string]interface{}{})
+		contact, err := salesforceService.GetOrCreateContact(context.Background(), firstNameDefault, contactName, "", phoneNumber, map[string]interface{}{})
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, `Invalid payload received : Key: 'ContactRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag`)
@@ -460,7 +468,7 @@ func TestSalesforceService_CreatCase(t *testing.T) {
 	t.Run("Create case Succesfull", func(t *testing.T) {
 		caseIDExpected := "14224111"
 		mockSaleforceInterface := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSaleforceInterface
 
 		payload := map[string]interface{}{"CP__source_flow_bot__c": "SFB001", "ContactId": "contactId", "Description": "Caso creado por yalo : subject", "Origin": "whatsapp", "OwnerId": "ownerWAID", "Priority": "low", "RecordTypeId": "recordTypeID", "Status": "Novo", "Subject": "subject"}
@@ -476,7 +484,7 @@ func TestSalesforceService_CreatCase(t *testing.T) {
 	t.Run("Create case Succesfull description", func(t *testing.T) {
 		caseIDExpected := "14224111"
 		mockSalesforce := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforce
 
 		payload := map[string]interface{}{"CP__source_flow_bot__c": "SFB001", "ContactId": "contactId", "Description": "description", "Origin": "whatsapp", "OwnerId": "ownerWAID", "Priority": "Medium", "RecordTypeId": "recordTypeID", "Status": "Nuevo", "Subject": "subject"}
@@ -492,7 +500,7 @@ func TestSalesforceService_CreatCase(t *testing.T) {
 	t.Run("Create case Error service", func(t *testing.T) {
 		caseIDExpected := "14224111"
 		mockSaleforceInterface := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSaleforceInterface
 
 		payload := map[string]interface{}{"CP__source_flow_bot__c": "SFB001", "ContactId": "contactId", "Description": "Caso creado por yalo : subject", "Origin": "whatsapp", "OwnerId": "ownerWAID", "Priority": "Medium", "RecordTypeId": "recordTypeID", "Status": "Nuevo", "Subject": "subject"}
@@ -511,7 +519,7 @@ func TestSalesforceService_CreatCase(t *testing.T) {
 	t.Run("Create case Error payload", func(t *testing.T) {
 
 		mock := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), "", firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), "", make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mock
 
 		salesforceService.SfcCustomFieldsCase = map[string]string{"source_flow_bot": "CP__source_flow_bot__c"}
@@ -525,7 +533,7 @@ func TestSalesforceService_CreatCase(t *testing.T) {
 func TestSalesforceService_SendMessage(t *testing.T) {
 	t.Run("Send message Succesfull", func(t *testing.T) {
 		mockSfcChatInterface := new(mocks.SfcChatInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcChatClient = mockSfcChatInterface
 
 		message := chat.MessagePayload{
@@ -544,7 +552,7 @@ func TestSalesforceService_SendMessage(t *testing.T) {
 func TestSalesforceService_GetMessages(t *testing.T) {
 	t.Run("Get message Succesfull", func(t *testing.T) {
 		mocksalesforceService := new(mocks.SfcChatInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcChatClient = mocksalesforceService
 
 		message := &chat.MessagesResponse{
@@ -571,7 +579,7 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 
 	t.Run("Insert file in case success", func(t *testing.T) {
 		salesforceMock := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = salesforceMock
 
 		salesforceMock.On("GetContentVersionURL").Return("contentVersionURL").Once()
@@ -622,7 +630,7 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 
 	t.Run("Insert file in case error get image", func(t *testing.T) {
 		salesforceMock := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = salesforceMock
 
 		contentVersion := salesforce.ContentVersionPayload{
@@ -640,7 +648,7 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 
 	t.Run("File not found error", func(t *testing.T) {
 		expectedError := "file not found"
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		err := salesforceService.InsertFileInCase("https://google.com/errr", title, mimeType, caseID)
 
 		assert.Error(t, err)
@@ -649,7 +657,7 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 
 	t.Run("Insert file in case error composite", func(t *testing.T) {
 		salesforceMock := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = salesforceMock
 
 		salesforceMock.On("GetContentVersionURL").Return("contentVersionURL").Once()
@@ -701,7 +709,7 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 
 	t.Run("Insert file in case success without mimetype", func(t *testing.T) {
 		salesforceMock := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = salesforceMock
 
 		salesforceMock.On("GetContentVersionURL").Return("contentVersionURL").Once()
@@ -750,6 +758,142 @@ func TestSalesforceService_InsertFileInCase(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+
+	t.Run("Insert file in case error file too large", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "20")
+			w.Write(bytes.Repeat([]byte("a"), 20))
+		}))
+		defer server.Close()
+
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+		salesforceService.MaxFileSize = 10
+
+		err := salesforceService.InsertFileInCase(server.URL, title, mimeType, caseID)
+
+		assert.ErrorIs(t, err, constants.ErrFileTooLarge)
+		salesforceMock.AssertNotCalled(t, "Composite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Insert file in case error file type not allowed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(append([]byte("MZ"), make([]byte, 100)...))
+		}))
+		defer server.Close()
+
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+		salesforceService.FileTypePolicy = helpers.FileTypePolicy{Allowed: []string{"image/*"}}
+
+		err := salesforceService.InsertFileInCase(server.URL, title, mimeType, caseID)
+
+		assert.ErrorIs(t, err, constants.ErrFileTypeNotAllowed)
+		salesforceMock.AssertNotCalled(t, "Composite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Insert big file in case with stream success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(append([]byte(pngSignature), bytes.Repeat([]byte("a"), compositeMaxFileSize+10)...))
+		}))
+		defer server.Close()
+
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+		salesforceService.MaxFileSize = compositeMaxFileSize * 2
+
+		metadata := salesforce.ContentVersionMetadata{
+			Title:           title,
+			ContentLocation: "S",
+			PathOnClient:    title + ".png",
+		}
+		var uploaded []byte
+		salesforceMock.On("CreateContentVersionStream", mock.Anything, metadata, mock.Anything).
+			Run(func(args mock.Arguments) {
+				uploaded, _ = ioutil.ReadAll(args.Get(2).(io.Reader))
+			}).Return(contentVersionID, nil).Once()
+
+		salesforceMock.On("GetSearchURL", fmt.Sprintf(queryContentDocumentID, contentVersionID)).Return("searchURL").Once()
+
+		salesforceMock.On("GetDocumentLinkURL").Return("documentLinkURL").Once()
+
+		request := salesforce.CompositeRequest{
+			AllOrNone:          true,
+			CollateSubrequests: false,
+			CompositeRequest: []salesforce.Composite{
+				{
+					Method:      http.MethodGet,
+					URL:         "searchURL",
+					ReferenceId: "newQuery",
+				},
+				{
+					Method: http.MethodPost,
+					URL:    "documentLinkURL",
+					Body: salesforce.LinkDocumentPayload{
+						ContentDocumentID: linkReferenceID,
+						LinkedEntityID:    caseID,
+						ShareType:         shareType,
+						Visibility:        visibility,
+					},
+					ReferenceId: "newContentDocumentLink",
+				},
+			},
+		}
+		salesforceMock.On("Composite", mock.Anything, request).Return(salesforce.CompositeResponses{}, nil).Once()
+
+		err := salesforceService.InsertFileInCase(server.URL, title, mimeType, caseID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, len(pngSignature)+compositeMaxFileSize+10, len(uploaded))
+		salesforceMock.AssertExpectations(t)
+	})
+
+	t.Run("Insert big file in case with stream error file too large", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(append([]byte(pngSignature), bytes.Repeat([]byte("a"), compositeMaxFileSize+10)...))
+		}))
+		defer server.Close()
+
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+		salesforceService.MaxFileSize = compositeMaxFileSize + 5
+
+		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusNotFound}
+		salesforceMock.On("CreateContentVersionStream", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				ioutil.ReadAll(args.Get(2).(io.Reader))
+			}).Return("", errorResponse).Once()
+
+		err := salesforceService.InsertFileInCase(server.URL, title, mimeType, caseID)
+
+		assert.ErrorIs(t, err, constants.ErrFileTooLarge)
+		salesforceMock.AssertNotCalled(t, "Composite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Insert big file in case with stream error link document", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(append([]byte(pngSignature), bytes.Repeat([]byte("a"), compositeMaxFileSize+10)...))
+		}))
+		defer server.Close()
+
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+
+		salesforceMock.On("CreateContentVersionStream", mock.Anything, mock.Anything, mock.Anything).Return(contentVersionID, nil).Once()
+		salesforceMock.On("GetSearchURL", mock.Anything).Return("searchURL").Once()
+		salesforceMock.On("GetDocumentLinkURL").Return("documentLinkURL").Once()
+		errorResponse := &helpers.ErrorResponse{Error: assert.AnError, StatusCode: http.StatusUnauthorized}
+		salesforceMock.On("Composite", mock.Anything, mock.Anything).Return(salesforce.CompositeResponses{}, errorResponse).Once()
+
+		err := salesforceService.InsertFileInCase(server.URL, title, mimeType, caseID)
+
+		assert.Error(t, err)
+	})
 }
 
 func TestSalesforceService_RefreshToken(t *testing.T) {
@@ -763,7 +907,7 @@ func TestSalesforceService_RefreshToken(t *testing.T) {
 			Password:     "password",
 		}
 		mock := new(mocks.SfcLoginInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{Proxy: &proxy.Proxy{}}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, tokenPayload, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{Proxy: &proxy.Proxy{}}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, tokenPayload, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcLoginClient = mock
 		mock.On("GetToken", tokenPayload).Return(accessToken, nil).Once()
 
@@ -783,7 +927,7 @@ func TestSalesforceService_RefreshToken(t *testing.T) {
 func TestSalesforceService_SearchContactComposite(t *testing.T) {
 	t.Run("Get message Succesfull", func(t *testing.T) {
 		mockSalesforceService := new(mocks.SaleforceInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 		salesforceService.SfcClient = mockSalesforceService
 
 		contact := &models.SfcContact{
@@ -804,7 +948,7 @@ func TestSalesforceService_ReconnectSession(t *testing.T) {
 
 	t.Run("Get reconnect Succesfull", func(t *testing.T) {
 		mock := new(mocks.SfcChatInterface)
-		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, firstNameDefault, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
 
 		salesforceService.SfcChatClient = mock
 		message := &chat.MessagesResponse{
@@ -825,3 +969,191 @@ func TestSalesforceService_ReconnectSession(t *testing.T) {
 	})
 
 }
+
+func TestSalesforceService_GetCaseNumber(t *testing.T) {
+	query := fmt.Sprintf(queryCaseNumber, "caseID")
+
+	t.Run("Get case number Succesfull", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+
+		response := &salesforce.SearchResponse{}
+		json.Unmarshal([]byte(`{"totalSize":1,"done":true,"records":[{"CaseNumber":"00001001"}]}`), response)
+		mockSalesforce.On("Search", query).Return(response, nil).Once()
+
+		caseNumber, err := salesforceService.GetCaseNumber(context.Background(), "caseID")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00001001", caseNumber)
+	})
+
+	t.Run("Get case number not found", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+
+		mockSalesforce.On("Search", query).Return(&salesforce.SearchResponse{}, nil).Once()
+
+		caseNumber, err := salesforceService.GetCaseNumber(context.Background(), "caseID")
+
+		assert.Error(t, err)
+		assert.Empty(t, caseNumber)
+	})
+
+	t.Run("Get case number Error service", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+
+		mockSalesforce.On("Search", query).Return(nil, &helpers.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      assert.AnError,
+		}).Once()
+
+		caseNumber, err := salesforceService.GetCaseNumber(context.Background(), "caseID")
+
+		assert.Error(t, err)
+		assert.Empty(t, caseNumber)
+	})
+}
+
+func TestSalesforceService_InsertCommentInCase(t *testing.T) {
+	request := salesforce.CaseCommentRequest{ParentID: "caseID", CommentBody: "Your case was resolved"}
+
+	t.Run("Insert comment Succesfull", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+
+		mockSalesforce.On("CreateCaseComment", mock.Anything, request).Return("commentID", nil).Once()
+
+		err := salesforceService.InsertCommentInCase(context.Background(), "caseID", "Your case was resolved")
+
+		assert.NoError(t, err)
+		mockSalesforce.AssertExpectations(t)
+	})
+
+	t.Run("Insert comment Error service", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+
+		mockSalesforce.On("CreateCaseComment", mock.Anything, request).Return("", &helpers.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      assert.AnError,
+		}).Once()
+
+		err := salesforceService.InsertCommentInCase(context.Background(), "caseID", "Your case was resolved")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSalesforceService_CheckToken(t *testing.T) {
+	t.Run("Check token Succesfull", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+		mockSalesforce.On("CheckToken", mock.Anything).Return(nil).Once()
+
+		err := salesforceService.CheckToken(context.Background())
+
+		assert.NoError(t, err)
+		mockSalesforce.AssertExpectations(t)
+	})
+
+	t.Run("Check token expired refreshes the token", func(t *testing.T) {
+		mockSalesforce := new(mocks.SaleforceInterface)
+		mockChat := new(mocks.SfcChatInterface)
+		mockLogin := new(mocks.SfcLoginInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = mockSalesforce
+		salesforceService.SfcChatClient = mockChat
+		salesforceService.SfcLoginClient = mockLogin
+		mockSalesforce.On("CheckToken", mock.Anything).Return(&helpers.ErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      assert.AnError,
+		}).Once()
+		mockLogin.On("GetToken", login.TokenPayload{}).Return("newToken", nil).Once()
+		mockChat.On("UpdateToken", "newToken").Once()
+		mockSalesforce.On("UpdateToken", "newToken").Once()
+
+		err := salesforceService.CheckToken(context.Background())
+
+		assert.ErrorIs(t, err, assert.AnError)
+		mockLogin.AssertExpectations(t)
+		mockSalesforce.AssertExpectations(t)
+	})
+}
+
+func TestSalesforceService_InsertContentInCase(t *testing.T) {
+	content := []byte("Cliente [31-08-2021 05:00:00]:Hola")
+	request := salesforce.CompositeRequest{
+		AllOrNone:          true,
+		CollateSubrequests: false,
+		CompositeRequest: []salesforce.Composite{
+			{
+				Method: http.MethodPost,
+				URL:    "contentVersionURL",
+				Body: salesforce.ContentVersionPayload{
+					Title:           "Transcript",
+					ContentLocation: "S",
+					PathOnClient:    "transcript.txt",
+					VersionData:     string(helpers.Encode(content)),
+				},
+				ReferenceId: "newContentVersion",
+			},
+			{
+				Method:      http.MethodGet,
+				URL:         "searchURL",
+				ReferenceId: "newQuery",
+			},
+			{
+				Method: http.MethodPost,
+				URL:    "documentLinkURL",
+				Body: salesforce.LinkDocumentPayload{
+					ContentDocumentID: linkReferenceID,
+					LinkedEntityID:    caseID,
+					ShareType:         shareType,
+					Visibility:        visibility,
+				},
+				ReferenceId: "newContentDocumentLink",
+			},
+		},
+	}
+
+	t.Run("Insert content in case success", func(t *testing.T) {
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+
+		salesforceMock.On("GetContentVersionURL").Return("contentVersionURL").Once()
+		salesforceMock.On("GetSearchURL", queryContentDocumentIDByID).Return("searchURL").Once()
+		salesforceMock.On("GetDocumentLinkURL").Return("documentLinkURL").Once()
+		salesforceMock.On("Composite", mock.Anything, request).Return(salesforce.CompositeResponses{}, nil).Once()
+
+		err := salesforceService.InsertContentInCase(context.Background(), content, "Transcript", "transcript.txt", caseID)
+
+		assert.NoError(t, err)
+		salesforceMock.AssertExpectations(t)
+	})
+
+	t.Run("Insert content in case error composite", func(t *testing.T) {
+		salesforceMock := new(mocks.SaleforceInterface)
+		salesforceService := NewSalesforceService(login.SfcLoginClient{}, chat.SfcChatClient{}, salesforce.SalesforceClient{}, login.TokenPayload{}, make(map[string]string), recordTypeID, make(map[string]string), make(map[string]string), make(map[string]string))
+		salesforceService.SfcClient = salesforceMock
+
+		salesforceMock.On("GetContentVersionURL").Return("contentVersionURL").Once()
+		salesforceMock.On("GetSearchURL", queryContentDocumentIDByID).Return("searchURL").Once()
+		salesforceMock.On("GetDocumentLinkURL").Return("documentLinkURL").Once()
+		salesforceMock.On("Composite", mock.Anything, request).Return(salesforce.CompositeResponses{}, &helpers.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      assert.AnError,
+		}).Once()
+
+		err := salesforceService.InsertContentInCase(context.Background(), content, "Transcript", "transcript.txt", caseID)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+const (
+	chatRequestKeyTemplate      = "%s:%s:%s:chat-request"
+	asyncChatRequestKeyTemplate = "%s:%s:async-chat-request"
+	chatRequestLockKeyTemplate  = "%s:%s:chat-request-lock"
+)
+
+// ChatRequestStatus is the status of an asynchronous chat request
+type ChatRequestStatus string
+
+const (
+	ChatRequestPending  ChatRequestStatus = "pending"
+	ChatRequestCreated  ChatRequestStatus = "created"
+	ChatRequestBlocked  ChatRequestStatus = "blocked"
+	ChatRequestNoAgents ChatRequestStatus = "no_agents"
+	ChatRequestError    ChatRequestStatus = "error"
+)
+
+// ChatRequest is the outcome of a request to create a chat, it is stored by user and idempotency key
+// so that the retries of the request return the original result and a user cannot read the result of another user.
+// The asynchronous requests are stored by request ID with their status, in a keyspace apart from the idempotency keys
+type ChatRequest struct {
+	Client    string            `json:"client"`
+	Key       string            `json:"key"`
+	UserID    string            `json:"userID"`
+	Status    ChatRequestStatus `json:"status,omitempty"`
+	CaseID    string            `json:"caseId,omitempty"`
+	Error     string            `json:"error"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+type ChatRequestCache struct {
+	cache *RedisCache
+}
+
+func NewChatRequestCache(cache *RedisCache) *ChatRequestCache {
+	return &ChatRequestCache{cache: cache}
+}
+
+// IChatRequestCache interface that holds method to store the chat requests and to lock the users while a chat is created
+type IChatRequestCache interface {
+	LockUser(client, userID, token string, ttl time.Duration) (bool, error)
+	UnlockUser(client, userID, token string) error
+	StoreChatRequest(chatRequest ChatRequest, ttl time.Duration) error
+	RetrieveChatRequest(client, userID, key string) (*ChatRequest, error)
+	CreateAsyncChatRequest(chatRequest ChatRequest, ttl time.Duration) (bool, error)
+	StoreAsyncChatRequest(chatRequest ChatRequest, ttl time.Duration) error
+	RetrieveAsyncChatRequest(client, requestID string) (*ChatRequest, error)
+}
+
+// LockUser takes the lock of the user with the token of the request, it returns false when another request has it
+func (rc *ChatRequestCache) LockUser(client, userID, token string, ttl time.Duration) (bool, error) {
+	return rc.cache.StoreDataIfNotExists(fmt.Sprintf(chatRequestLockKeyTemplate, client, userID), []byte(token), ttl)
+}
+
+// UnlockUser releases the lock of the user only when it has the token of the request, so a request does not release
+// the lock that another request took after it expired
+func (rc *ChatRequestCache) UnlockUser(client, userID, token string) error {
+	_, err := rc.cache.DeleteDataIfEquals(fmt.Sprintf(chatRequestLockKeyTemplate, client, userID), token)
+	return err
+}
+
+// StoreChatRequest saves the outcome of the chat request by its user and key
+func (rc *ChatRequestCache) StoreChatRequest(chatRequest ChatRequest, ttl time.Duration) error {
+	data, _ := json.Marshal(chatRequest)
+	return rc.cache.StoreData(fmt.Sprintf(chatRequestKeyTemplate, chatRequest.Client, chatRequest.UserID, chatRequest.Key), data, ttl)
+}
+
+// RetrieveChatRequest returns the outcome of the chat request of the user with the key
+func (rc *ChatRequestCache) RetrieveChatRequest(client, userID, key string) (*ChatRequest, error) {
+	return rc.retrieveChatRequest(fmt.Sprintf(chatRequestKeyTemplate, client, userID, key))
+}
+
+// CreateAsyncChatRequest saves the asynchronous chat request only when there is no request with its ID,
+// it returns false otherwise
+func (rc *ChatRequestCache) CreateAsyncChatRequest(chatRequest ChatRequest, ttl time.Duration) (bool, error) {
+	data, _ := json.Marshal(chatRequest)
+	return rc.cache.StoreDataIfNotExists(fmt.Sprintf(asyncChatRequestKeyTemplate, chatRequest.Client, chatRequest.Key), data, ttl)
+}
+
+// StoreAsyncChatRequest saves the status of the asynchronous chat request by its ID
+func (rc *ChatRequestCache) StoreAsyncChatRequest(chatRequest ChatRequest, ttl time.Duration) error {
+	data, _ := json.Marshal(chatRequest)
+	return rc.cache.StoreData(fmt.Sprintf(asyncChatRequestKeyTemplate, chatRequest.Client, chatRequest.Key), data, ttl)
+}
+
+// RetrieveAsyncChatRequest returns the asynchronous chat request with the requestID
+func (rc *ChatRequestCache) RetrieveAsyncChatRequest(client, requestID string) (*ChatRequest, error) {
+	return rc.retrieveChatRequest(fmt.Sprintf(asyncChatRequestKeyTemplate, client, requestID))
+}
+
+func (rc *ChatRequestCache) retrieveChatRequest(key string) (*ChatRequest, error) {
+	data, err := rc.cache.RetrieveData(key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, constants.ErrChatRequestNotFound
+		}
+		return nil, err
+	}
+
+	var chatRequest ChatRequest
+	if err := json.Unmarshal([]byte(data), &chatRequest); err != nil {
+		return nil, err
+	}
+	return &chatRequest, nil
+}
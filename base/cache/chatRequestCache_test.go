@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+func TestChatRequestCache(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	}
+	rcs, _ := NewRedisCache(opts)
+	cache := NewChatRequestCache(rcs)
+
+	t.Run("Should lock the user only once", func(t *testing.T) {
+		ok, err := cache.LockUser("client", "userID", "token", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = cache.LockUser("client", "userID", "otherToken", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = cache.LockUser("client", "otherUserID", "otherToken", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		assert.NoError(t, cache.UnlockUser("client", "userID", "token"))
+		ok, err = cache.LockUser("client", "userID", "otherToken", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Should release the lock when it expires", func(t *testing.T) {
+		ok, err := cache.LockUser("client", "expiredUserID", "token", time.Second)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		m.FastForward(2 * time.Second)
+		ok, err = cache.LockUser("client", "expiredUserID", "otherToken", time.Second)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Shouldn't release the lock of another request", func(t *testing.T) {
+		ok, err := cache.LockUser("client", "lockedUserID", "newToken", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		assert.NoError(t, cache.UnlockUser("client", "lockedUserID", "expiredToken"))
+		ok, err = cache.LockUser("client", "lockedUserID", "otherToken", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		assert.NoError(t, cache.UnlockUser("client", "lockedUserID", "newToken"))
+		ok, err = cache.LockUser("client", "lockedUserID", "otherToken", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Should store and retrieve the chat request", func(t *testing.T) {
+		chatRequest := ChatRequest{
+			Client:    "client",
+			Key:       "key",
+			UserID:    "userID",
+			Error:     "could not create chat",
+			Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		assert.NoError(t, cache.StoreChatRequest(chatRequest, time.Minute))
+
+		stored, err := cache.RetrieveChatRequest("client", "userID", "key")
+		assert.NoError(t, err)
+		assert.Equal(t, chatRequest, *stored)
+	})
+
+	t.Run("Shouldn't return the chat request of another user with the same key", func(t *testing.T) {
+		chatRequest := ChatRequest{Client: "client", Key: "userKey", UserID: "userID", Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+		assert.NoError(t, cache.StoreChatRequest(chatRequest, time.Minute))
+
+		stored, err := cache.RetrieveChatRequest("client", "otherUserID", "userKey")
+		assert.ErrorIs(t, err, constants.ErrChatRequestNotFound)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("Should create the asynchronous chat request only once", func(t *testing.T) {
+		chatRequest := ChatRequest{
+			Client:    "client",
+			Key:       "requestID",
+			UserID:    "userID",
+			Status:    ChatRequestPending,
+			Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		ok, err := cache.CreateAsyncChatRequest(chatRequest, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		duplicated := chatRequest
+		duplicated.Status = ChatRequestCreated
+		ok, err = cache.CreateAsyncChatRequest(duplicated, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		stored, err := cache.RetrieveAsyncChatRequest("client", "requestID")
+		assert.NoError(t, err)
+		assert.Equal(t, chatRequest, *stored)
+	})
+
+	t.Run("Should keep the asynchronous chat requests apart from the idempotency keys", func(t *testing.T) {
+		chatRequest := ChatRequest{Client: "client", Key: "sharedKey", UserID: "userID", Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+		assert.NoError(t, cache.StoreChatRequest(chatRequest, time.Minute))
+
+		ok, err := cache.CreateAsyncChatRequest(chatRequest, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		asyncRequest := chatRequest
+		asyncRequest.Status = ChatRequestNoAgents
+		assert.NoError(t, cache.StoreAsyncChatRequest(asyncRequest, time.Minute))
+
+		stored, err := cache.RetrieveChatRequest("client", "userID", "sharedKey")
+		assert.NoError(t, err)
+		assert.Equal(t, chatRequest, *stored)
+		stored, err = cache.RetrieveAsyncChatRequest("client", "sharedKey")
+		assert.NoError(t, err)
+		assert.Equal(t, asyncRequest, *stored)
+	})
+
+	t.Run("Should return not found when the chat request does not exist", func(t *testing.T) {
+		stored, err := cache.RetrieveChatRequest("client", "userID", "unknown")
+		assert.ErrorIs(t, err, constants.ErrChatRequestNotFound)
+		assert.Nil(t, stored)
+
+		stored, err = cache.RetrieveAsyncChatRequest("client", "unknown")
+		assert.ErrorIs(t, err, constants.ErrChatRequestNotFound)
+		assert.Nil(t, stored)
+	})
+}
@@ -35,7 +35,11 @@ type Interconnection struct {
 	Email         string                 `json:"email"`
 	PhoneNumber   string                 `json:"phoneNumber"`
 	CaseID        string                 `json:"caseID"`
+	CaseNumber    string                 `json:"caseNumber"`
 	ExtraData     map[string]interface{} `json:"extraData"`
+	// LastUserMessageAt and WindowTemplateSentAt keep the 24-hour window of whatsapp of the chat
+	LastUserMessageAt    time.Time `json:"lastUserMessageAt"`
+	WindowTemplateSentAt time.Time `json:"windowTemplateSentAt"`
 }
 
 type InterconnectionCache struct {
@@ -53,6 +57,7 @@ type IInterconnectionCache interface {
 	DeleteAllInterconnections() error
 	DeleteInterconnection(Interconnection) (bool, error)
 	RetrieveAllInterconnections(client string) *[]Interconnection
+	FinishInterconnection(interconnection Interconnection, status string, finishedStatuses ...string) (bool, error)
 }
 
 // assembleKey retrive key by template
@@ -81,6 +86,27 @@ func (rc *InterconnectionCache) RetrieveInterconnection(interconnection Intercon
 	return &redisInterconnection, nil
 }
 
+// FinishInterconnection sets the status of the interconnection in the Cache unless it already has one of the
+// finished statuses, it returns false when the interconnection was already finished so only one caller ends the chat
+func (rc *InterconnectionCache) FinishInterconnection(interconnection Interconnection, status string, finishedStatuses ...string) (bool, error) {
+	finished, err := rc.cache.UpdateData(assembleKey(interconnection), Ttl, func(data string) ([]byte, bool) {
+		var redisInterconnection Interconnection
+		json.Unmarshal([]byte(data), &redisInterconnection)
+		for _, finishedStatus := range finishedStatuses {
+			if redisInterconnection.Status == finishedStatus {
+				return nil, false
+			}
+		}
+		redisInterconnection.Status = status
+		newData, _ := json.Marshal(redisInterconnection)
+		return newData, true
+	})
+	if errors.Is(err, redis.Nil) {
+		return false, constants.ErrInterconnectionNotFound
+	}
+	return finished, err
+}
+
 // RetrieveAllInterconnections returns interconnections array from the Cache
 func (rc *InterconnectionCache) RetrieveAllInterconnections(client string) *[]Interconnection {
 	var redisInterconnectionsArray []Interconnection
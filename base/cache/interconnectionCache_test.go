@@ -324,3 +324,51 @@ func TestDeleteInterconnection(t *testing.T) {
 		}
 	})
 }
+
+func TestFinishInterconnection(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+		SessionsTTL: time.Second * 2,
+	}
+	rcs, _ := NewRedisCache(opts)
+
+	cache := NewInterconnectionCache(rcs)
+
+	t.Run("Should finish an open interconnection only once", func(t *testing.T) {
+		interconnection := Interconnection{Client: "client", UserID: "userID", Status: "ACTIVE", CaseID: "caseID"}
+		cache.StoreInterconnection(interconnection)
+
+		finished, err := cache.FinishInterconnection(interconnection, "CLOSED", "CLOSED", "FAILED")
+		assert.NoError(t, err)
+		assert.True(t, finished)
+
+		finished, err = cache.FinishInterconnection(interconnection, "CLOSED", "CLOSED", "FAILED")
+		assert.NoError(t, err)
+		assert.False(t, finished)
+
+		actual, _ := cache.RetrieveInterconnection(interconnection)
+		assert.Equal(t, "CLOSED", actual.Status)
+		assert.Equal(t, "caseID", actual.CaseID)
+	})
+
+	t.Run("Should return not found when the interconnection does not exist", func(t *testing.T) {
+		finished, err := cache.FinishInterconnection(Interconnection{Client: "client", UserID: "unknown"}, "CLOSED", "CLOSED")
+		assert.ErrorIs(t, err, constants.ErrInterconnectionNotFound)
+		assert.False(t, finished)
+	})
+
+	t.Run("Should fail when redis fails", func(t *testing.T) {
+		m.SetError("error")
+		defer m.SetError("")
+
+		finished, err := cache.FinishInterconnection(Interconnection{Client: "client", UserID: "userID"}, "CLOSED", "CLOSED")
+		assert.Error(t, err)
+		assert.False(t, finished)
+	})
+}
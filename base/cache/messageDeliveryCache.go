@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+const messageDeliveryKeyTemplate = "%s:message-delivery:%s"
+
+// MessageDeliveryStatus is the status of a message sent to the user, as the provider reports it
+type MessageDeliveryStatus string
+
+const (
+	MessageAccepted  MessageDeliveryStatus = "accepted"
+	MessageSent      MessageDeliveryStatus = "sent"
+	MessageDelivered MessageDeliveryStatus = "delivered"
+	MessageRead      MessageDeliveryStatus = "read"
+	MessageFailed    MessageDeliveryStatus = "failed"
+)
+
+// messageDeliveryOrder is the order of the statuses, a status only replaces the previous ones because the
+// status updates can arrive out of order
+var messageDeliveryOrder = map[MessageDeliveryStatus]int{
+	MessageAccepted:  0,
+	MessageSent:      1,
+	MessageDelivered: 2,
+	MessageRead:      3,
+	MessageFailed:    4,
+}
+
+// Follows returns true when the status comes after the current status, the unknown statuses don't follow any
+func (s MessageDeliveryStatus) Follows(current MessageDeliveryStatus) bool {
+	order, ok := messageDeliveryOrder[s]
+	return ok && order > messageDeliveryOrder[current]
+}
+
+// MessageDelivery is a message sent to the user through Integrations, it is stored by the ID that Integrations
+// returned with the session of the chat where it was sent. The ID is unique by client, so the status updates
+// match the message even when their sender is not the user
+type MessageDelivery struct {
+	ID        string                `json:"id"`
+	MessageID string                `json:"messageId"`
+	Client    string                `json:"client"`
+	UserID    string                `json:"userId"`
+	SessionID string                `json:"sessionId"`
+	Provider  string                `json:"provider"`
+	Status    MessageDeliveryStatus `json:"status"`
+	SentAt    time.Time             `json:"sentAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+type MessageDeliveryCache struct {
+	cache *RedisCache
+}
+
+func NewMessageDeliveryCache(cache *RedisCache) *MessageDeliveryCache {
+	return &MessageDeliveryCache{cache: cache}
+}
+
+// IMessageDeliveryCache interface that holds method to store the messages sent to the users with their status
+type IMessageDeliveryCache interface {
+	StoreMessageDelivery(delivery MessageDelivery, ttl time.Duration) error
+	RetrieveMessageDelivery(client, id string) (*MessageDelivery, error)
+}
+
+// StoreMessageDelivery saves the message sent to the user by the ID of Integrations
+func (mc *MessageDeliveryCache) StoreMessageDelivery(delivery MessageDelivery, ttl time.Duration) error {
+	data, _ := json.Marshal(delivery)
+	return mc.cache.StoreData(fmt.Sprintf(messageDeliveryKeyTemplate, delivery.Client, delivery.ID), data, ttl)
+}
+
+// RetrieveMessageDelivery returns the message sent to the user with the ID of Integrations
+func (mc *MessageDeliveryCache) RetrieveMessageDelivery(client, id string) (*MessageDelivery, error) {
+	data, err := mc.cache.RetrieveData(fmt.Sprintf(messageDeliveryKeyTemplate, client, id))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, constants.ErrMessageDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	var delivery MessageDelivery
+	if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+func TestMessageDeliveryCache(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	}
+	rcs, _ := NewRedisCache(opts)
+	cache := NewMessageDeliveryCache(rcs)
+	delivery := MessageDelivery{
+		ID:        "wamid.1",
+		MessageID: "messageID",
+		Client:    "client",
+		UserID:    "userID",
+		SessionID: "sessionID",
+		Provider:  "whatsapp",
+		Status:    MessageAccepted,
+		SentAt:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Should store and retrieve the message delivery", func(t *testing.T) {
+		assert.NoError(t, cache.StoreMessageDelivery(delivery, time.Minute))
+
+		actual, err := cache.RetrieveMessageDelivery("client", "wamid.1")
+		assert.NoError(t, err)
+		assert.Equal(t, &delivery, actual)
+	})
+
+	t.Run("Should not find the message delivery of another client", func(t *testing.T) {
+		actual, err := cache.RetrieveMessageDelivery("otherClient", "wamid.1")
+		assert.ErrorIs(t, err, constants.ErrMessageDeliveryNotFound)
+		assert.Nil(t, actual)
+	})
+
+	t.Run("Should expire the message delivery", func(t *testing.T) {
+		assert.NoError(t, cache.StoreMessageDelivery(delivery, time.Second))
+
+		m.FastForward(2 * time.Second)
+		_, err := cache.RetrieveMessageDelivery("client", "wamid.1")
+		assert.ErrorIs(t, err, constants.ErrMessageDeliveryNotFound)
+	})
+}
+
+func TestMessageDeliveryStatus_Follows(t *testing.T) {
+	t.Run("Should follow the previous statuses", func(t *testing.T) {
+		assert.True(t, MessageSent.Follows(MessageAccepted))
+		assert.True(t, MessageRead.Follows(MessageSent))
+		assert.True(t, MessageFailed.Follows(MessageDelivered))
+	})
+
+	t.Run("Should not follow the same or the next statuses", func(t *testing.T) {
+		assert.False(t, MessageSent.Follows(MessageSent))
+		assert.False(t, MessageDelivered.Follows(MessageRead))
+		assert.False(t, MessageRead.Follows(MessageFailed))
+	})
+
+	t.Run("Should not follow with an unknown status", func(t *testing.T) {
+		assert.False(t, MessageDeliveryStatus("deleted").Follows(MessageAccepted))
+	})
+}
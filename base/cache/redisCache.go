@@ -2,6 +2,7 @@ package cache
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -17,8 +18,27 @@ var (
 
 const (
 	countScan int64 = 10
+	// updateDataAttempts is the number of times UpdateData runs the transaction when the key changes
+	updateDataAttempts = 3
 )
 
+// claimSortedSetScript moves the member to the score ARGV[3] only when its score is up to ARGV[2], so only one
+// client claims it
+var claimSortedSetScript = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if score and tonumber(score) <= tonumber(ARGV[2]) then
+	redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+	return 1
+end
+return 0`)
+
+// deleteIfEqualsScript deletes the key only when its value is ARGV[1]
+var deleteIfEqualsScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
 // CommonRedisCache interface that holds method to retrieve cached sessions
 type CommonRedisCache interface {
 	StoreData(string, []byte, time.Duration) error
@@ -64,6 +84,15 @@ func NewRedisCache(config *RedisOptions) (*RedisCache, error) {
 
 // ping tests connectivity for redis (PONG should be returned)
 func (rc *RedisCache) ping() error {
+	if err := rc.Ping(); err != nil {
+		return err
+	}
+	log.Info("Connected to redis")
+	return nil
+}
+
+// Ping checks that redis answers with PONG
+func (rc *RedisCache) Ping() error {
 	pong, err := rc.client.Ping().Result()
 	if err != nil {
 		return err
@@ -71,7 +100,6 @@ func (rc *RedisCache) ping() error {
 	if pong != "PONG" {
 		return ErrGettingRedis
 	}
-	log.Info("Connected to redis")
 	return nil
 }
 
@@ -84,6 +112,56 @@ func (rc *RedisCache) StoreData(key string, data []byte, ttl time.Duration) erro
 	return nil
 }
 
+// StoreDataIfNotExists saves the data only when the key does not exist, it returns false when the key exists
+func (rc *RedisCache) StoreDataIfNotExists(key string, data []byte, ttl time.Duration) (bool, error) {
+	return rc.client.SetNX(key, data, ttl).Result()
+}
+
+// DeleteData deletes the data of the key
+func (rc *RedisCache) DeleteData(key string) error {
+	return rc.client.Del(key).Err()
+}
+
+// DeleteDataIfEquals deletes the data of the key only when it is equal to the data, it returns false when the key
+// has other data or does not exist
+func (rc *RedisCache) DeleteDataIfEquals(key, data string) (bool, error) {
+	deleted, err := deleteIfEqualsScript.Run(rc.client, []string{key}, data).Int()
+	return deleted > 0, err
+}
+
+// UpdateData replaces the data of the key with the data returned by update in a transaction, update returns false
+// to keep the data. The update is retried when the key changes during the transaction, it returns false when the
+// data was kept
+func (rc *RedisCache) UpdateData(key string, ttl time.Duration, update func(data string) ([]byte, bool)) (bool, error) {
+	updated := false
+	transaction := func(tx *redis.Tx) error {
+		data, err := tx.Get(key).Result()
+		if err != nil {
+			return err
+		}
+		newData, ok := update(data)
+		if !ok {
+			return nil
+		}
+		_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(key, newData, ttl)
+			return nil
+		})
+		updated = err == nil
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < updateDataAttempts; attempt++ {
+		updated = false
+		err = rc.client.Watch(transaction, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	return updated, err
+}
+
 // RetrieveData returns a user session from the Session Cache
 func (rc *RedisCache) RetrieveData(key string) (string, error) {
 	data, err := rc.client.Get(key).Result()
@@ -159,3 +237,61 @@ func (rc *RedisCache) DeleteSet(key string) error {
 	}
 	return nil
 }
+
+// StoreDataToSortedSet saves the data to the sorted set with the score
+func (rc *RedisCache) StoreDataToSortedSet(key string, data []byte, score float64) error {
+	return rc.client.ZAdd(key, redis.Z{Score: score, Member: data}).Err()
+}
+
+// RetrieveDataFromSortedSet returns the data of the sorted set with score up to max, the lowest scores first
+func (rc *RedisCache) RetrieveDataFromSortedSet(key string, max float64, count int64) ([]string, error) {
+	return rc.client.ZRangeByScore(key, redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatFloat(max, 'f', -1, 64),
+		Count: count,
+	}).Result()
+}
+
+// DeleteDataFromSortedSet deletes the data from the sorted set, it returns false when the data was not in the set
+func (rc *RedisCache) DeleteDataFromSortedSet(key, data string) (bool, error) {
+	deleted, err := rc.client.ZRem(key, data).Result()
+	return deleted > 0, err
+}
+
+// ClaimDataFromSortedSet moves the data of the sorted set to the score when its score is up to max, it returns false
+// when the data is not due or another client claimed it first
+func (rc *RedisCache) ClaimDataFromSortedSet(key, data string, max, score float64) (bool, error) {
+	claimed, err := claimSortedSetScript.Run(rc.client, []string{key}, data,
+		strconv.FormatFloat(max, 'f', -1, 64), strconv.FormatFloat(score, 'f', -1, 64)).Int()
+	return claimed == 1, err
+}
+
+// StoreDataToHash saves the data in the field of the hash
+func (rc *RedisCache) StoreDataToHash(key, field string, data []byte) error {
+	return rc.client.HSet(key, field, data).Err()
+}
+
+// RetrieveDataFromHash returns the data of the field of the hash
+func (rc *RedisCache) RetrieveDataFromHash(key, field string) (string, error) {
+	return rc.client.HGet(key, field).Result()
+}
+
+// DeleteDataFromHash deletes the field of the hash
+func (rc *RedisCache) DeleteDataFromHash(key, field string) error {
+	return rc.client.HDel(key, field).Err()
+}
+
+// StoreDataToList saves the data at the head of the list and keeps only the newest size elements
+func (rc *RedisCache) StoreDataToList(key string, data []byte, size int64) error {
+	_, err := rc.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LPush(key, data)
+		pipe.LTrim(key, 0, size-1)
+		return nil
+	})
+	return err
+}
+
+// RetrieveDataFromList returns the data of the list from the head
+func (rc *RedisCache) RetrieveDataFromList(key string) ([]string, error) {
+	return rc.client.LRange(key, 0, -1).Result()
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+const usedRefreshTokenKeyTemplate = "%s:%s:used-refresh-token"
+
+type RefreshTokenCache struct {
+	cache *RedisCache
+}
+
+func NewRefreshTokenCache(cache *RedisCache) *RefreshTokenCache {
+	return &RefreshTokenCache{cache: cache}
+}
+
+// IRefreshTokenCache interface that holds method to remember the refresh tokens used in all the instances
+type IRefreshTokenCache interface {
+	UseRefreshToken(client, jti string, ttl time.Duration) (bool, error)
+}
+
+// UseRefreshToken saves the jti of a refresh token until it expires, it returns false when the token was already used
+func (rc *RefreshTokenCache) UseRefreshToken(client, jti string, ttl time.Duration) (bool, error) {
+	return rc.cache.StoreDataIfNotExists(fmt.Sprintf(usedRefreshTokenKeyTemplate, client, jti), []byte("1"), ttl)
+}
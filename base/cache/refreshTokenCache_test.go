@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenCache(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	}
+	rcs, _ := NewRedisCache(opts)
+	cache := NewRefreshTokenCache(rcs)
+
+	t.Run("Should use the refresh token only once", func(t *testing.T) {
+		used, err := cache.UseRefreshToken("client", "jti", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, used)
+
+		used, err = cache.UseRefreshToken("client", "jti", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, used)
+
+		used, err = cache.UseRefreshToken("other", "jti", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, used)
+	})
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+const signatureKeyTemplate = "%s:%s:webhook-signature"
+
+type SignatureCache struct {
+	cache *RedisCache
+}
+
+func NewSignatureCache(cache *RedisCache) *SignatureCache {
+	return &SignatureCache{cache: cache}
+}
+
+// ISignatureCache interface that holds method to remember the signatures of the webhooks received by all the instances
+type ISignatureCache interface {
+	StoreSignature(client, signature string, ttl time.Duration) (bool, error)
+	DeleteSignature(client, signature string) error
+}
+
+// StoreSignature saves the signature of a webhook request, it returns false when the signature was already received
+func (sc *SignatureCache) StoreSignature(client, signature string, ttl time.Duration) (bool, error) {
+	return sc.cache.StoreDataIfNotExists(fmt.Sprintf(signatureKeyTemplate, client, signature), []byte("1"), ttl)
+}
+
+// DeleteSignature forgets the signature of a webhook request so that its retries are accepted
+func (sc *SignatureCache) DeleteSignature(client, signature string) error {
+	return sc.cache.DeleteData(fmt.Sprintf(signatureKeyTemplate, client, signature))
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureCache(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	}
+	rcs, _ := NewRedisCache(opts)
+	cache := NewSignatureCache(rcs)
+
+	t.Run("Should store the signature once in the ttl", func(t *testing.T) {
+		stored, err := cache.StoreSignature("client", "signature", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+
+		stored, err = cache.StoreSignature("client", "signature", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, stored)
+
+		m.FastForward(2 * time.Minute)
+		stored, err = cache.StoreSignature("client", "signature", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+	})
+
+	t.Run("Should store the signature by client", func(t *testing.T) {
+		stored, err := cache.StoreSignature("other", "signature", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+	})
+
+	t.Run("Should store the signature again after it is deleted", func(t *testing.T) {
+		stored, err := cache.StoreSignature("client", "deleted", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+
+		assert.NoError(t, cache.DeleteSignature("client", "deleted"))
+		stored, err = cache.StoreSignature("client", "deleted", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+	})
+}
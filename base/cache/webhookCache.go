@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	webhookOutboxKeyTemplate     = "%s:webhook-outbox"
+	webhookOutboxDataKeyTemplate = "%s:webhook-outbox-data"
+	webhookDeliveriesKeyTemplate = "%s:webhook-deliveries"
+)
+
+// WebhookDeliveryStatus is the status of the delivery of a webhook to a subscriber
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	WebhookFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event to deliver to a subscriber, it is stored in the outbox until it is delivered or
+// its attempts run out, and each attempt is stored in the delivery log. The outbox is a sorted set of the IDs by
+// the time of the next attempt and a hash with the deliveries by ID
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	Client        string                `json:"client"`
+	Subscriber    string                `json:"subscriber"`
+	URL           string                `json:"url"`
+	EventID       string                `json:"eventId"`
+	EventType     string                `json:"eventType"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	StatusCode    int                   `json:"statusCode,omitempty"`
+	Error         string                `json:"error,omitempty"`
+	CreatedAt     time.Time             `json:"createdAt"`
+	NextAttemptAt time.Time             `json:"nextAttemptAt"`
+}
+
+type WebhookCache struct {
+	cache *RedisCache
+}
+
+func NewWebhookCache(cache *RedisCache) *WebhookCache {
+	return &WebhookCache{cache: cache}
+}
+
+// IWebhookCache interface that holds method to store the outbox of the webhooks and their delivery log
+type IWebhookCache interface {
+	EnqueueWebhook(delivery WebhookDelivery) error
+	ClaimWebhooks(client string, until time.Time, visibilityTimeout time.Duration, limit int64) ([]WebhookDelivery, error)
+	CompleteWebhook(client, id string) error
+	LogWebhookDelivery(delivery WebhookDelivery, size int64) error
+	RetrieveWebhookDeliveries(client string) ([]WebhookDelivery, error)
+}
+
+// EnqueueWebhook saves the delivery in the outbox of the client until its next attempt, a delivery that is in the
+// outbox is replaced
+func (wc *WebhookCache) EnqueueWebhook(delivery WebhookDelivery) error {
+	data, _ := json.Marshal(delivery)
+	err := wc.cache.StoreDataToHash(fmt.Sprintf(webhookOutboxDataKeyTemplate, delivery.Client), delivery.ID, data)
+	if err != nil {
+		return err
+	}
+	return wc.cache.StoreDataToSortedSet(fmt.Sprintf(webhookOutboxKeyTemplate, delivery.Client), []byte(delivery.ID),
+		float64(delivery.NextAttemptAt.UnixMilli()))
+}
+
+// ClaimWebhooks returns the deliveries due until the time and hides them in the outbox for the visibility timeout,
+// a delivery is only returned to one of the instances of the client. The deliveries stay in the outbox until they
+// are completed or enqueued again, so they are claimed again when the instance stops before
+func (wc *WebhookCache) ClaimWebhooks(client string, until time.Time, visibilityTimeout time.Duration,
+	limit int64) ([]WebhookDelivery, error) {
+	key := fmt.Sprintf(webhookOutboxKeyTemplate, client)
+	dataKey := fmt.Sprintf(webhookOutboxDataKeyTemplate, client)
+	ids, err := wc.cache.RetrieveDataFromSortedSet(key, float64(until.UnixMilli()), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDelivery, 0, len(ids))
+	for _, id := range ids {
+		claimed, err := wc.cache.ClaimDataFromSortedSet(key, id, float64(until.UnixMilli()),
+			float64(until.Add(visibilityTimeout).UnixMilli()))
+		if err != nil {
+			return deliveries, err
+		}
+		if !claimed {
+			continue
+		}
+
+		data, err := wc.cache.RetrieveDataFromHash(dataKey, id)
+		if errors.Is(err, redis.Nil) {
+			_, err = wc.cache.DeleteDataFromSortedSet(key, id)
+			if err != nil {
+				return deliveries, err
+			}
+			continue
+		}
+		if err != nil {
+			return deliveries, err
+		}
+
+		var delivery WebhookDelivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			if err := wc.CompleteWebhook(client, id); err != nil {
+				return deliveries, err
+			}
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// CompleteWebhook removes the delivery from the outbox once it was delivered or failed
+func (wc *WebhookCache) CompleteWebhook(client, id string) error {
+	if _, err := wc.cache.DeleteDataFromSortedSet(fmt.Sprintf(webhookOutboxKeyTemplate, client), id); err != nil {
+		return err
+	}
+	return wc.cache.DeleteDataFromHash(fmt.Sprintf(webhookOutboxDataKeyTemplate, client), id)
+}
+
+// LogWebhookDelivery saves the attempt of the delivery in the log of the client, the log keeps the newest size attempts
+func (wc *WebhookCache) LogWebhookDelivery(delivery WebhookDelivery, size int64) error {
+	data, _ := json.Marshal(delivery)
+	return wc.cache.StoreDataToList(fmt.Sprintf(webhookDeliveriesKeyTemplate, delivery.Client), data, size)
+}
+
+// RetrieveWebhookDeliveries returns the log of the deliveries of the client, the newest attempts first
+func (wc *WebhookCache) RetrieveWebhookDeliveries(client string) ([]WebhookDelivery, error) {
+	data, err := wc.cache.RetrieveDataFromList(fmt.Sprintf(webhookDeliveriesKeyTemplate, client))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDelivery, 0, len(data))
+	for _, item := range data {
+		var delivery WebhookDelivery
+		if err := json.Unmarshal([]byte(item), &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
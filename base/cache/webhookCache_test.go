@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookCache(t *testing.T) {
+	m, s := CreateRedisServer()
+	defer m.Close()
+	defer s.Close()
+	opts := &RedisOptions{
+		FailOverOptions: &redis.FailoverOptions{
+			MasterName:    s.MasterInfo().Name,
+			SentinelAddrs: []string{s.Addr()},
+		},
+	}
+	rcs, _ := NewRedisCache(opts)
+	cache := NewWebhookCache(rcs)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	newDelivery := func(id string, nextAttemptAt time.Time) WebhookDelivery {
+		return WebhookDelivery{
+			ID:            id,
+			Client:        "client",
+			Subscriber:    "crm",
+			URL:           "https://crm.yalochat.com/webhooks",
+			EventID:       "eventID",
+			EventType:     "chat.created",
+			Payload:       json.RawMessage(`{"id":"eventID"}`),
+			Status:        WebhookPending,
+			CreatedAt:     now,
+			NextAttemptAt: nextAttemptAt,
+		}
+	}
+
+	t.Run("Should claim only the due deliveries once in the visibility timeout", func(t *testing.T) {
+		due := newDelivery("due", now)
+		later := newDelivery("later", now.Add(time.Minute))
+		assert.NoError(t, cache.EnqueueWebhook(later))
+		assert.NoError(t, cache.EnqueueWebhook(due))
+
+		claimed, err := cache.ClaimWebhooks("client", now, 2*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []WebhookDelivery{due}, claimed)
+
+		claimed, err = cache.ClaimWebhooks("client", now, 2*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		claimed, err = cache.ClaimWebhooks("client", now.Add(time.Minute), 2*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []WebhookDelivery{later}, claimed)
+
+		assert.NoError(t, cache.CompleteWebhook("client", "later"))
+		claimed, err = cache.ClaimWebhooks("client", now.Add(2*time.Minute), 2*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []WebhookDelivery{due}, claimed, "the delivery not completed is claimed after the timeout")
+
+		assert.NoError(t, cache.CompleteWebhook("client", "due"))
+		claimed, err = cache.ClaimWebhooks("client", now.Add(time.Hour), 2*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+	})
+
+	t.Run("Should replace the claimed delivery when it is enqueued again", func(t *testing.T) {
+		retried := newDelivery("retried", now)
+		assert.NoError(t, cache.EnqueueWebhook(retried))
+		claimed, err := cache.ClaimWebhooks("client", now, time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+
+		retried.Attempts = 1
+		retried.NextAttemptAt = now.Add(10 * time.Minute)
+		assert.NoError(t, cache.EnqueueWebhook(retried))
+
+		claimed, err = cache.ClaimWebhooks("client", now.Add(5*time.Minute), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		claimed, err = cache.ClaimWebhooks("client", now.Add(10*time.Minute), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []WebhookDelivery{retried}, claimed)
+		assert.NoError(t, cache.CompleteWebhook("client", "retried"))
+	})
+
+	t.Run("Should claim the deliveries up to the limit", func(t *testing.T) {
+		assert.NoError(t, cache.EnqueueWebhook(newDelivery("first", now)))
+		assert.NoError(t, cache.EnqueueWebhook(newDelivery("second", now.Add(time.Second))))
+
+		claimed, err := cache.ClaimWebhooks("client", now.Add(time.Minute), time.Hour, 1)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "first", claimed[0].ID)
+
+		claimed, err = cache.ClaimWebhooks("client", now.Add(time.Minute), time.Hour, 1)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "second", claimed[0].ID)
+	})
+
+	t.Run("Should keep the newest deliveries in the log", func(t *testing.T) {
+		first := newDelivery("first", now)
+		second := newDelivery("second", now)
+		second.Status = WebhookDelivered
+		third := newDelivery("third", now)
+		third.Status = WebhookFailed
+		third.StatusCode = 500
+		third.Error = "callback responded with status 500"
+		assert.NoError(t, cache.LogWebhookDelivery(first, 2))
+		assert.NoError(t, cache.LogWebhookDelivery(second, 2))
+		assert.NoError(t, cache.LogWebhookDelivery(third, 2))
+
+		deliveries, err := cache.RetrieveWebhookDeliveries("client")
+		assert.NoError(t, err)
+		assert.Equal(t, []WebhookDelivery{third, second}, deliveries)
+
+		deliveries, err = cache.RetrieveWebhookDeliveries("otherClient")
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+}
@@ -0,0 +1,103 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+)
+
+const (
+	SignatureHeader = "X-Yalochat-Signature"
+	TimestampHeader = "X-Yalochat-Timestamp"
+	signaturePrefix = "sha256="
+)
+
+// Client sends the callbacks to the URLs given by the clients of the API. The body is signed like the webhooks of
+// Integrations, the signature is the hex of the HMAC-SHA256 of "<timestamp>.<body>" with the secret
+type Client struct {
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+// StatusError is the error of the callbacks that were answered without 2xx status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("callback responded with status %d", e.StatusCode)
+}
+
+// CallbackInterface sends the payload to the url
+type CallbackInterface interface {
+	Send(ctx context.Context, url string, payload interface{}) error
+}
+
+// NewCallbackClient creates a client that retries the callbacks that fail, the callbacks are not signed without secret
+func NewCallbackClient(secret string, timeout time.Duration, maxRetries int) *Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = maxRetries
+	retryClient.HTTPClient.Timeout = timeout
+	retryClient.Logger = nil
+
+	return &Client{
+		secret: []byte(secret),
+		client: httptrace.WrapClient(retryClient.StandardClient()),
+		now:    time.Now,
+	}
+}
+
+// Send posts the payload as JSON to the url, a response without 2xx status is an error
+func (c *Client) Send(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(c.secret) > 0 {
+		timestamp := strconv.FormatInt(c.now().Unix(), 10)
+		request.Header.Set(TimestampHeader, timestamp)
+		request.Header.Set(SignatureHeader, Sign(c.secret, timestamp, body))
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	logrus.WithFields(logrus.Fields{
+		"url":    url,
+		"status": response.StatusCode,
+	}).Info("Callback sent")
+	if response.StatusCode < http.StatusOK || response.StatusCode > 299 {
+		return &StatusError{StatusCode: response.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the value of the signature header of the body sent at the timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package callback
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Send(t *testing.T) {
+	now := time.Unix(1667496171, 0)
+
+	t.Run("Should send the signed payload", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := NewCallbackClient("secret", time.Second, 0)
+		client.now = func() time.Time { return now }
+		err := client.Send(context.Background(), server.URL, map[string]string{"status": "created"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"status":"created"}`, string(body))
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "1667496171", received.Header.Get(TimestampHeader))
+		assert.Equal(t, Sign([]byte("secret"), "1667496171", body), received.Header.Get(SignatureHeader))
+	})
+
+	t.Run("Should not sign the payload without secret", func(t *testing.T) {
+		var received *http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+		}))
+		defer server.Close()
+
+		err := NewCallbackClient("", time.Second, 0).Send(context.Background(), server.URL, map[string]string{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, received.Header.Get(SignatureHeader))
+	})
+
+	t.Run("Should return an error when the callback fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		err := NewCallbackClient("secret", time.Second, 0).Send(context.Background(), server.URL, map[string]string{})
+
+		assert.EqualError(t, err, "callback responded with status 400")
+		var statusError *StatusError
+		assert.ErrorAs(t, err, &statusError)
+		assert.Equal(t, http.StatusBadRequest, statusError.StatusCode)
+	})
+}
+
+func TestSign(t *testing.T) {
+	t.Run("Should sign the timestamp and the body", func(t *testing.T) {
+		signature := Sign([]byte("secret"), "1667496171", []byte(`{"status":"created"}`))
+
+		assert.Equal(t, "sha256=", signature[:7])
+		assert.Len(t, signature, 7+64)
+		assert.NotEqual(t, signature, Sign([]byte("secret"), "1667496172", []byte(`{"status":"created"}`)))
+	})
+}
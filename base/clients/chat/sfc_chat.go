@@ -36,6 +36,7 @@ const (
 	AgentTyping        = "AgentTyping"
 	AgentNotTyping     = "AgentNotTyping"
 	ChatEnded          = "ChatEnded"
+	ChatTransferred    = "ChatTransferred"
 	ReconnectSession   = "ReconnectSession"
 )
 
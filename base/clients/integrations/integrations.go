@@ -46,6 +46,7 @@ type IntegrationInterface interface {
 	WebhookRegister(HealthcheckPayload HealthcheckPayload) (*HealthcheckResponse, error)
 	WebhookRemove(removeWebhookPayload RemoveWebhookPayload) (bool, error)
 	SendMessage(messagePayload interface{}, provider string) (*SendMessageResponse, error)
+	Ping() error
 }
 
 type HealthcheckResponse struct {
@@ -88,7 +89,18 @@ type Recipient struct {
 	ID string `json:"id"`
 }
 type Message struct {
-	Text string `json:"text"`
+	Text       string      `json:"text,omitempty"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+}
+
+type Attachment struct {
+	Type    string            `json:"type" validate:"required"`
+	Payload AttachmentPayload `json:"payload" validate:"required"`
+}
+
+type AttachmentPayload struct {
+	Url        string `json:"url" validate:"required"`
+	IsReusable bool   `json:"is_reusable"`
 }
 
 type SendImagePayload struct {
@@ -119,6 +131,37 @@ type SendAudioPayload struct {
 	Audio  Media  `json:"audio" validate:"required"`
 }
 
+// SendTemplatePayload is an approved WhatsApp template (HSM), the only messages accepted when the 24-hour window
+// of the user is closed
+type SendTemplatePayload struct {
+	Id       string          `json:"id"`
+	Type     string          `json:"type" validate:"required"`
+	UserID   string          `json:"userId" validate:"required"`
+	Template TemplateMessage `json:"template" validate:"required"`
+}
+
+type TemplateMessage struct {
+	Namespace  string              `json:"namespace,omitempty"`
+	Name       string              `json:"name" validate:"required"`
+	Language   TemplateLanguage    `json:"language" validate:"required"`
+	Components []TemplateComponent `json:"components,omitempty"`
+}
+
+type TemplateLanguage struct {
+	Policy string `json:"policy"`
+	Code   string `json:"code" validate:"required"`
+}
+
+type TemplateComponent struct {
+	Type       string              `json:"type"`
+	Parameters []TemplateParameter `json:"parameters"`
+}
+
+type TemplateParameter struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
 type MessageId struct {
 	Id string `json:"id"`
 }
@@ -349,3 +392,28 @@ func (cc *IntegrationsClient) SendMessage(messagePayload interface{}, provider s
 
 	return &response, nil
 }
+
+// Ping checks that the Integrations API is reachable, any answer that is not a server error is valid
+func (cc *IntegrationsClient) Ping() error {
+	// datadog tracing
+	span := tracer.StartSpan("ping_integrations")
+	span.SetTag(ext.AnalyticsEvent, true)
+	defer span.Finish()
+
+	proxiedResponse, proxyError := cc.Proxy.SendHTTPRequest(span, &proxy.Request{
+		Method: http.MethodGet,
+		URI:    "/",
+	})
+	if proxyError != nil {
+		span.SetTag(ext.Error, proxyError)
+		return fmt.Errorf("%s : %s", constants.ForwardError, proxyError.Error())
+	}
+	defer proxiedResponse.Body.Close()
+
+	if proxiedResponse.StatusCode >= http.StatusInternalServerError {
+		err := fmt.Errorf("%s : %d", constants.StatusError, proxiedResponse.StatusCode)
+		span.SetTag(ext.Error, err)
+		return err
+	}
+	return nil
+}
@@ -454,3 +454,38 @@ func TestIntegrationsClient_SendMessage(t *testing.T) {
 		assert.Empty(t, id)
 	})
 }
+
+func TestIntegrationsClient_Ping(t *testing.T) {
+	t.Run("Ping Successful", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		client := NewIntegrationsClient(url, tokenWA, tokenFB, channelWA, channelFB, botWAID, botFBID)
+		client.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`Not Found`))),
+		}, nil).Once()
+
+		assert.NoError(t, client.Ping())
+	})
+
+	t.Run("Ping error status", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		client := NewIntegrationsClient(url, tokenWA, tokenFB, channelWA, channelFB, botWAID, botFBID)
+		client.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusBadGateway,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`Bad Gateway`))),
+		}, nil).Once()
+
+		assert.Error(t, client.Ping())
+	})
+
+	t.Run("Ping error forward", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		client := NewIntegrationsClient(url, tokenWA, tokenFB, channelWA, channelFB, botWAID, botFBID)
+		client.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+		assert.Error(t, client.Ping())
+	})
+}
@@ -28,6 +28,8 @@ const (
 type Proxy struct {
 	BaseURL string
 	Client  *http.Client
+	// StreamClient sends requests with a streamed body, they can't be retried because the body is read only once
+	StreamClient *http.Client
 }
 
 func NewProxy(baseUrl string, timeout int, maxRetries int, minRetryWait int, maxRetryWait int) *Proxy {
@@ -38,7 +40,8 @@ func NewProxy(baseUrl string, timeout int, maxRetries int, minRetryWait int, max
 	retryClient.HTTPClient.Timeout = time.Second * time.Duration(timeout)
 
 	return &Proxy{
-		Client: httptrace.WrapClient(retryClient.StandardClient()),
+		Client:       httptrace.WrapClient(retryClient.StandardClient()),
+		StreamClient: httptrace.WrapClient(&http.Client{Timeout: time.Second * time.Duration(timeout)}),
 
 		BaseURL: baseUrl,
 	}
@@ -51,6 +54,7 @@ type Request struct {
 	Header     http.Header
 	HeaderMap  map[string]string
 	Body       []byte
+	BodyReader io.Reader
 	DataEncode url.Values
 }
 
@@ -92,6 +96,9 @@ func (proxy *Proxy) SendHTTPRequest(mainSpan tracer.Span, request *Request) (*ht
 	if request.DataEncode != nil {
 		span.SetTag(events.Payload, request.DataEncode)
 		body = strings.NewReader(request.DataEncode.Encode())
+	} else if request.BodyReader != nil {
+		span.SetTag(events.Payload, "stream")
+		body = request.BodyReader
 	} else {
 		span.SetTag(events.Payload, string(request.Body))
 		body = bytes.NewReader(request.Body)
@@ -116,7 +123,11 @@ func (proxy *Proxy) SendHTTPRequest(mainSpan tracer.Span, request *Request) (*ht
 	span.SetTag("headers", newRequest.Header)
 
 	newRequest.Close = true
-	response, err := proxy.Client.Do(newRequest)
+	client := proxy.Client
+	if request.BodyReader != nil && proxy.StreamClient != nil {
+		client = proxy.StreamClient
+	}
+	response, err := client.Do(newRequest)
 
 	if err != nil {
 		logrus.WithError(err).Error("Error proxying a request")
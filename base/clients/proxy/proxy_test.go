@@ -7,7 +7,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -151,3 +153,47 @@ func TestForward(t *testing.T) {
 	})
 
 }
+
+func TestForwardStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	t.Run("Valid forward with body reader", func(t *testing.T) {
+		proxyInstance := NewProxy(server.URL, 5, 3, 1, 30)
+		request := &Request{
+			BodyReader: strings.NewReader("Hola"),
+			Method:     http.MethodPost,
+			URI:        "/upload",
+		}
+
+		span, _ := tracer.SpanFromContext(context.Background())
+		response, err := proxyInstance.SendHTTPRequest(span, request)
+		if err != nil {
+			t.Fatalf("It should finish with success")
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+		if response.StatusCode != http.StatusCreated || string(body) != "Hola" {
+			t.Errorf("It should return the streamed body, but this was found <%d %s>", response.StatusCode, body)
+		}
+	})
+
+	t.Run("Valid forward with body reader without stream client", func(t *testing.T) {
+		proxyInstance := &Proxy{BaseURL: server.URL, Client: http.DefaultClient}
+		request := &Request{
+			BodyReader: strings.NewReader("Hola"),
+			Method:     http.MethodPost,
+			URI:        "/upload",
+		}
+
+		span, _ := tracer.SpanFromContext(context.Background())
+		_, err := proxyInstance.SendHTTPRequest(span, request)
+		if err != nil {
+			t.Errorf("It should finish with success")
+		}
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"regexp"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -50,6 +53,14 @@ type ContentVersionPayload struct {
 	VersionData     string `json:"VersionData" validate:"required"`
 }
 
+//ContentVersionMetadata handles the entity_content part of a multipart content version request
+type ContentVersionMetadata struct {
+	Title           string `json:"Title" validate:"required"`
+	Description     string `json:"Description,omitempty" validate:"omitempty"`
+	ContentLocation string `json:"ContentLocation" validate:"required"`
+	PathOnClient    string `json:"PathOnClient" validate:"required"`
+}
+
 //LinkDocumentPayload handles a link document response
 type LinkDocumentPayload struct {
 	ContentDocumentID string `json:"ContentDocumentId" validate:"required"`
@@ -81,6 +92,13 @@ type CaseRequest struct {
 	Comments        string      `json:"Comments"`
 }
 
+//CaseCommentRequest handles the comment of a case
+type CaseCommentRequest struct {
+	ParentID    string `json:"ParentId" validate:"required"`
+	CommentBody string `json:"CommentBody" validate:"required"`
+	IsPublished bool   `json:"IsPublished"`
+}
+
 //SalesforceResponse handles a generic response
 type SalesforceResponse struct {
 	ID      string        `json:"id"`
@@ -100,6 +118,7 @@ type recordResponse struct {
 	PersonContactID   string                 `json:"PersonContactId"`
 	PersonEmail       string                 `json:"PersonEmail"`
 	PersonMobilePhone string                 `json:"PersonMobilePhone"`
+	CaseNumber        string                 `json:"CaseNumber"`
 }
 
 //SearchResponse handles search document response
@@ -162,6 +181,7 @@ type HTTPHeaders struct {
 //SaleforceInterface handles all Saleforce's methods
 type SaleforceInterface interface {
 	CreateCase(mainSpan tracer.Span, payload interface{}) (string, *helpers.ErrorResponse)
+	CreateCaseComment(mainSpan tracer.Span, payload CaseCommentRequest) (string, *helpers.ErrorResponse)
 	Search(string) (*SearchResponse, *helpers.ErrorResponse)
 	SearchID(string) (string, error)
 	SearchContact(string) (*models.SfcContact, *helpers.ErrorResponse)
@@ -169,12 +189,14 @@ type SaleforceInterface interface {
 	SearchAccount(string) (*models.SfcAccount, *helpers.ErrorResponse)
 	//Methods related to upload and associate an image to a case
 	CreateContentVersion(ContentVersionPayload) (string, error)
+	CreateContentVersionStream(mainSpan tracer.Span, metadata ContentVersionMetadata, content io.Reader) (string, *helpers.ErrorResponse)
 	SearchDocumentID(string) (string, error)
 	LinkDocumentToCase(LinkDocumentPayload) (string, error)
 	CreateContact(mainSpan tracer.Span, payload interface{}) (string, *helpers.ErrorResponse)
 	CreateAccount(payload AccountRequest) (string, *helpers.ErrorResponse)
 	CreateAccountComposite(mainSpan tracer.Span, payload interface{}) (*models.SfcAccount, *helpers.ErrorResponse)
 	Composite(mainSpan tracer.Span, compositeRequest CompositeRequest) (CompositeResponses, *helpers.ErrorResponse)
+	CheckToken(mainSpan tracer.Span) *helpers.ErrorResponse
 	GetContentVersionURL() string
 	GetSearchURL(query string) string
 	GetDocumentLinkURL() string
@@ -259,6 +281,103 @@ func (cc *SalesforceClient) CreateContentVersion(contentVersionPayload ContentVe
 	return response.ID, nil
 }
 
+//CreateContentVersionStream creates a new content version sending the file as a multipart stream,
+//so the file is never fully loaded in memory nor encoded in base64
+func (cc *SalesforceClient) CreateContentVersionStream(mainSpan tracer.Span, metadata ContentVersionMetadata, content io.Reader) (string, *helpers.ErrorResponse) {
+	// datadog tracing
+	spanContext := events.GetSpanContextFromSpan(mainSpan)
+	span := tracer.StartSpan("create_content_version_stream", tracer.ChildOf(spanContext))
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag(events.Payload, fmt.Sprintf("%#v", metadata))
+	defer span.Finish()
+	uri := cc.GetContentVersionURL()
+	span.SetTag(ext.ResourceName, fmt.Sprintf("%s %s", http.MethodPost, uri))
+	var errorMessage string
+
+	//validating ContentVersionMetadata struct
+	if err := helpers.Govalidator().Struct(metadata); err != nil {
+		errorMessage = fmt.Sprintf("%s : %s", helpers.InvalidPayload, err.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, err)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: http.StatusBadRequest}
+	}
+
+	//building the multipart body, it is written while the proxy reads it
+	bodyReader, bodyWriter := io.Pipe()
+	multipartWriter := multipart.NewWriter(bodyWriter)
+	go func() {
+		bodyWriter.CloseWithError(writeContentVersionMultipart(multipartWriter, metadata, content))
+	}()
+	defer bodyReader.Close()
+
+	header := make(map[string]string)
+	header["Content-Type"] = multipartWriter.FormDataContentType()
+	header["Authorization"] = fmt.Sprintf("Bearer %s", cc.AccessToken)
+
+	newRequest := proxy.Request{
+		BodyReader: bodyReader,
+		Method:     http.MethodPost,
+		URI:        uri,
+		HeaderMap:  header,
+	}
+
+	proxiedResponse, proxyError := cc.Proxy.SendHTTPRequest(span, &newRequest)
+	if proxyError != nil {
+		errorMessage = fmt.Sprintf("%s : %s", constants.ForwardError, proxyError.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, proxyError)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: http.StatusNotFound}
+	}
+
+	if proxiedResponse.StatusCode != http.StatusCreated {
+		return "", helpers.GetErrorResponseArrayMap(proxiedResponse.Body, constants.StatusError, proxiedResponse.StatusCode)
+	}
+
+	var response SalesforceResponse
+	readAndUnmarshalError := helpers.ReadAndUnmarshal(proxiedResponse.Body, &response)
+
+	if readAndUnmarshalError != nil {
+		errorMessage = fmt.Sprintf("%s : %s", constants.UnmarshallError, readAndUnmarshalError.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, readAndUnmarshalError)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: http.StatusInternalServerError}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"response": response,
+	}).Info("Create ContentVersion stream Success")
+
+	return response.ID, nil
+}
+
+func writeContentVersionMultipart(multipartWriter *multipart.Writer, metadata ContentVersionMetadata, content io.Reader) error {
+	entityHeader := make(textproto.MIMEHeader)
+	entityHeader.Set("Content-Disposition", `form-data; name="entity_content"`)
+	entityHeader.Set("Content-Type", "application/json")
+	entityPart, err := multipartWriter.CreatePart(entityHeader)
+	if err != nil {
+		return err
+	}
+
+	if err = json.NewEncoder(entityPart).Encode(metadata); err != nil {
+		return err
+	}
+
+	versionDataHeader := make(textproto.MIMEHeader)
+	versionDataHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="VersionData"; filename="%s"`, metadata.PathOnClient))
+	versionDataHeader.Set("Content-Type", "application/octet-stream")
+	versionDataPart, err := multipartWriter.CreatePart(versionDataHeader)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(versionDataPart, content); err != nil {
+		return err
+	}
+
+	return multipartWriter.Close()
+}
+
 //Search for entities in salesforce
 func (cc *SalesforceClient) Search(query string) (*SearchResponse, *helpers.ErrorResponse) {
 	// datadog tracing
@@ -666,6 +785,65 @@ func (cc *SalesforceClient) CreateCase(mainSpan tracer.Span, payload interface{}
 	return response.ID, nil
 }
 
+//CreateCaseComment adds a comment to a case
+func (cc *SalesforceClient) CreateCaseComment(mainSpan tracer.Span, payload CaseCommentRequest) (string, *helpers.ErrorResponse) {
+	// datadog tracing
+	spanContext := events.GetSpanContextFromSpan(mainSpan)
+	span := tracer.StartSpan("create_case_comment", tracer.ChildOf(spanContext))
+	span.SetTag(ext.AnalyticsEvent, true)
+	span.SetTag("payload", payload)
+	defer span.Finish()
+	var errorMessage string
+
+	//validating CaseCommentRequest struct
+	if err := helpers.Govalidator().Struct(payload); err != nil {
+		errorMessage = fmt.Sprintf("%s : %s", helpers.InvalidPayload, err.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, err)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: http.StatusBadRequest}
+	}
+
+	//building request to send through proxy
+	requestBytes, _ := json.Marshal(payload)
+
+	header := make(map[string]string)
+	header["Content-Type"] = "application/json"
+	header["Authorization"] = fmt.Sprintf("Bearer %s", cc.AccessToken)
+
+	newRequest := proxy.Request{
+		Body:      requestBytes,
+		Method:    http.MethodPost,
+		URI:       fmt.Sprintf("/services/data/v%s.0/sobjects/CaseComment", cc.APIVersion),
+		HeaderMap: header,
+	}
+	span.SetTag(ext.ResourceName, fmt.Sprintf("%s %s", newRequest.Method, newRequest.URI))
+
+	proxiedResponse, proxyError := cc.Proxy.SendHTTPRequest(span, &newRequest)
+	if proxyError != nil {
+		errorMessage = fmt.Sprintf("%s : %s", constants.ForwardError, proxyError.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, proxyError)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: 0}
+	}
+
+	if proxiedResponse.StatusCode != http.StatusCreated {
+		errorResponse := helpers.GetErrorResponseArrayMap(proxiedResponse.Body, constants.StatusError, proxiedResponse.StatusCode)
+		span.SetTag(ext.Error, errorResponse.Error)
+		return "", errorResponse
+	}
+
+	var response SalesforceResponse
+	readAndUnmarshalError := helpers.ReadAndUnmarshal(proxiedResponse.Body, &response)
+	if readAndUnmarshalError != nil {
+		errorMessage = fmt.Sprintf("%s : %s", constants.UnmarshallError, readAndUnmarshalError.Error())
+		logrus.Error(errorMessage)
+		span.SetTag(ext.Error, readAndUnmarshalError)
+		return "", &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: http.StatusCreated}
+	}
+
+	return response.ID, nil
+}
+
 //CreateContact Create contact for Salesforce Requests
 func (cc *SalesforceClient) CreateContact(mainSpan tracer.Span, payload interface{}) (string, *helpers.ErrorResponse) {
 	// datadog tracing
@@ -920,6 +1098,46 @@ func (cc *SalesforceClient) Composite(mainSpan tracer.Span, compositeRequest Com
 	return response, nil
 }
 
+// CheckToken validates the access token with the userinfo endpoint, it does not count in the API limits
+func (cc *SalesforceClient) CheckToken(mainSpan tracer.Span) *helpers.ErrorResponse {
+	// datadog tracing
+	spanContext := events.GetSpanContextFromSpan(mainSpan)
+	span := tracer.StartSpan("check_token", tracer.ChildOf(spanContext))
+	span.SetTag(ext.AnalyticsEvent, true)
+	defer span.Finish()
+
+	if cc.AccessToken == "" {
+		err := errors.New("there is no access token")
+		span.SetTag(ext.Error, err)
+		return &helpers.ErrorResponse{Error: err, StatusCode: http.StatusUnauthorized}
+	}
+
+	header := make(map[string]string)
+	header["Authorization"] = fmt.Sprintf("Bearer %s", cc.AccessToken)
+
+	newRequest := proxy.Request{
+		Method:    http.MethodGet,
+		URI:       "/services/oauth2/userinfo",
+		HeaderMap: header,
+	}
+	span.SetTag(ext.ResourceName, fmt.Sprintf("%s %s", newRequest.Method, newRequest.URI))
+
+	proxiedResponse, proxyError := cc.Proxy.SendHTTPRequest(span, &newRequest)
+	if proxyError != nil {
+		errorMessage := fmt.Sprintf("%s : %s", constants.ForwardError, proxyError.Error())
+		span.SetTag(ext.Error, proxyError)
+		return &helpers.ErrorResponse{Error: errors.New(errorMessage), StatusCode: 0}
+	}
+	defer proxiedResponse.Body.Close()
+
+	if proxiedResponse.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s : %d", constants.StatusError, proxiedResponse.StatusCode)
+		span.SetTag(ext.Error, err)
+		return &helpers.ErrorResponse{Error: err, StatusCode: proxiedResponse.StatusCode}
+	}
+	return nil
+}
+
 func (cc *SalesforceClient) GetContentVersionURL() string {
 	return fmt.Sprintf("/services/data/v%s.0/sobjects/ContentVersion", cc.APIVersion)
 }
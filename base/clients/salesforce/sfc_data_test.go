@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -138,6 +141,81 @@ func TestSfcData_CreateContentVersion(t *testing.T) {
 	})
 }
 
+func TestSfcData_CreateContentVersionStream(t *testing.T) {
+	metadata := ContentVersionMetadata{
+		Title:           "test image",
+		ContentLocation: "S",
+		PathOnClient:    "screnshoot.jpg",
+	}
+
+	t.Run("Create ContentVersion stream Succesfull", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		var entityContent ContentVersionMetadata
+		var versionData []byte
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			request := args.Get(1).(*proxy.Request)
+			_, params, _ := mime.ParseMediaType(request.HeaderMap["Content-Type"])
+			reader := multipart.NewReader(request.BodyReader, params["boundary"])
+			part, _ := reader.NextPart()
+			json.NewDecoder(part).Decode(&entityContent)
+			part, _ = reader.NextPart()
+			versionData, _ = ioutil.ReadAll(part)
+		}).Return(&http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"id":"dasfasfasd"}`))),
+		}, nil)
+
+		id, err := salesforceClient.CreateContentVersionStream(tracer.StartSpan("test"), metadata, strings.NewReader("file content"))
+		assert.Nil(t, err)
+		assert.Equal(t, "dasfasfasd", id)
+		assert.Equal(t, metadata, entityContent)
+		assert.Equal(t, "file content", string(versionData))
+	})
+
+	t.Run("Create ContentVersion stream Error validation payload", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+
+		id, err := salesforceClient.CreateContentVersionStream(tracer.StartSpan("test"), ContentVersionMetadata{Title: "test image"}, strings.NewReader("file content"))
+
+		assert.Error(t, err.Error)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+		assert.Empty(t, id)
+		proxyMock.AssertNotCalled(t, "SendHTTPRequest", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Create ContentVersion stream error SendHTTPRequest", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{}, assert.AnError)
+
+		id, err := salesforceClient.CreateContentVersionStream(tracer.StartSpan("test"), metadata, strings.NewReader("file content"))
+
+		assert.Error(t, err.Error)
+		assert.Empty(t, id)
+	})
+
+	t.Run("Create ContentVersion stream error status", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`[{"message":"Error create content version"}]`))),
+		}, nil)
+
+		id, err := salesforceClient.CreateContentVersionStream(tracer.StartSpan("test"), metadata, strings.NewReader("file content"))
+
+		assert.Error(t, err.Error)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+		assert.Empty(t, id)
+	})
+}
+
 func TestSfcData_SearchDocumentID(t *testing.T) {
 
 	t.Run("SearchDocumentID Succesfull", func(t *testing.T) {
@@ -740,6 +818,97 @@ func TestSfcData_CreateCase(t *testing.T) {
 	})
 }
 
+func TestSfcData_CreateCaseComment(t *testing.T) {
+	payload := CaseCommentRequest{ParentID: "caseID", CommentBody: "Your case was resolved"}
+
+	t.Run("Create case comment Succesfull", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"id":"commentID"}`))),
+		}, nil)
+		span, _ := tracer.SpanFromContext(context.Background())
+		id, err := salesforceClient.CreateCaseComment(span, payload)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "commentID", id)
+	})
+
+	t.Run("Create case comment invalid payload", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		span, _ := tracer.SpanFromContext(context.Background())
+		id, err := salesforceClient.CreateCaseComment(span, CaseCommentRequest{ParentID: "caseID"})
+
+		assert.Error(t, err.Error)
+		assert.Empty(t, id)
+		proxyMock.AssertNotCalled(t, "SendHTTPRequest", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Create case comment error status", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`[{"message":"invalid case"}]`))),
+		}, nil)
+		span, _ := tracer.SpanFromContext(context.Background())
+		id, err := salesforceClient.CreateCaseComment(span, payload)
+
+		assert.Error(t, err.Error)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+		assert.Empty(t, id)
+	})
+}
+
+func TestSfcData_CheckToken(t *testing.T) {
+	t.Run("Check token Succesfull", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.MatchedBy(func(request *proxy.Request) bool {
+			return request.URI == "/services/oauth2/userinfo" && request.HeaderMap["Authorization"] == "Bearer "+token
+		})).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"user_id":"userID"}`))),
+		}, nil).Once()
+		span, _ := tracer.SpanFromContext(context.Background())
+
+		assert.Nil(t, salesforceClient.CheckToken(span))
+	})
+
+	t.Run("Check token expired", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, token)
+		salesforceClient.Proxy = proxyMock
+		proxyMock.On("SendHTTPRequest", mock.Anything, mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusForbidden,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`Bad_OAuth_Token`))),
+		}, nil).Once()
+		span, _ := tracer.SpanFromContext(context.Background())
+
+		err := salesforceClient.CheckToken(span)
+		assert.Error(t, err.Error)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+	})
+
+	t.Run("Check token without token", func(t *testing.T) {
+		proxyMock := new(mocks.ProxyInterface)
+		salesforceClient := NewSalesforceRequester(caseURL, "")
+		salesforceClient.Proxy = proxyMock
+		span, _ := tracer.SpanFromContext(context.Background())
+
+		err := salesforceClient.CheckToken(span)
+		assert.Error(t, err.Error)
+		assert.Equal(t, http.StatusUnauthorized, err.StatusCode)
+		proxyMock.AssertNotCalled(t, "SendHTTPRequest", mock.Anything, mock.Anything)
+	})
+}
+
 func TestSfcData_CreateContact(t *testing.T) {
 	span, _ := tracer.SpanFromContext(context.Background())
 
@@ -8,6 +8,14 @@ const (
 	QueryParamError            = "Error getting query param"
 	ResponseError              = "Error getting response, it was empty or format not handled correctly"
 	ErrInterconnectionNotFound = applicationErrors("not found interconnection")
+	ErrFileTooLarge            = applicationErrors("file exceeds the maximum size allowed")
+	ErrFileTypeNotAllowed      = applicationErrors("file type not allowed")
+	ErrCaseNotFound            = applicationErrors("not found case")
+	ErrInvalidMessage          = applicationErrors("invalid message")
+	ErrChatRequestNotFound     = applicationErrors("not found chat request")
+	ErrChatRequestInProgress   = applicationErrors("chat request in progress")
+	ErrContactBlocked          = applicationErrors("this contact is blocked")
+	ErrMessageDeliveryNotFound = applicationErrors("not found message delivery")
 )
 
 type applicationErrors string
@@ -11,4 +11,5 @@ const (
 	ImageType              = "image"
 	TextType               = "text"
 	FileType               = "file"
+	TemplateType           = "template"
 )
@@ -25,15 +25,18 @@ const (
 	EventType           = "eventType"
 	MessageKafka        = "messageKafka"
 
-	ContextSaved     = "contextSaved"
-	ChatActive       = "chatActive"
-	SendMessage      = "sendMessage"
-	RetryMessage     = "retryMessage"
-	UserBlocked      = "userBlocked"
-	StatusSalesforce = "statusSalesforce"
-	MessageRepeated  = "messageRepeated"
-	MessageSentAgent = "messageSentAgent"
-	SendFile         = "sendFile"
+	ContextSaved       = "contextSaved"
+	ChatActive         = "chatActive"
+	SendMessage        = "sendMessage"
+	RetryMessage       = "retryMessage"
+	UserBlocked        = "userBlocked"
+	StatusSalesforce   = "statusSalesforce"
+	MessageRepeated    = "messageRepeated"
+	MessageSentAgent   = "messageSentAgent"
+	SendFile           = "sendFile"
+	FileTooLarge       = "fileTooLarge"
+	FileTypeNotAllowed = "fileTypeNotAllowed"
+	EndUserCommand     = "endUserCommand"
 )
 
 // GetSpanContextFromSpan returns a SpanContext to be used as parent given a span
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status of a component or of the app
+type Status string
+
+const (
+	// Up the component works
+	Up Status = "up"
+	// Down the component does not work, the app is down when a critical component is down
+	Down Status = "down"
+	// Degraded only non-critical components are down
+	Degraded Status = "degraded"
+)
+
+// Component is a dependency of the app, Check returns an error when the dependency does not work
+type Component struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+// ComponentReport is the result of the check of a component
+type ComponentReport struct {
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report is the result of the check of all the components
+type Report struct {
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentReport `json:"components,omitempty"`
+}
+
+// Checker checks the components in parallel, each one with a timeout, and keeps the report during cacheTTL
+// so that the probes don't overload the dependencies
+type Checker struct {
+	components []Component
+	timeout    time.Duration
+	cacheTTL   time.Duration
+
+	mutex     sync.Mutex
+	report    *Report
+	checkedAt time.Time
+}
+
+// NewChecker creates a Checker of the components
+func NewChecker(timeout, cacheTTL time.Duration, components ...Component) *Checker {
+	return &Checker{
+		components: components,
+		timeout:    timeout,
+		cacheTTL:   cacheTTL,
+	}
+}
+
+// Check returns the cached report or checks the components again when it expired
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.report != nil && time.Since(c.checkedAt) < c.cacheTTL {
+		return *c.report
+	}
+
+	report := Report{
+		Status:     Up,
+		Components: make(map[string]ComponentReport, len(c.components)),
+	}
+	reports := make([]ComponentReport, len(c.components))
+	var wg sync.WaitGroup
+	for i, component := range c.components {
+		wg.Add(1)
+		go func(i int, component Component) {
+			defer wg.Done()
+			reports[i] = c.checkComponent(ctx, component)
+		}(i, component)
+	}
+	wg.Wait()
+
+	for i, component := range c.components {
+		report.Components[component.Name] = reports[i]
+		if reports[i].Status == Up {
+			continue
+		}
+		if component.Critical {
+			report.Status = Down
+		} else if report.Status == Up {
+			report.Status = Degraded
+		}
+	}
+
+	c.report = &report
+	c.checkedAt = time.Now()
+	return report
+}
+
+func (c *Checker) checkComponent(ctx context.Context, component Component) ComponentReport {
+	started := time.Now()
+	err := runCheck(ctx, component.Check, c.timeout)
+
+	report := ComponentReport{
+		Status:    Up,
+		Critical:  component.Critical,
+		Duration:  time.Since(started).String(),
+		CheckedAt: started,
+	}
+	if err != nil {
+		report.Status = Down
+		report.Error = err.Error()
+	}
+	return report
+}
+
+// runCheck stops waiting for the check after the timeout, the check keeps running but its result is ignored
+func runCheck(ctx context.Context, check func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("check failed: %v", r)
+			}
+		}()
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timeout after %s", timeout)
+		}
+		return ctx.Err()
+	}
+}
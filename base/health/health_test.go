@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Check(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	t.Run("Should be up when all the components are up", func(t *testing.T) {
+		checker := NewChecker(time.Second, 0,
+			Component{Name: "redis", Critical: true, Check: up},
+			Component{Name: "integrations", Check: up},
+		)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, Up, report.Status)
+		assert.Equal(t, Up, report.Components["redis"].Status)
+		assert.True(t, report.Components["redis"].Critical)
+		assert.Equal(t, Up, report.Components["integrations"].Status)
+	})
+
+	t.Run("Should be down when a critical component is down", func(t *testing.T) {
+		checker := NewChecker(time.Second, 0,
+			Component{Name: "redis", Critical: true, Check: down},
+			Component{Name: "integrations", Check: down},
+		)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, Down, report.Status)
+		assert.Equal(t, Down, report.Components["redis"].Status)
+		assert.Equal(t, "connection refused", report.Components["redis"].Error)
+	})
+
+	t.Run("Should be degraded when only non-critical components are down", func(t *testing.T) {
+		checker := NewChecker(time.Second, 0,
+			Component{Name: "redis", Critical: true, Check: up},
+			Component{Name: "integrations", Check: down},
+		)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, Degraded, report.Status)
+		assert.Equal(t, Down, report.Components["integrations"].Status)
+	})
+
+	t.Run("Should be down when the check times out", func(t *testing.T) {
+		checker := NewChecker(10*time.Millisecond, 0,
+			Component{Name: "salesforce", Critical: true, Check: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}},
+		)
+
+		started := time.Now()
+		report := checker.Check(context.Background())
+
+		assert.Less(t, time.Since(started), time.Second)
+		assert.Equal(t, Down, report.Status)
+		assert.Equal(t, "timeout after 10ms", report.Components["salesforce"].Error)
+	})
+
+	t.Run("Should be down when the check panics", func(t *testing.T) {
+		checker := NewChecker(time.Second, 0,
+			Component{Name: "kafka", Critical: true, Check: func(ctx context.Context) error {
+				panic("nil producer")
+			}},
+		)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, Down, report.Status)
+		assert.Equal(t, "check failed: nil producer", report.Components["kafka"].Error)
+	})
+
+	t.Run("Should return the cached report", func(t *testing.T) {
+		var checks int32
+		checker := NewChecker(time.Second, time.Minute,
+			Component{Name: "redis", Critical: true, Check: func(ctx context.Context) error {
+				atomic.AddInt32(&checks, 1)
+				return nil
+			}},
+		)
+
+		checker.Check(context.Background())
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, Up, report.Status)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&checks))
+	})
+}
@@ -7,8 +7,12 @@ import (
 	"mime"
 
 	"github.com/gabriel-vasile/mimetype"
+	"yalochat.com/salesforce-integration/base/constants"
 )
 
+// sniffLimit is the size of the header of the files used to detect their MIME, it is the limit of the detector
+const sniffLimit = 3072
+
 func Encode(bin []byte) []byte {
 	e64 := base64.StdEncoding
 
@@ -36,20 +40,57 @@ func StreamToByte(stream io.Reader) []byte {
 
 // Get the content type of the magic numbers of the file, return the content type, return the original file
 func GetContentAndTypeByReader(reader io.Reader) (contentType string, multiReader io.Reader, err error) {
-	// Set header size to 261 bytes.
-	mimetype.SetLimit(261)
-	testBytes := StreamToByte(reader)
-	inputReader := bytes.NewReader(testBytes)
-	// We only have to pass the file header = first 261 bytes
-	header := bytes.NewBuffer(nil)
-	// After DetectReader, the first bytes are stored in buf.
-	mime, err := mimetype.DetectReader(io.TeeReader(inputReader, header))
-	if err == nil {
-		// Concatenate back the first bytes.
-		// reusableReader now contains the complete, original data.
-		reusableReader := io.MultiReader(header, inputReader)
-		return mime.String(), reusableReader, nil
-	} else {
+	mime, multiReader, err := GetMIMEAndReader(reader)
+	if err != nil {
 		return "", nil, err
 	}
+	return mime.String(), multiReader, nil
+}
+
+// GetMIMEAndReader detects the MIME of the file by its magic numbers, return the detected MIME with its hierarchy, return the original file
+func GetMIMEAndReader(reader io.Reader) (*mimetype.MIME, io.Reader, error) {
+	// We only have to pass the file header, the first sniffLimit bytes
+	header := bytes.NewBuffer(nil)
+	_, err := io.Copy(header, io.LimitReader(reader, sniffLimit))
+	if err != nil {
+		return nil, nil, err
+	}
+	mime := mimetype.Detect(header.Bytes())
+	// Concatenate back the first bytes.
+	// reusableReader now contains the complete, original data without loading the rest of the file.
+	reusableReader := io.MultiReader(header, reader)
+	return mime, reusableReader, nil
+}
+
+// LimitedReader reads from Reader and fails with constants.ErrFileTooLarge when more than Limit bytes are read
+type LimitedReader struct {
+	Reader   io.Reader
+	Limit    int64
+	read     int64
+	exceeded bool
+}
+
+// NewLimitedReader wraps the reader with the limit, a limit lower or equal to zero means no limit
+func NewLimitedReader(reader io.Reader, limit int64) *LimitedReader {
+	return &LimitedReader{Reader: reader, Limit: limit}
+}
+
+func (l *LimitedReader) Read(p []byte) (int, error) {
+	n, err := l.Reader.Read(p)
+	l.read += int64(n)
+	if l.Limit > 0 && l.read > l.Limit {
+		l.exceeded = true
+		return n, constants.ErrFileTooLarge
+	}
+	return n, err
+}
+
+// BytesRead returns the number of bytes read
+func (l *LimitedReader) BytesRead() int64 {
+	return l.read
+}
+
+// Exceeded reports if the reader went over the limit
+func (l *LimitedReader) Exceeded() bool {
+	return l.exceeded
 }
@@ -2,11 +2,14 @@ package helpers
 
 import (
 	"io"
+	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/base/constants"
 )
 
 func TestEncode(t *testing.T) {
@@ -107,3 +110,31 @@ func TestGetContentAndTypeByReader(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitedReader(t *testing.T) {
+	t.Run("Read under the limit", func(t *testing.T) {
+		reader := NewLimitedReader(strings.NewReader("Hola"), 4)
+		content, err := ioutil.ReadAll(reader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Hola", string(content))
+		assert.False(t, reader.Exceeded())
+	})
+
+	t.Run("Read over the limit", func(t *testing.T) {
+		reader := NewLimitedReader(strings.NewReader("Hola mundo"), 4)
+		_, err := ioutil.ReadAll(reader)
+
+		assert.ErrorIs(t, err, constants.ErrFileTooLarge)
+		assert.True(t, reader.Exceeded())
+	})
+
+	t.Run("Read without limit", func(t *testing.T) {
+		reader := NewLimitedReader(strings.NewReader("Hola mundo"), 0)
+		content, err := ioutil.ReadAll(reader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Hola mundo", string(content))
+		assert.False(t, reader.Exceeded())
+	})
+}
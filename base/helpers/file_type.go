@@ -0,0 +1,135 @@
+package helpers
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+const genericMIMEType = "application/octet-stream"
+
+// mediaFamilies are the families where the subtypes are encodings of the same content, a file can declare another
+// type of its family, e.g. a jpeg sent as image/png
+var mediaFamilies = map[string]bool{
+	"image": true,
+	"audio": true,
+	"video": true,
+}
+
+// zipContainerTypes are the prefixes of the types stored as a zip file, the sniffer detects them as application/zip
+// when their entries are not in the header of the file, e.g. most of the docx. The jar and apk are not included
+var zipContainerTypes = []string{
+	"application/vnd.openxmlformats-officedocument.",
+	"application/vnd.oasis.opendocument.",
+	"application/epub+zip",
+}
+
+// FileTypePolicy holds the types allowed and denied for the files sent by the users.
+// Each entry can be a mime type (image/png), a mime family (image/*) or an extension (.png),
+// an empty Allowed list means that every type not denied is allowed
+type FileTypePolicy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// Validate checks the sniffed type of a file against the policy and against the declared mime type,
+// it fails with constants.ErrFileTypeNotAllowed when the file is denied, not allowed or disguises its type
+func (p FileTypePolicy) Validate(sniffed *mimetype.MIME, declaredMIME, fileName string) error {
+	declaredMIME = mediaType(declaredMIME)
+	extensions := []string{sniffed.Extension(), strings.ToLower(filepath.Ext(fileName))}
+	if declaredMIME != "" {
+		declaredExtensions, _ := mime.ExtensionsByType(declaredMIME)
+		extensions = append(extensions, declaredExtensions...)
+	}
+
+	for _, entry := range p.Denied {
+		if matchMIMEType(entry, sniffed.String()) || matchMIMEType(entry, declaredMIME) || matchExtension(entry, extensions...) {
+			return fmt.Errorf("%w: %s is denied", constants.ErrFileTypeNotAllowed, sniffed.String())
+		}
+	}
+
+	if len(p.Allowed) > 0 {
+		allowed := false
+		for _, entry := range p.Allowed {
+			if matchMIMEType(entry, sniffed.String()) || matchMIMEType(entry, declaredMIME) || matchExtension(entry, extensions...) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("%w: %s is not allowed", constants.ErrFileTypeNotAllowed, sniffed.String())
+		}
+	}
+
+	if declaredMIME != "" && declaredMIME != genericMIMEType && !isSameFileType(sniffed, declaredMIME) {
+		return fmt.Errorf("%w: %s declared as %s", constants.ErrFileTypeNotAllowed, sniffed.String(), declaredMIME)
+	}
+
+	return nil
+}
+
+// isSameFileType validates that the content of the file corresponds to the declared type,
+// the declared type can be the sniffed one, one of its parents, a zip container of a zip or, for the media,
+// one of the same family
+func isSameFileType(sniffed *mimetype.MIME, declaredMIME string) bool {
+	for current := sniffed; current != nil; current = current.Parent() {
+		if current.Is(declaredMIME) {
+			return true
+		}
+	}
+
+	if sniffed.Is("application/zip") {
+		for _, zipContainerType := range zipContainerTypes {
+			if strings.HasPrefix(declaredMIME, zipContainerType) {
+				return true
+			}
+		}
+	}
+
+	family := mediaFamily(sniffed.String())
+	return mediaFamilies[family] && family == mediaFamily(declaredMIME)
+}
+
+func matchMIMEType(entry, mimeType string) bool {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	mimeType = mediaType(mimeType)
+	if mimeType == "" || strings.HasPrefix(entry, ".") {
+		return false
+	}
+
+	if strings.HasSuffix(entry, "/*") {
+		return strings.TrimSuffix(entry, "/*") == mediaFamily(mimeType)
+	}
+	return entry == mimeType
+}
+
+func matchExtension(entry string, extensions ...string) bool {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if !strings.HasPrefix(entry, ".") {
+		return false
+	}
+
+	for _, extension := range extensions {
+		if extension != "" && entry == strings.ToLower(extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func mediaType(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(mimeType))
+	}
+	return mediaType
+}
+
+func mediaFamily(mimeType string) string {
+	return strings.SplitN(mediaType(mimeType), "/", 2)[0]
+}
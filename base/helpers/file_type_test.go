@@ -0,0 +1,220 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"yalochat.com/salesforce-integration/base/constants"
+)
+
+var (
+	pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfContent = []byte("%PDF-1.4\n%test")
+	exeContent = append([]byte("MZ"), make([]byte, 100)...)
+)
+
+// newDocx returns a docx like the ones of Word, its thumbnail moves the entries of the document out of the header
+func newDocx(t *testing.T) []byte {
+	thumbnail := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(thumbnail)
+
+	content := bytes.NewBuffer(nil)
+	writer := zip.NewWriter(content)
+	entries := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships></Relationships>`)},
+		{"docProps/thumbnail.jpeg", thumbnail},
+		{"word/document.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document></w:document>`)},
+	}
+	for _, entry := range entries {
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Store})
+		assert.NoError(t, err)
+		_, err = file.Write(entry.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return content.Bytes()
+}
+
+func TestFileTypePolicy_Validate(t *testing.T) {
+	type args struct {
+		content      []byte
+		declaredMIME string
+		fileName     string
+	}
+	tests := []struct {
+		name    string
+		policy  FileTypePolicy
+		args    args
+		wantErr bool
+	}{
+		{
+			name:   "success without policy",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      pngContent,
+				declaredMIME: "image/png",
+				fileName:     "image",
+			},
+		},
+		{
+			name:   "success with mime family allowed",
+			policy: FileTypePolicy{Allowed: []string{"image/*"}},
+			args: args{
+				content:      pngContent,
+				declaredMIME: "image/jpeg",
+				fileName:     "image",
+			},
+		},
+		{
+			name:   "success with extension allowed and without declared mime",
+			policy: FileTypePolicy{Allowed: []string{".pdf"}},
+			args: args{
+				content:  pdfContent,
+				fileName: "document",
+			},
+		},
+		{
+			name:   "success with declared mime with parameters",
+			policy: FileTypePolicy{Allowed: []string{"application/pdf"}},
+			args: args{
+				content:      pdfContent,
+				declaredMIME: "application/pdf; name=document.pdf",
+				fileName:     "document",
+			},
+		},
+		{
+			name:   "success with generic declared mime",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      pdfContent,
+				declaredMIME: "application/octet-stream",
+				fileName:     "document",
+			},
+		},
+		{
+			name:   "success with a docx sniffed as zip",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      newDocx(t),
+				declaredMIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				fileName:     "contrato.docx",
+			},
+		},
+		{
+			name:   "success with the extension of the file name allowed",
+			policy: FileTypePolicy{Allowed: []string{".docx"}},
+			args: args{
+				content:      newDocx(t),
+				declaredMIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				fileName:     "contrato.docx",
+			},
+		},
+		{
+			name:   "success with the declared mime allowed",
+			policy: FileTypePolicy{Allowed: []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}},
+			args: args{
+				content:      newDocx(t),
+				declaredMIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				fileName:     "contrato",
+			},
+		},
+		{
+			name:   "error zip declared as jar",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      newDocx(t),
+				declaredMIME: "application/java-archive",
+				fileName:     "program.jar",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error zip declared as apk",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      newDocx(t),
+				declaredMIME: "application/vnd.android.package-archive",
+				fileName:     "program.apk",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error type not allowed",
+			policy: FileTypePolicy{Allowed: []string{"image/*"}},
+			args: args{
+				content:      pdfContent,
+				declaredMIME: "application/pdf",
+				fileName:     "document",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error executable denied",
+			policy: FileTypePolicy{Denied: []string{"application/vnd.microsoft.portable-executable"}},
+			args: args{
+				content:  exeContent,
+				fileName: "program",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error extension of the file name denied",
+			policy: FileTypePolicy{Denied: []string{".exe"}},
+			args: args{
+				content:      pdfContent,
+				declaredMIME: "application/pdf",
+				fileName:     "program.EXE",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error executable disguised as image",
+			policy: FileTypePolicy{Allowed: []string{"image/*", ".exe"}},
+			args: args{
+				content:      exeContent,
+				declaredMIME: "image/png",
+				fileName:     "image",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error executable disguised as document",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      exeContent,
+				declaredMIME: "application/zip",
+				fileName:     "document",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error document disguised as image",
+			policy: FileTypePolicy{},
+			args: args{
+				content:      pdfContent,
+				declaredMIME: "image/png",
+				fileName:     "image",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sniffed, _, err := GetMIMEAndReader(bytes.NewReader(tt.args.content))
+			assert.NoError(t, err)
+			err = tt.policy.Validate(sniffed, tt.args.declaredMIME, tt.args.fileName)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, constants.ErrFileTypeNotAllowed)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -126,6 +126,28 @@ func GetPaginationValues(r *http.Request) (int64, int64, error) {
 	return page, size, nil
 }
 
+// GetLimitAndOffset obtains the limit and offset of the request,
+// if the values do not come in the request, they are 0, so all the records are returned
+func GetLimitAndOffset(r *http.Request) (int, int, error) {
+	limit, offset := 0, 0
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: %s", value)
+		}
+	}
+
+	return limit, offset, nil
+}
+
 // GetFilterAndShort obtain the filter and sort of the request,
 // it returns a default value if they are not found.
 // filter: {} and sort: {inserted_at: -1}
@@ -211,6 +211,49 @@ func TestGetPaginationValues(t *testing.T) {
 	}
 }
 
+func TestGetLimitAndOffset(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{
+			name: "success default values",
+			url:  "/v1/context/userID",
+		},
+		{
+			name:       "success get values",
+			url:        "/v1/context/userID?limit=20&offset=40",
+			wantLimit:  20,
+			wantOffset: 40,
+		},
+		{
+			name:    "error limit",
+			url:     "/v1/context/userID?limit=dsdsad",
+			wantErr: true,
+		},
+		{
+			name:    "error negative offset",
+			url:     "/v1/context/userID?limit=10&offset=-1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			limit, offset, err := GetLimitAndOffset(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetLimitAndOffset() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantLimit, limit)
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
 func TestGetFilterAndShort(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/v1/integrations/whatsapp/webhook", nil)
 	reqSort, _ := http.NewRequest("POST", "/v1/integrations/whatsapp/webhook?sort=1&dateStart=2021-10-07%2010:00:00", nil)
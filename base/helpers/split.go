@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitMessage splits the text in parts of up to limit characters, it cuts on line or word boundaries
+// when possible. With markers every part starts with its position, e.g. "(1/3) "
+func SplitMessage(text string, limit int, markers bool) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	if !markers {
+		return splitText(text, limit)
+	}
+
+	// the marker size depends on the number of parts, the text is split again until they match
+	parts := splitText(text, limit)
+	for {
+		markerSize := len(marker(len(parts), len(parts)))
+		if markerSize >= limit {
+			return parts
+		}
+		newParts := splitText(text, limit-markerSize)
+		if len(marker(len(newParts), len(newParts))) == markerSize {
+			parts = newParts
+			break
+		}
+		parts = newParts
+	}
+
+	for i := range parts {
+		parts[i] = marker(i+1, len(parts)) + parts[i]
+	}
+	return parts
+}
+
+func marker(index, total int) string {
+	return fmt.Sprintf("(%d/%d) ", index, total)
+}
+
+func splitText(text string, limit int) []string {
+	var parts []string
+	runes := []rune(strings.TrimSpace(text))
+	for len(runes) > limit {
+		cut := lastIndex(runes[:limit+1], '\n')
+		if cut <= 0 {
+			cut = lastIndex(runes[:limit+1], ' ')
+		}
+		if cut <= 0 {
+			parts = append(parts, string(runes[:limit]))
+			runes = runes[limit:]
+			continue
+		}
+
+		parts = append(parts, strings.TrimRight(string(runes[:cut]), " \n"))
+		runes = []rune(strings.TrimLeft(string(runes[cut+1:]), " \n"))
+	}
+
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+func lastIndex(runes []rune, separator rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == separator {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		limit   int
+		markers bool
+		want    []string
+	}{
+		{
+			name:  "Short message",
+			text:  "Hola, necesito ayuda",
+			limit: 20,
+			want:  []string{"Hola, necesito ayuda"},
+		},
+		{
+			name:  "Without limit",
+			text:  "Hola, necesito ayuda",
+			limit: 0,
+			want:  []string{"Hola, necesito ayuda"},
+		},
+		{
+			name:  "Split on lines",
+			text:  "Cliente: hola\nBot: hola\nCliente: ayuda",
+			limit: 25,
+			want:  []string{"Cliente: hola\nBot: hola", "Cliente: ayuda"},
+		},
+		{
+			name:  "Split on words",
+			text:  "uno dos tres cuatro cinco",
+			limit: 9,
+			want:  []string{"uno dos", "tres", "cuatro", "cinco"},
+		},
+		{
+			name:  "Split long words",
+			text:  "abcdefghij",
+			limit: 4,
+			want:  []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:  "Split by characters",
+			text:  "ñañaña ñañaña",
+			limit: 6,
+			want:  []string{"ñañaña", "ñañaña"},
+		},
+		{
+			name:    "Split with markers",
+			text:    "uno dos tres cuatro cinco",
+			limit:   16,
+			markers: true,
+			want:    []string{"(1/4) uno dos", "(2/4) tres", "(3/4) cuatro", "(4/4) cinco"},
+		},
+		{
+			name:    "Markers bigger than the limit",
+			text:    "uno dos",
+			limit:   4,
+			markers: true,
+			want:    []string{"uno", "dos"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SplitMessage(tt.text, tt.limit, tt.markers))
+		})
+	}
+
+	t.Run("Parts respect the limit", func(t *testing.T) {
+		text := strings.Repeat("Cliente [31-08-2021 05:00:00]:Hola, necesito ayuda con mi pedido\n\n", 100)
+		parts := SplitMessage(text, 2000, true)
+
+		assert.Len(t, parts, 4)
+		for _, part := range parts {
+			assert.LessOrEqual(t, utf8.RuneCountInString(part), 2000)
+		}
+		assert.True(t, strings.HasPrefix(parts[3], "(4/4) "))
+	})
+}
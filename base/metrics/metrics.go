@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "salesforce_integration"
+
+// Labels of the metrics, the client label is added to all the metrics when they are registered
+const (
+	ClientLabel     = "client"
+	ProviderLabel   = "provider"
+	BotSlugLabel    = "bot_slug"
+	ReasonLabel     = "reason"
+	StatusLabel     = "status"
+	DirectionLabel  = "direction"
+	ResultLabel     = "result"
+	TopicLabel      = "topic"
+	SubscriberLabel = "subscriber"
+)
+
+// Directions of the messages
+const (
+	ToUser       = "to_user"
+	ToSalesforce = "to_salesforce"
+)
+
+// Results of the operations
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+var (
+	// ChatsCreated counts the chats created in Salesforce
+	ChatsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chats_created_total",
+		Help:      "Chats created in Salesforce.",
+	}, []string{ProviderLabel, BotSlugLabel})
+
+	// ChatsFailed counts the chats that could not be created by the step that failed
+	ChatsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chats_failed_total",
+		Help:      "Chats that could not be created, by reason.",
+	}, []string{ProviderLabel, BotSlugLabel, ReasonLabel})
+
+	// Interconnections is the number of interconnections stored in redis by status, it's collected on each scrape
+	Interconnections = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "interconnections"),
+		"Interconnections stored in redis, by status.",
+		[]string{ProviderLabel, BotSlugLabel, StatusLabel}, nil,
+	)
+
+	// LongPollingDuration measures the requests of the long polling to the Salesforce chat by response status
+	LongPollingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "long_polling_duration_seconds",
+		Help:      "Latency of the long polling requests to Salesforce chat, by response status.",
+		Buckets:   []float64{.1, .5, 1, 5, 10, 20, 30, 45, 60},
+	}, []string{ProviderLabel, BotSlugLabel, StatusLabel})
+
+	// MessagesForwarded counts the messages sent to the users and to the agents
+	MessagesForwarded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_forwarded_total",
+		Help:      "Messages forwarded to the users or to Salesforce.",
+	}, []string{DirectionLabel, ProviderLabel, BotSlugLabel})
+
+	// MessageRetries counts the retries of the messages that failed
+	MessageRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_retries_total",
+		Help:      "Retries of the messages forwarded to the users or to Salesforce.",
+	}, []string{DirectionLabel, ProviderLabel, BotSlugLabel})
+
+	// MessagesDropped counts the messages that were not sent after the retries
+	MessagesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dropped_total",
+		Help:      "Messages that could not be forwarded to the users or to Salesforce.",
+	}, []string{DirectionLabel, ProviderLabel, BotSlugLabel})
+
+	// KafkaPublishLag measures the time between the publication of a message and its delivery report
+	KafkaPublishLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_publish_lag_seconds",
+		Help:      "Time between the publication of a message in kafka and its delivery report, by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{TopicLabel, ResultLabel})
+
+	// KafkaConsumeLag measures the time between the publication of a message and its consumption
+	KafkaConsumeLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_consume_lag_seconds",
+		Help:      "Time between the publication of a message in kafka and its consumption.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{TopicLabel})
+
+	// FileUploads counts the files of the users uploaded to the cases by result
+	FileUploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "file_uploads_total",
+		Help:      "Files of the users uploaded to the cases, by result.",
+	}, []string{ProviderLabel, BotSlugLabel, ResultLabel})
+
+	// FileUploadSize measures the size of the files uploaded to Salesforce
+	FileUploadSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "file_upload_size_bytes",
+		Help:      "Size of the files uploaded to Salesforce.",
+		Buckets:   prometheus.ExponentialBuckets(16*1024, 4, 8),
+	})
+
+	// TokenRefreshes counts the refreshes of the Salesforce access token by result
+	TokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "salesforce_token_refreshes_total",
+		Help:      "Refreshes of the Salesforce access token, by result.",
+	}, []string{ResultLabel})
+
+	// WebhookDeliveries counts the attempts to deliver the lifecycle webhooks by the status of the delivery after them
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Attempts to deliver the lifecycle webhooks, by subscriber and status of the delivery.",
+	}, []string{SubscriberLabel, StatusLabel})
+
+	// MessageStatuses counts the status updates of the messages sent to the users that changed their status
+	MessageStatuses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_statuses_total",
+		Help:      "Status updates of the messages sent to the users, by provider and status.",
+	}, []string{ProviderLabel, StatusLabel})
+)
+
+// NewRegistry returns a registry with the runtime metrics, the metrics of the app and the collectors, the metrics
+// of the app and the collectors have the client label
+func NewRegistry(client string, appCollectors ...prometheus.Collector) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{ClientLabel: client}, registry)
+	appCollectors = append([]prometheus.Collector{
+		ChatsCreated,
+		ChatsFailed,
+		LongPollingDuration,
+		MessagesForwarded,
+		MessageRetries,
+		MessagesDropped,
+		KafkaPublishLag,
+		KafkaConsumeLag,
+		FileUploads,
+		FileUploadSize,
+		TokenRefreshes,
+		WebhookDeliveries,
+		MessageStatuses,
+	}, appCollectors...)
+	for _, collector := range appCollectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Result returns the result label of an error
+func Result(err error) string {
+	if err != nil {
+		return Failure
+	}
+	return Success
+}
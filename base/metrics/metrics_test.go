@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	t.Run("Should register the metrics with the client label", func(t *testing.T) {
+		registry, err := NewRegistry("coppel")
+		assert.NoError(t, err)
+
+		ChatsCreated.WithLabelValues("whatsapp", "coppel-bot").Inc()
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+
+		found := false
+		for _, family := range families {
+			if family.GetName() != "salesforce_integration_chats_created_total" {
+				continue
+			}
+			found = true
+			labels := map[string]string{}
+			for _, label := range family.GetMetric()[0].GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, map[string]string{ClientLabel: "coppel", ProviderLabel: "whatsapp", BotSlugLabel: "coppel-bot"}, labels)
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Should register the metrics in several registries", func(t *testing.T) {
+		_, err := NewRegistry("coppel")
+		assert.NoError(t, err)
+		_, err = NewRegistry("coppel")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should return an error when a collector is registered twice", func(t *testing.T) {
+		_, err := NewRegistry("coppel", TokenRefreshes)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Should count the results", func(t *testing.T) {
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total"}, []string{ResultLabel})
+
+		counter.WithLabelValues(Result(nil)).Inc()
+		counter.WithLabelValues(Result(errors.New("failed"))).Inc()
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(counter.WithLabelValues(Success)))
+		assert.Equal(t, float64(1), testutil.ToFloat64(counter.WithLabelValues(Failure)))
+	})
+}
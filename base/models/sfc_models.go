@@ -9,6 +9,8 @@ type SfcContact struct {
 	AccountID   string `json:"AccountID"`
 	// This field will be given to us by salesforce, to know if a user is blocked
 	Blocked bool `json:"Blocked"`
+	// Created is true when the contact was not found and it was created
+	Created bool `json:"-"`
 }
 
 type SfcAccount struct {
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// TemplateVariables are the variables that the messages can use with the text/template syntax:
+//
+//	{{.Name}}          name of the user
+//	{{.CaseNumber}}    number of the case created in salesforce
+//	{{.QueuePosition}} position of the user in the queue of the agents
+//	{{.WaitTime}}      estimated wait time in seconds
+//	{{.AgentName}}     name of the agent that accepted the chat
+//	{{.BotSlug}}       slug of the bot
+//	{{.Provider}}      provider of the chat, whatsapp or facebook
+//	{{.Command}}       end-user command used to finish the chat
+//	{{extra "field"}}  field of the extraData, empty when it doesn't exist
+//
+// e.g. "Hola {{.Name}}, tu caso es el {{.CaseNumber}}"
+type TemplateVariables struct {
+	Name          string
+	CaseNumber    string
+	QueuePosition int
+	WaitTime      int
+	AgentName     string
+	BotSlug       string
+	Provider      string
+	Command       string
+	ExtraData     map[string]interface{}
+}
+
+// legacyArguments are the number of fmt verbs of the messages that are still supported with the fmt format,
+// e.g. "Hola soy %s y necesito ayuda"
+var legacyArguments = map[string]int{
+	"WelcomeTemplate":    1,
+	"EndUserCommandNote": 1,
+}
+
+// printfVerb finds the fmt verbs of a message, e.g. "%s" or "%d", the messages without legacy arguments and the
+// messages with actions "{{ }}" would send them to the user as they are
+var printfVerb = regexp.MustCompile(`%(\[\d+\])?[-+#0]*\d*(\.\d+)?[vTtbcdoOqxXUeEfFgGsp]`)
+
+// RenderMessage returns the message with the variables, the messages without actions "{{ }}" use
+// the fmt format with the args
+func RenderMessage(message string, variables TemplateVariables, args ...interface{}) (string, error) {
+	if !strings.Contains(message, "{{") {
+		if len(args) == 0 {
+			return message, nil
+		}
+		return fmt.Sprintf(message, args...), nil
+	}
+
+	messageTemplate, err := parseMessage(message, variables.ExtraData)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	err = messageTemplate.Execute(&buffer, variables)
+	if err != nil {
+		return "", fmt.Errorf("could not render the message: %w", err)
+	}
+	return buffer.String(), nil
+}
+
+// Validate checks that all the messages can be rendered
+func (sd MessageTemplate) Validate() error {
+	messages := reflect.ValueOf(sd)
+	messagesType := messages.Type()
+	for i := 0; i < messages.NumField(); i++ {
+		message := messages.Field(i)
+		if message.Kind() != reflect.String || message.String() == "" {
+			continue
+		}
+
+		field := messagesType.Field(i)
+		err := validateMessage(message.String(), legacyArguments[field.Name])
+		if err != nil {
+			return fmt.Errorf("invalid message %s: %w", field.Tag.Get("json"), err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the keys of the template sets and that all their messages can be rendered
+func (mt MessageTemplates) Validate() error {
+	for key, messageTemplate := range mt {
+		if err := validateTemplatesKey(key); err != nil {
+			return err
+		}
+		if err := messageTemplate.Validate(); err != nil {
+			return fmt.Errorf("invalid template set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// validateTemplatesKey checks that the key is "botSlug:language", "botSlug" or "*:language", so a bot slug is never
+// taken as a language
+func validateTemplatesKey(key string) error {
+	parts := strings.Split(key, ":")
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid template set key %q, the keys can be botSlug:language, botSlug or *:language", key)
+		}
+	}
+	if len(parts) > 2 || (len(parts) == 1 && key == anyBot) {
+		return fmt.Errorf("invalid template set key %q, the keys can be botSlug:language, botSlug or *:language", key)
+	}
+	return nil
+}
+
+// Validate checks that the template has a name and a language and that its params can be rendered
+func (wt WindowTemplate) Validate() error {
+	if wt.Name == "" || wt.Language == "" {
+		return fmt.Errorf("the window template must have name and language")
+	}
+	for i, param := range wt.Params {
+		if err := validateMessage(param, 0); err != nil {
+			return fmt.Errorf("invalid param %d of the window template: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateMessage checks that the message can be rendered, the messages with actions "{{ }}" are not formatted with
+// the legacy arguments so they can not have verbs either
+func validateMessage(message string, arguments int) error {
+	if arguments == 0 || strings.Contains(message, "{{") {
+		if verb := printfVerb.FindString(strings.ReplaceAll(message, "%%", "")); verb != "" {
+			return fmt.Errorf("the message can not have the verb %s, use the variables {{ }} instead", verb)
+		}
+	}
+	if !strings.Contains(message, "{{") {
+		if arguments == 0 {
+			return nil
+		}
+		args := make([]interface{}, arguments)
+		for i := range args {
+			args[i] = ""
+		}
+		if rendered := fmt.Sprintf(message, args...); strings.Contains(rendered, "%!") {
+			return fmt.Errorf("the message must have %d verbs: %s", arguments, rendered)
+		}
+		return nil
+	}
+
+	messageTemplate, err := parseMessage(message, nil)
+	if err != nil {
+		return err
+	}
+
+	// the execution finds the variables that don't exist
+	err = messageTemplate.Execute(ioutil.Discard, TemplateVariables{ExtraData: map[string]interface{}{}})
+	if err != nil {
+		return fmt.Errorf("could not render the message: %w", err)
+	}
+	return nil
+}
+
+func parseMessage(message string, extraData map[string]interface{}) (*template.Template, error) {
+	messageTemplate, err := template.New("message").Funcs(template.FuncMap{
+		"extra": func(field string) string {
+			if value, ok := extraData[field]; ok && value != nil {
+				return fmt.Sprint(value)
+			}
+			return ""
+		},
+	}).Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the message: %w", err)
+	}
+	return messageTemplate, nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMessage(t *testing.T) {
+	variables := TemplateVariables{
+		Name:          "Eduardo",
+		CaseNumber:    "00001234",
+		QueuePosition: 2,
+		WaitTime:      30,
+		AgentName:     "Ana",
+		BotSlug:       "coppel-bot",
+		Provider:      "whatsapp",
+		Command:       "salir",
+		ExtraData:     map[string]interface{}{"segment": "gold", "empty": nil},
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		args    []interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Message without variables",
+			message: "Esperando un agente",
+			want:    "Esperando un agente",
+		},
+		{
+			name:    "Message with fmt format",
+			message: "Hola soy %s y necesito ayuda",
+			args:    []interface{}{"Eduardo"},
+			want:    "Hola soy Eduardo y necesito ayuda",
+		},
+		{
+			name:    "Message with variables",
+			message: "Hola {{.Name}}, eres el {{.QueuePosition}} en la cola ({{.WaitTime}}s), caso {{.CaseNumber}} de {{.BotSlug}} por {{.Provider}}",
+			args:    []interface{}{"Eduardo"},
+			want:    "Hola Eduardo, eres el 2 en la cola (30s), caso 00001234 de coppel-bot por whatsapp",
+		},
+		{
+			name:    "Message with agent and command",
+			message: "{{.AgentName}} {{.Command}}",
+			want:    "Ana salir",
+		},
+		{
+			name:    "Message with extraData fields",
+			message: `{{extra "segment"}}-{{extra "empty"}}-{{extra "unknown"}}`,
+			want:    "gold--",
+		},
+		{
+			name:    "Message with a variable that doesn't exist",
+			message: "Hola {{.LastName}}",
+			wantErr: true,
+		},
+		{
+			name:    "Malformed message",
+			message: "Hola {{.Name}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderMessage(tt.message, variables, tt.args...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMessageTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages MessageTemplate
+		wantErr  string
+	}{
+		{
+			name: "Valid messages",
+			messages: MessageTemplate{
+				WaitAgent:          "Esperando un agente, eres el {{.QueuePosition}} en la cola",
+				WelcomeTemplate:    "Hola soy %s y necesito ayuda",
+				EndUserCommandNote: "**El usuario finalizo el chat con el comando {{.Command}}**",
+				UploadFileError:    "Archivo no enviado 100%",
+			},
+		},
+		{
+			name:     "Malformed template",
+			messages: MessageTemplate{WaitAgent: "Hola {{.Name"},
+			wantErr:  "invalid message waitAgent: could not parse the message",
+		},
+		{
+			name:     "Variable that doesn't exist",
+			messages: MessageTemplate{WaitAgent: "Hola {{.LastName}}"},
+			wantErr:  "invalid message waitAgent: could not render the message",
+		},
+		{
+			name:     "Verb in a message without arguments",
+			messages: MessageTemplate{WaitAgent: "Tu caso es el %s"},
+			wantErr:  "invalid message waitAgent: the message can not have the verb %s",
+		},
+		{
+			name:     "Verb in a message with variables",
+			messages: MessageTemplate{QueuePosition: "{{.Name}}, eres el %d en la cola"},
+			wantErr:  "invalid message queuePosition: the message can not have the verb %d",
+		},
+		{
+			name:     "Verb in a message with legacy arguments and variables",
+			messages: MessageTemplate{WelcomeTemplate: "{{.Name}} necesita ayuda con %s"},
+			wantErr:  "invalid message welcomeTemplate: the message can not have the verb %s",
+		},
+		{
+			name:     "Escaped percent in a message without arguments",
+			messages: MessageTemplate{UploadFileSuccess: "Archivo enviado al 100%%"},
+		},
+		{
+			name:     "Wrong number of verbs",
+			messages: MessageTemplate{WelcomeTemplate: "Hola soy %s %s y necesito ayuda"},
+			wantErr:  "invalid message welcomeTemplate: the message must have 1 verbs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.messages.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestMessageTemplates_Validate(t *testing.T) {
+	err := MessageTemplates{"pt": {WaitAgent: "Aguardando {{.Agent}}"}}.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid template set pt: invalid message waitAgent")
+
+	err = MessageTemplates{"pt": {WaitAgent: "Aguardando um agente"}}.Validate()
+	assert.NoError(t, err)
+}
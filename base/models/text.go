@@ -3,24 +3,63 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 type MessageTemplate struct {
-	WaitAgent          string `json:"waitAgent"`
-	QueuePosition      string `json:"queuePosition"`
-	WaitTime           string `json:"waitTime"`
-	WelcomeTemplate    string `json:"welcomeTemplate"`
-	Context            string `json:"context"`
-	DescriptionCase    string `json:"descriptionCase"`
-	UploadImageError   string `json:"uploadImageError"`
-	UploadImageSuccess string `json:"uploadImageSuccess"`
-	UploadFileError    string `json:"uploadFileError"`
-	UploadFileSuccess  string `json:"uploadFileSuccess"`
-	UploadAudioError   string `json:"uploadAudioError"`
-	UploadAudioSuccess string `json:"uploadAudioSuccess"`
-	FirstNameContact   string `json:"firstNameContact"`
-	ClientLabel        string `json:"clientLabel"`
-	BotLabel           string `json:"botLabel"`
+	WaitAgent                string `json:"waitAgent"`
+	QueuePosition            string `json:"queuePosition"`
+	WaitTime                 string `json:"waitTime"`
+	WelcomeTemplate          string `json:"welcomeTemplate"`
+	Context                  string `json:"context"`
+	DescriptionCase          string `json:"descriptionCase"`
+	UploadImageError         string `json:"uploadImageError"`
+	UploadImageSuccess       string `json:"uploadImageSuccess"`
+	UploadFileError          string `json:"uploadFileError"`
+	UploadFileSuccess        string `json:"uploadFileSuccess"`
+	UploadAudioError         string `json:"uploadAudioError"`
+	UploadAudioSuccess       string `json:"uploadAudioSuccess"`
+	UploadFileTooLarge       string `json:"uploadFileTooLarge"`
+	UploadFileTypeNotAllowed string `json:"uploadFileTypeNotAllowed"`
+	EndUserCommandNote       string `json:"endUserCommandNote"`
+	CaseNumberTemplate       string `json:"caseNumberTemplate"`
+	FirstNameContact         string `json:"firstNameContact"`
+	ClientLabel              string `json:"clientLabel"`
+	BotLabel                 string `json:"botLabel"`
+	ImageLabel               string `json:"imageLabel"`
+	AudioLabel               string `json:"audioLabel"`
+	DocumentLabel            string `json:"documentLabel"`
+	TranscriptTitle          string `json:"transcriptTitle"`
+	ProactiveChatTemplate    string `json:"proactiveChatTemplate"`
+	MessageNotDelivered      string `json:"messageNotDelivered"`
+	WindowClosed             string `json:"windowClosed"`
+}
+
+// WindowTemplate is the approved WhatsApp template (HSM) sent to the user when the 24-hour window is closed, the
+// params of its body are messages rendered with the variables of the chat, e.g.
+// {"name":"continue_chat","namespace":"namespace","language":"es_MX","params":["{{.Name}}","{{.CaseNumber}}"]}
+type WindowTemplate struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Language  string   `json:"language"`
+	Params    []string `json:"params"`
+}
+
+// Decode Decoder this function deserializes the struct by the envconfig Decoder interface implementation
+func (wt *WindowTemplate) Decode(value string) error {
+	var windowTemplate = WindowTemplate{}
+
+	err := json.Unmarshal([]byte(value), &windowTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid map json: %w", err)
+	}
+	if err := windowTemplate.Validate(); err != nil {
+		return err
+	}
+	*wt = windowTemplate
+
+	return nil
 }
 
 // Decode Decoder this function deserializes the struct by the envconfig Decoder interface implementation
@@ -31,7 +70,61 @@ func (sd *MessageTemplate) Decode(value string) error {
 	if err != nil {
 		return fmt.Errorf("invalid map json: %w", err)
 	}
+	if err := messageTemplate.Validate(); err != nil {
+		return err
+	}
 	*sd = *messageTemplate
 
 	return nil
 }
+
+// MessageTemplates holds template sets by bot slug and/or language, the keys can be
+// "botSlug:language", "botSlug" or "*:language" for every bot, e.g. "coppel-bot:en", "coppel-bot" or "*:pt"
+type MessageTemplates map[string]MessageTemplate
+
+// anyBot is the bot slug of the keys of the template sets of a language for every bot
+const anyBot = "*"
+
+// Decode Decoder this function deserializes the struct by the envconfig Decoder interface implementation
+func (mt *MessageTemplates) Decode(value string) error {
+	var messageTemplates = MessageTemplates{}
+
+	err := json.Unmarshal([]byte(value), &messageTemplates)
+	if err != nil {
+		return fmt.Errorf("invalid map json: %w", err)
+	}
+	if err := messageTemplates.Validate(); err != nil {
+		return err
+	}
+	*mt = messageTemplates
+
+	return nil
+}
+
+// Get returns the template set of the bot and language, the empty messages are taken from the default set
+func (mt MessageTemplates) Get(defaultTemplate MessageTemplate, botSlug, language string) MessageTemplate {
+	language = strings.ToLower(language)
+	keys := []string{botSlug}
+	if language != "" {
+		keys = []string{fmt.Sprintf("%s:%s", botSlug, language), botSlug, fmt.Sprintf("%s:%s", anyBot, language)}
+	}
+
+	for _, key := range keys {
+		if messageTemplate, ok := mt[key]; ok {
+			return defaultTemplate.merge(messageTemplate)
+		}
+	}
+	return defaultTemplate
+}
+
+// merge returns a copy of the set with the non-empty messages of the override set
+func (sd MessageTemplate) merge(override MessageTemplate) MessageTemplate {
+	merged := reflect.ValueOf(&sd).Elem()
+	overrideValue := reflect.ValueOf(override)
+	for i := 0; i < overrideValue.NumField(); i++ {
+		if value := overrideValue.Field(i); value.Kind() == reflect.String && value.String() != "" {
+			merged.Field(i).SetString(value.String())
+		}
+	}
+	return sd
+}
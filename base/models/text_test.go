@@ -38,6 +38,14 @@ func TestMessageTemplate_Decode(t *testing.T) {
 				BotLabel:           "Bot",
 			},
 		},
+		{
+			name: "Decode Fail with a malformed template",
+			sd:   &MessageTemplate{},
+			args: args{
+				value: `{"waitAgent":"Esperando a {{.AgentName","welcomeTemplate":"Hola soy %s y necesito ayuda"}`,
+			},
+			wantErr: true,
+		},
 		{
 			name: "Decode Fail",
 			sd:   &MessageTemplate{},
@@ -71,3 +79,133 @@ func TestMessageTemplate_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageTemplates_Decode(t *testing.T) {
+	t.Run("Decode success", func(t *testing.T) {
+		messageTemplates := MessageTemplates{}
+		err := messageTemplates.Decode(`{"coppel-bot:en":{"waitAgent":"Waiting for an agent"},"*:pt":{"waitAgent":"Aguardando um agente"}}`)
+		assert.NoError(t, err)
+		assert.Equal(t, MessageTemplates{
+			"coppel-bot:en": {WaitAgent: "Waiting for an agent"},
+			"*:pt":          {WaitAgent: "Aguardando um agente"},
+		}, messageTemplates)
+	})
+
+	t.Run("Decode Fail", func(t *testing.T) {
+		messageTemplates := MessageTemplates{}
+		err := messageTemplates.Decode(`{"pt":"Aguardando um agente"}`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decode Fail with a malformed template", func(t *testing.T) {
+		messageTemplates := MessageTemplates{}
+		err := messageTemplates.Decode(`{"*:pt":{"waitAgent":"Aguardando {{.Name"}}`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decode Fail with a malformed key", func(t *testing.T) {
+		for _, key := range []string{"*", ":pt", "coppel-bot:", "coppel-bot:en:pt"} {
+			messageTemplates := MessageTemplates{}
+			err := messageTemplates.Decode(`{"` + key + `":{"waitAgent":"Aguardando um agente"}}`)
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestMessageTemplates_Get(t *testing.T) {
+	defaultTemplate := MessageTemplate{
+		WaitAgent:   "Esperando un agente",
+		ClientLabel: "Cliente",
+		BotLabel:    "Bot",
+	}
+	messageTemplates := MessageTemplates{
+		"coppel-bot:en": {WaitAgent: "Waiting for an agent", ClientLabel: "Client"},
+		"coppel-bot":    {WaitAgent: "Esperando un asesor"},
+		"*:pt":          {WaitAgent: "Aguardando um agente", ClientLabel: "Cliente"},
+		"en":            {WaitAgent: "Waiting for an agent of the en bot"},
+	}
+
+	tests := []struct {
+		name     string
+		botSlug  string
+		language string
+		want     MessageTemplate
+	}{
+		{
+			name:     "Bot and language set",
+			botSlug:  "coppel-bot",
+			language: "EN",
+			want:     MessageTemplate{WaitAgent: "Waiting for an agent", ClientLabel: "Client", BotLabel: "Bot"},
+		},
+		{
+			name:     "Bot set when the language has no set",
+			botSlug:  "coppel-bot",
+			language: "pt",
+			want:     MessageTemplate{WaitAgent: "Esperando un asesor", ClientLabel: "Cliente", BotLabel: "Bot"},
+		},
+		{
+			name:    "Bot set without language",
+			botSlug: "coppel-bot",
+			want:    MessageTemplate{WaitAgent: "Esperando un asesor", ClientLabel: "Cliente", BotLabel: "Bot"},
+		},
+		{
+			name:     "Language set",
+			botSlug:  "other-bot",
+			language: "pt",
+			want:     MessageTemplate{WaitAgent: "Aguardando um agente", ClientLabel: "Cliente", BotLabel: "Bot"},
+		},
+		{
+			name:     "Default set",
+			botSlug:  "other-bot",
+			language: "en",
+			want:     defaultTemplate,
+		},
+		{
+			name:     "Bot set of a bot slug equal to a language",
+			botSlug:  "en",
+			language: "pt",
+			want:     MessageTemplate{WaitAgent: "Waiting for an agent of the en bot", ClientLabel: "Cliente", BotLabel: "Bot"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, messageTemplates.Get(defaultTemplate, tt.botSlug, tt.language))
+		})
+	}
+
+	t.Run("Without template sets", func(t *testing.T) {
+		assert.Equal(t, defaultTemplate, MessageTemplates(nil).Get(defaultTemplate, "coppel-bot", "en"))
+	})
+}
+
+func TestWindowTemplate_Decode(t *testing.T) {
+	t.Run("Decode success", func(t *testing.T) {
+		windowTemplate := WindowTemplate{}
+		err := windowTemplate.Decode(`{"name":"continue_chat","namespace":"namespace","language":"es_MX","params":["{{.Name}}","{{.CaseNumber}}"]}`)
+		assert.NoError(t, err)
+		assert.Equal(t, WindowTemplate{
+			Name:      "continue_chat",
+			Namespace: "namespace",
+			Language:  "es_MX",
+			Params:    []string{"{{.Name}}", "{{.CaseNumber}}"},
+		}, windowTemplate)
+	})
+
+	t.Run("Decode Fail", func(t *testing.T) {
+		windowTemplate := WindowTemplate{}
+		err := windowTemplate.Decode(`"continue_chat"`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decode Fail without language", func(t *testing.T) {
+		windowTemplate := WindowTemplate{}
+		err := windowTemplate.Decode(`{"name":"continue_chat"}`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decode Fail with a malformed param", func(t *testing.T) {
+		windowTemplate := WindowTemplate{}
+		err := windowTemplate.Decode(`{"name":"continue_chat","language":"es_MX","params":["{{.Name"]}`)
+		assert.Error(t, err)
+	})
+}
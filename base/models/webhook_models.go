@@ -11,6 +11,8 @@ type IntegrationsRequest struct {
 	Document  Media  `json:"document,omitempty"`
 	Image     Media  `json:"image,omitempty"`
 	Text      Text   `json:"text,omitempty"`
+	// Status is the new status of the message sent to the user in the status types, the ID is the ID of the message
+	Status string `json:"status,omitempty"`
 }
 
 type Media struct {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/metrics"
 	"yalochat.com/salesforce-integration/base/subscribers"
 )
 
@@ -70,6 +71,9 @@ func (s consumer) readMessage() {
 		logrus.Errorf("[reading_err:%s][topic:%s]", err, s.rTopic)
 		return
 	}
+	if !m.Timestamp.IsZero() {
+		metrics.KafkaConsumeLag.WithLabelValues(s.rTopic).Observe(time.Since(m.Timestamp).Seconds())
+	}
 
 	err = s.service.Process(context.Background(), m.Value)
 	if err != nil {
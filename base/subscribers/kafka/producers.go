@@ -1,16 +1,20 @@
 package kafka
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/sirupsen/logrus"
+	"yalochat.com/salesforce-integration/base/metrics"
 	"yalochat.com/salesforce-integration/base/subscribers"
 )
 
 type producer interface {
 	ProduceChannel() chan *kafka.Message
 	Events() chan kafka.Event
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
 }
 
 type publisher struct {
@@ -56,7 +60,8 @@ func (p *publisher) SendMessage(prms interface{}) error {
 			Topic:     &params.Topic,
 			Partition: kafka.PartitionAny,
 		},
-		Value: params.Msg,
+		Value:     params.Msg,
+		Timestamp: time.Now(),
 	}
 
 	if len(params.Key) > 0 {
@@ -68,6 +73,18 @@ func (p *publisher) SendMessage(prms interface{}) error {
 	return nil
 }
 
+// Ping checks that the brokers answer a metadata request
+func (p *publisher) Ping(timeout time.Duration) error {
+	metadata, err := p.producer.GetMetadata(nil, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	if len(metadata.Brokers) == 0 {
+		return errors.New("there are no kafka brokers")
+	}
+	return nil
+}
+
 func (p *publisher) readEvents(prefix string) {
 
 	for e := range p.producer.Events() {
@@ -77,8 +94,18 @@ func (p *publisher) readEvents(prefix string) {
 			if m.TopicPartition.Error != nil {
 				logrus.Errorf("[kafka_producer][Delivery_failed:%v]", m.TopicPartition.Error.Error())
 			}
+			observePublishLag(m)
 
 			continue
 		}
 	}
 }
+
+// observePublishLag measures the time between the publication of the message and its delivery report
+func observePublishLag(m *kafka.Message) {
+	if m.Timestamp.IsZero() || m.TopicPartition.Topic == nil {
+		return
+	}
+	metrics.KafkaPublishLag.WithLabelValues(*m.TopicPartition.Topic, metrics.Result(m.TopicPartition.Error)).
+		Observe(time.Since(m.Timestamp).Seconds())
+}
@@ -1,11 +1,19 @@
 package subscribers
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Producer interface {
 	SendMessage(msg interface{}) error
 }
 
+// Pinger is implemented by the producers and consumers that can check the connection with the brokers
+type Pinger interface {
+	Ping(timeout time.Duration) error
+}
+
 type Consumer interface {
 	Start()
 }